@@ -8,16 +8,61 @@ type Enum struct {
 	// Nested names will concat with their parent messages so that it will remain unique
 	// This also means nested type might be a bit ugly in type script but whatever
 	Name string
+	// FQType is the fully qualified type name for the enum itself
+	FQType string
 	// Due to the fact that Protos allows alias fields which is not a feature
 	// in Typescript, it's better to use string representation of it.
 	// So Values here will basically be the name of the field.
-	Values []string
+	Values []*EnumValue
+	// Comment is the comment attached to the enum in the proto file
+	Comment string
+	// SourceLocation is the proto file and the line the enum is declared at, e.g. foo.proto:42, when source locations are emitted
+	SourceLocation string
+}
+
+// EnumValue is the data to render a single value inside an enum
+type EnumValue struct {
+	// Name is the name of the enum value
+	Name string
+	// Number is the number of the enum value
+	Number int32
+	// Comment is the comment attached to the enum value in the proto file
+	Comment string
+}
+
+// UniqueValues returns the values in declaration order, aliases sharing the number of a previous value are left out
+func (e *Enum) UniqueValues() []*EnumValue {
+	numbers := make(map[int32]bool)
+	values := make([]*EnumValue, 0, len(e.Values))
+	for _, v := range e.Values {
+		if numbers[v.Number] {
+			continue
+		}
+		numbers[v.Number] = true
+		values = append(values, v)
+	}
+
+	return values
+}
+
+// IsAlias returns whether the value shares its number with a value declared before it, which is allowed by the allow_alias option
+func (e *Enum) IsAlias(value *EnumValue) bool {
+	for _, v := range e.Values {
+		if v == value {
+			return false
+		}
+		if v.Number == value.Number {
+			return true
+		}
+	}
+
+	return false
 }
 
 // NewEnum creates an enum instance.
 func NewEnum() *Enum {
 	return &Enum{
 		Name:   "",
-		Values: make([]string, 0),
+		Values: make([]*EnumValue, 0),
 	}
 }
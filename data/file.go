@@ -1,10 +1,13 @@
 package data
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"unicode"
 )
 
 // File store the information about rendering a file
@@ -24,8 +27,15 @@ type File struct {
 	Name string
 	// TSFileName is the name of the output file
 	TSFileName string
+	// SourceHash is the hex encoded sha256 hash of the descriptor of the proto file, it's only set when source hashes are emitted
+	SourceHash string
 	// PackageNonScalarType stores the type inside the same packages within the file, which will be used to figure out external dependencies inside the same package (different files)
 	PackageNonScalarType []Type
+	// ServiceFiles are the files the services of the file are generated into when each service is generated into its own file
+	ServiceFiles []*File
+	// IsServiceFile indicates the file only holds a service of the proto file, which imports the types of the proto file
+	// from its generated file rather than declaring them
+	IsServiceFile bool
 }
 
 // StableDependencies are dependencies in a stable order.
@@ -33,6 +43,10 @@ func (f *File) StableDependencies() []*Dependency {
 	out := make([]*Dependency, len(f.Dependencies))
 	copy(out, f.Dependencies)
 	sort.Slice(out, func(i, j int) bool {
+		if out[i].SourceFile == out[j].SourceFile {
+			// files sharing the same ts package import the same source file
+			return out[i].ModuleIdentifier < out[j].ModuleIdentifier
+		}
 		return out[i].SourceFile < out[j].SourceFile
 	})
 	return out
@@ -49,6 +63,17 @@ func (f *File) NeedsOneOfSupport() bool {
 	return false
 }
 
+// FindMessage returns the top level message of the fully qualified name inside the file, nil if it's not found
+func (f *File) FindMessage(fqType string) *Message {
+	for _, m := range f.Messages {
+		if m.FQType == fqType {
+			return m
+		}
+	}
+
+	return nil
+}
+
 // TrackPackageNonScalarType tracks the supplied non scala type in the same package
 func (f *File) TrackPackageNonScalarType(t Type) {
 	isNonScalarType := strings.Index(t.GetType().Type, ".") == 0
@@ -61,6 +86,50 @@ func (f *File) IsEmpty() bool {
 	return len(f.Enums) == 0 && len(f.Messages) == 0 && len(f.Services) == 0
 }
 
+// NewBundleFile returns a file with the enums, messages and services of all the given files.
+// dependencies are deduplicated as they will be rendered only once at the top of the bundle
+func NewBundleFile(tsFileName string, files []*File) *File {
+	bundle := NewFile()
+	bundle.Name = tsFileName
+	bundle.TSFileName = tsFileName
+
+	dependencies := make(map[string]bool)
+	for _, f := range files {
+		for _, d := range f.Dependencies {
+			identifier := d.ModuleIdentifier + "|" + d.SourceFile
+			if !dependencies[identifier] {
+				dependencies[identifier] = true
+				bundle.Dependencies = append(bundle.Dependencies, d)
+			}
+		}
+
+		bundle.Enums = append(bundle.Enums, f.Enums...)
+		bundle.Messages = append(bundle.Messages, f.Messages...)
+		bundle.Services = append(bundle.Services, f.Services...)
+	}
+
+	bundle.SourceHash = bundleSourceHash(files)
+	return bundle
+}
+
+// bundleSourceHash hashes the source hashes of the bundled files in their order, so that the bundle is stale whenever any of
+// its files is. it's empty when source hashes aren't emitted
+func bundleSourceHash(files []*File) string {
+	if len(files) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, f := range files {
+		if f.SourceHash == "" {
+			return ""
+		}
+		h.Write([]byte(f.SourceHash))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // NewFile returns an initialised new file
 func NewFile() *File {
 	return &File{
@@ -83,28 +152,77 @@ type Dependency struct {
 	SourceFile string
 }
 
-// GetModuleName returns module name = package name + file name to be the unique identifier for source file in a ts file
+// GetModuleName returns module name = package name + file name to be the unique identifier for source file in a ts file.
+// files without a package are told apart by their directories instead, e.g. vendor/foo.proto becomes VendorFoo
 func GetModuleName(packageName, fileName string) string {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	if packageName != "" {
+		name = filepath.Base(name)
+	}
+
+	return GetPackagePrefix(packageName) + toPascalCase(name)
+}
+
+// toPascalCase joins the parts of the name separated by characters not allowed in identifiers in PascalCase, e.g. a/my-file becomes AMyFile
+func toPascalCase(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for i, p := range parts {
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+
+	return strings.Join(parts, "")
+}
+
+// GetPackagePrefix returns the package name in PascalCase, e.g. com.example becomes ComExample
+func GetPackagePrefix(packageName string) string {
+	if packageName == "" {
+		return ""
+	}
+
+	packageParts := strings.Split(packageName, ".")
+	for i, p := range packageParts {
+		packageParts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+
+	return strings.Join(packageParts, "")
+}
+
+// GetTSFileName gets the typescript filename out of the proto file name, the extension will be appended after .pb.
+// files in the current directory don't have a directory in the result, e.g. foo.proto becomes foo.pb.ts,
+// it's a file name rather than an import path, which are made explicitly relative when the imports are resolved
+func GetTSFileName(fileName, extension string) string {
 	baseName := filepath.Base(fileName)
 	ext := filepath.Ext(fileName)
 	name := baseName[0 : len(baseName)-len(ext)]
-	packageParts := strings.Split(packageName, ".")
+	return path.Join(filepath.Dir(fileName), name+".pb"+extension)
+}
 
-	if packageName != "" {
-		for i, p := range packageParts {
-			packageParts[i] = strings.ToUpper(p[:1]) + p[1:]
+// GetPackageTSFileName returns the name of the generated file inside the nested directories of the dotted package name,
+// e.g. a/b/c.proto with package x.y will be generated as x/y/c.pb.ts
+func GetPackageTSFileName(packageName, fileName, extension string) string {
+	dir := path.Join(strings.Split(packageName, ".")...)
+	return path.Join(dir, path.Base(GetTSFileName(fileName, extension)))
+}
+
+// TrimTSExtension removes the extension typescript module resolution appends to the import path,
+// e.g. foo.pb.d.ts will be imported as foo.pb. Custom extensions like .gen.ts will keep the part before .ts
+func TrimTSExtension(fileName string) string {
+	for _, ext := range []string{".d.ts", ".ts", ".tsx"} {
+		if strings.HasSuffix(fileName, ext) {
+			return strings.TrimSuffix(fileName, ext)
 		}
 	}
 
-	return strings.Join(packageParts, "") + strings.ToUpper(name[:1]) + name[1:]
+	return fileName
 }
 
-// GetTSFileName gets the typescript filename out of the proto file name
-func GetTSFileName(fileName string) string {
-	baseName := filepath.Base(fileName)
-	ext := filepath.Ext(fileName)
-	name := baseName[0 : len(baseName)-len(ext)]
-	return path.Join(filepath.Dir(fileName), name+".pb.ts")
+// GetServiceTSFileName returns the name of the file a service is generated into, next to the generated file of its proto file,
+// e.g. the service Foo of foo.pb.ts is generated into foo.pb.Foo.client.ts
+func GetServiceTSFileName(tsFileName, serviceName string) string {
+	name := TrimTSExtension(tsFileName)
+	return name + "." + serviceName + ".client" + strings.TrimPrefix(tsFileName, name)
 }
 
 // Type is an interface to get type out of field and method arguments
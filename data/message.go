@@ -10,16 +10,23 @@ type Message struct {
 	FQType string
 	// Enums is a list of NestedEnums inside
 	Enums []*NestedEnum
-	// Fields is a list of fields to render
+	// Fields is a list of fields to render, in the order they are declared in the proto file
 	Fields []*Field
 	// NonOneOfFields contains a subset of fields that are not in the one-of groups
 	NonOneOfFields []*Field
 	// Message is the nested messages defined inside the message
 	Messages []*Message
 	// OneOfFieldsGroups is the grouped list of one of fields with same index. so that renderer can render the clearing of other fields on set.
+	// templates range over the groups in the order of the index, which is the order the one ofs are declared in
 	OneOfFieldsGroups map[int32][]*Field
 	// OneOfFieldNames is the names of one of fields with same index. so that renderer can render the clearing of other fields on set.
 	OneOfFieldsNames map[int32]string
+	// Comment is the comment attached to the message in the proto file
+	Comment string
+	// SourceLocation is the proto file and the line the message is declared at, e.g. foo.proto:42, when source locations are emitted
+	SourceLocation string
+	// IsDeprecated indicates the message is marked as deprecated in the proto file
+	IsDeprecated bool
 }
 
 // HasOneOfFields returns true when the message has a one of field.
@@ -58,6 +65,8 @@ type NestedEnum struct {
 // Field stores the information about a field inside message
 type Field struct {
 	Name string
+	// JSONName is the name of the field in JSON, it's populated from the json_name in the descriptor
+	JSONName string
 	// Type will be similar to NestedEnum.Type. Where scalar type and types inside
 	// the same file will be short type
 	// external types will have fully-qualified name and translated during render time
@@ -68,12 +77,61 @@ type Field struct {
 	// one of fields will have extra method clearXXX,
 	// and the setter accessor will clear out other fields in the group on set
 	IsOneOfField bool
+	// IsOptional indicates the field is a proto3 optional field, which has explicit presence
+	IsOptional bool
+	// IsRequired indicates the field is a proto2 required field
+	IsRequired bool
+	// IsDeprecated indicates the field is marked as deprecated in the proto file
+	IsDeprecated bool
 	// Message is the reference back to the parent message
 	Message *Message
 	// OneOfIndex is the index in the one of fields
 	OneOfIndex int32
-	// IsRepeated indicates whether the field is a repeated field
+	// IsRepeated indicates whether the field is a repeated field, map fields are not repeated fields
 	IsRepeated bool
+	// IsExtension indicates the field is an extension declared outside the message, its name is the fully qualified name of the extension in brackets
+	IsExtension bool
+	// IsMessage indicates the type of the field is a message, including the well-known types rendered as primitives
+	IsMessage bool
+	// Comment is the leading and trailing comment attached to the field in the proto file
+	Comment string
+	// Rules are the protoc-gen-validate or buf.validate constraints of the field, nil when it has none or validators aren't emitted
+	Rules *FieldRules
+}
+
+// FieldRules are the constraints of a field declared with the validate.rules option of protoc-gen-validate or the
+// buf.validate.field option of protovalidate, only the common rules are supported
+type FieldRules struct {
+	// Required indicates the field must be set
+	Required bool
+	// Const is the only value allowed for a numeric field, rendered as a number literal, empty when unset
+	Const string
+	// Lt is the exclusive upper bound of a numeric field, rendered as a number literal, empty when unset
+	Lt string
+	// Lte is the inclusive upper bound of a numeric field, rendered as a number literal, empty when unset
+	Lte string
+	// Gt is the exclusive lower bound of a numeric field, rendered as a number literal, empty when unset
+	Gt string
+	// Gte is the inclusive lower bound of a numeric field, rendered as a number literal, empty when unset
+	Gte string
+	// Len is the exact number of characters of a string field
+	Len *uint64
+	// MinLen is the minimum number of characters of a string field
+	MinLen *uint64
+	// MaxLen is the maximum number of characters of a string field
+	MaxLen *uint64
+	// Pattern is the regular expression a string field matches, empty when unset
+	Pattern string
+	// Prefix is the prefix of a string field, empty when unset
+	Prefix string
+	// Suffix is the suffix of a string field, empty when unset
+	Suffix string
+	// Contains is the substring of a string field, empty when unset
+	Contains string
+	// MinItems is the minimum number of elements of a repeated field or entries of a map field
+	MinItems *uint64
+	// MaxItems is the maximum number of elements of a repeated field or entries of a map field
+	MaxItems *uint64
 }
 
 // GetType returns some information of the type to aid the rendering
@@ -4,8 +4,14 @@ package data
 type Service struct {
 	// Name is the name of the Service
 	Name string
+	// FQType is the fully qualified name of the service
+	FQType string
 	// Methods is a list of methods data
 	Methods []*Method
+	// Comment is the comment attached to the service in the proto file
+	Comment string
+	// SourceLocation is the proto file and the line the service is declared at, e.g. foo.proto:42, when source locations are emitted
+	SourceLocation string
 }
 
 // Services is an alias of Service array
@@ -60,12 +66,16 @@ type Method struct {
 	Output *MethodArgument
 	// ServerStreaming indicates the RPC call is a server streaming call
 	ServerStreaming bool
-	// ClientStreaming indicates the RPC call is a client streaming call, which will not be supported by GRPC Gateway
+	// ClientStreaming indicates the RPC call is a client streaming call, which is only kept for bidirectional streaming calls sent over WebSockets
 	ClientStreaming bool
 	// HTTPMethod indicates the http method for this function
 	HTTPMethod string
+	// Idempotent indicates the method is declared free of side effects or idempotent with the idempotency_level option
+	Idempotent bool
 	// HTTPBody is the path for request body in the body's payload
 	HTTPRequestBody *string
+	// Comment is the comment attached to the method in the proto file
+	Comment string
 }
 
 // MethodArgument stores the type information about method argument
@@ -76,6 +86,8 @@ type MethodArgument struct {
 	IsExternal bool
 	// IsRepeated indicates whether the field is a repeated field
 	IsRepeated bool
+	// IsEmpty indicates the type is google.protobuf.Empty, the request can be left out and the response is void
+	IsEmpty bool
 }
 
 // GetType returns some information of the type to aid the rendering
@@ -0,0 +1,154 @@
+package generator
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/iancoleman/strcase"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus" // nolint: depguard
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// barrelExport is the symbols a barrel file re-exports from a single generated file
+type barrelExport struct {
+	// ModuleIdentifier is the name the generated file is imported as with CommonJS require
+	ModuleIdentifier string
+	// SourceFile is the path of the generated file relative to the barrel file
+	SourceFile string
+	// Values are the symbols with runtime values, e.g. enums and services
+	Values []string
+	// Types are the symbols only exist as types, e.g. messages
+	Types []string
+}
+
+// generateBarrels generates an index file for each directory containing generated files, re-exporting all their symbols.
+// symbols defined in more than one file of the same directory are only exported from the first file in alphabetical order
+func (t *TypeScriptGRPCGatewayGenerator) generateBarrels(files []*data.File) ([]*plugin.CodeGeneratorResponse_File, error) {
+	filesByDir := make(map[string][]*data.File)
+	for _, f := range files {
+		dir := path.Dir(f.TSFileName)
+		filesByDir[dir] = append(filesByDir[dir], f)
+	}
+
+	dirs := make([]string, 0, len(filesByDir))
+	for dir := range filesByDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	tmpl := GetBarrelTemplate(t.Registry)
+	generated := make([]*plugin.CodeGeneratorResponse_File, 0, len(dirs))
+	for _, dir := range dirs {
+		dirFiles := filesByDir[dir]
+		sort.Slice(dirFiles, func(i, j int) bool {
+			return dirFiles[i].TSFileName < dirFiles[j].TSFileName
+		})
+
+		exportedBy := make(map[string]string)
+		exports := make([]*barrelExport, 0, len(dirFiles))
+		for _, f := range dirFiles {
+			sourceFile := data.TrimTSExtension(path.Base(f.TSFileName))
+			export := &barrelExport{
+				ModuleIdentifier: strcase.ToCamel(sourceFile),
+				SourceFile:       "./" + sourceFile,
+			}
+
+			values, types := t.getExportedSymbols(f)
+			for _, symbols := range []struct {
+				names  []string
+				target *[]string
+			}{{values, &export.Values}, {types, &export.Types}} {
+				for _, name := range symbols.names {
+					if previous, ok := exportedBy[name]; ok {
+						if t.Registry.Strict {
+							return nil, errors.Errorf("%s is defined in both %s and %s, cannot re-export it from the barrel file", name, previous, f.TSFileName)
+						}
+						log.Warnf("%s is defined in both %s and %s, only the one in %s is re-exported from the barrel file", name, previous, f.TSFileName, previous)
+						continue
+					}
+
+					exportedBy[name] = f.TSFileName
+					*symbols.target = append(*symbols.target, name)
+				}
+			}
+
+			if len(export.Values) > 0 || len(export.Types) > 0 {
+				exports = append(exports, export)
+			}
+		}
+
+		w := bytes.NewBufferString("")
+		fileName := path.Join(dir, "index"+t.Registry.TSFileExtension)
+		if err := tmpl.Execute(w, exports); err != nil {
+			return nil, errors.Wrapf(err, "error generating barrel file %s", fileName)
+		}
+
+		content := strings.TrimSpace(w.String())
+		generated = append(generated, &plugin.CodeGeneratorResponse_File{
+			Name:    &fileName,
+			Content: &content,
+		})
+	}
+
+	return generated, nil
+}
+
+// getExportedSymbols returns the symbols exported by the generated file, separated by whether they have runtime values
+func (t *TypeScriptGRPCGatewayGenerator) getExportedSymbols(f *data.File) (values, types []string) {
+	for _, e := range f.Enums {
+		if t.Registry.EnumStyle == registry.EnumStyleStringUnion {
+			types = append(types, e.Name)
+		} else {
+			values = append(values, e.Name)
+		}
+		values = append(values, e.Name+"Values", e.Name+"ByNumber", untitle(e.Name)+"FromJSON", untitle(e.Name)+"ToJSON", untitle(e.Name)+"ToNumber")
+	}
+
+	for _, m := range f.Messages {
+		if t.Registry.MessageKind == registry.MessageKindClass {
+			values = append(values, m.Name)
+		} else {
+			types = append(types, m.Name)
+		}
+		for _, f := range getInt64KeyedMaps(t.Registry)(m) {
+			values = append(values, int64MapEntriesName(m, f))
+		}
+		if t.Registry.EmitFactories {
+			values = append(values, "create"+m.Name)
+		}
+		if t.Registry.EmitGuards {
+			values = append(values, "is"+m.Name)
+		}
+		if t.Registry.EmitValidators && len(getFieldValidations(t.Registry)(m)) > 0 {
+			values = append(values, "validate"+m.Name)
+		}
+	}
+
+	for _, s := range f.Services {
+		values = append(values, s.Name, s.Name+"Methods")
+	}
+	if t.Registry.APIObject && len(f.Services) > 0 {
+		values = append(values, "createApi", "api")
+	}
+
+	return values, types
+}
+
+// GetBarrelTemplate returns the go template for barrel files
+func GetBarrelTemplate(r *registry.Registry) *template.Template {
+	t := template.New("barrel")
+	t = t.Funcs(template.FuncMap{
+		"join": strings.Join,
+		"moduleSystem": func() string {
+			return r.ModuleSystem
+		},
+	})
+	return template.Must(t.Parse(barrelTmpl))
+}
@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// bytesFields describes where bytes fields are inside messages, so that the clients can convert them from and into base64.
+// it's keyed by the fully qualified name of the message, then the rendered field name. the value of a field is either
+// "bytes", the fully qualified name of a message containing bytes fields, or either of them prefixed by "map:" for maps
+type bytesFields map[string]map[string]string
+
+// getBytesFields returns the bytes fields of the given message types and all the messages reachable from them
+func getBytesFields(r *registry.Registry, fqTypes ...string) bytesFields {
+	// collect all the messages reachable from the given types, recursive messages are only visited once
+	reachable := make(map[string]*registry.TypeInformation)
+	var visit func(fqType string)
+	visit = func(fqType string) {
+		typeInfo, ok := r.Types[fqType]
+		if !ok || reachable[fqType] != nil {
+			return
+		}
+
+		if typeInfo.IsMapEntry {
+			visit(typeInfo.ValueType.Type)
+			return
+		}
+
+		reachable[fqType] = typeInfo
+		for _, f := range typeInfo.Fields {
+			visit(f.Type)
+		}
+	}
+	for _, fqType := range fqTypes {
+		visit(fqType)
+	}
+
+	fields := make(bytesFields)
+	codec := func(fieldType string) string {
+		if fieldType == "bytes" {
+			return "bytes"
+		}
+
+		typeInfo, ok := r.Types[fieldType]
+		if !ok {
+			return ""
+		}
+
+		if typeInfo.IsMapEntry {
+			valueType := typeInfo.ValueType.Type
+			if _, ok := fields[valueType]; ok || valueType == "bytes" {
+				return "map:" + valueType
+			}
+			return ""
+		}
+
+		if _, ok := fields[fieldType]; ok {
+			return fieldType
+		}
+
+		return ""
+	}
+
+	// a message contains bytes when any of its fields is bytes or a message containing bytes, iterate until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for fqType, typeInfo := range reachable {
+			for _, f := range typeInfo.Fields {
+				c := codec(f.Type)
+				if c == "" {
+					continue
+				}
+
+				if _, ok := fields[fqType]; !ok {
+					fields[fqType] = make(map[string]string)
+				}
+
+				name := renderFieldName(r, f.Name, f.JSONName)
+				if fields[fqType][name] != c {
+					fields[fqType][name] = c
+					changed = true
+				}
+			}
+		}
+	}
+
+	return fields
+}
+
+// needsBytesConversion returns whether the bytes inside the method argument need to be converted by the client
+func needsBytesConversion(r *registry.Registry) func(arg *data.MethodArgument) bool {
+	return func(arg *data.MethodArgument) bool {
+		if r.BytesType != registry.BytesTypeUint8Array {
+			return false
+		}
+
+		_, ok := getBytesFields(r, arg.Type)[arg.Type]
+		return ok
+	}
+}
+
+// renderBytesFields renders the bytes fields of all the method arguments inside the services as a typescript object literal
+func renderBytesFields(r *registry.Registry) func(services data.Services) string {
+	return func(services data.Services) string {
+		if r.BytesType != registry.BytesTypeUint8Array {
+			return ""
+		}
+
+		fqTypes := make([]string, 0)
+		for _, s := range services {
+			for _, m := range s.Methods {
+				fqTypes = append(fqTypes, m.Input.Type, m.Output.Type)
+			}
+		}
+
+		fields := getBytesFields(r, fqTypes...)
+		if len(fields) == 0 {
+			return ""
+		}
+
+		messages := make([]string, 0, len(fields))
+		for fqType := range fields {
+			messages = append(messages, fqType)
+		}
+		sort.Strings(messages)
+
+		var b strings.Builder
+		b.WriteString("const bytesFields: fm.BytesFields = {\n")
+		for _, fqType := range messages {
+			names := make([]string, 0, len(fields[fqType]))
+			for name := range fields[fqType] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			entries := make([]string, 0, len(names))
+			for _, name := range names {
+				entries = append(entries, fmt.Sprintf("%q: %q", name, fields[fqType][name]))
+			}
+			fmt.Fprintf(&b, "  %q: {%s},\n", fqType, strings.Join(entries, ", "))
+		}
+		b.WriteString("}\n\n")
+
+		return b.String()
+	}
+}
+
+// needsUnaryResponseDecoding returns whether the response of any unary method inside the services needs to be decoded by the client
+func needsUnaryResponseDecoding(r *registry.Registry) func(services data.Services) bool {
+	return func(services data.Services) bool {
+		for _, s := range services {
+			for _, m := range s.Methods {
+				if !m.ServerStreaming && needsResponseDecoding(r)(m.Output) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"encoding/json"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pkg/errors"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+)
+
+// DryRunFileName is the name of the file summarising the generation in dry run mode
+const DryRunFileName = "ts_dry_run.json"
+
+// dryRun is the summary of what the generator would generate
+type dryRun struct {
+	// GeneratedFiles are the names of all the files that would be generated, including the fetch module and the barrels
+	GeneratedFiles []string `json:"generatedFiles"`
+	// Files are the analysed files rendered into the generated files
+	Files []*dryRunFile `json:"files"`
+}
+
+// dryRunFile is the summary of a single analysed file, it doesn't refer back to the data.File
+// as the fields of the messages refer back to the messages
+type dryRunFile struct {
+	Name         string              `json:"name"`
+	TSFileName   string              `json:"tsFileName"`
+	Dependencies []*dryRunDependency `json:"dependencies"`
+	Enums        []string            `json:"enums"`
+	Messages     []string            `json:"messages"`
+	Services     []string            `json:"services"`
+}
+
+// dryRunDependency is a resolved import of a file
+type dryRunDependency struct {
+	ModuleIdentifier string `json:"moduleIdentifier"`
+	SourceFile       string `json:"sourceFile"`
+}
+
+// generateDryRun returns the file summarising the generated files and the analysed files rendered into them
+func generateDryRun(generated []*plugin.CodeGeneratorResponse_File, files []*data.File) (*plugin.CodeGeneratorResponse_File, error) {
+	summary := &dryRun{
+		GeneratedFiles: make([]string, 0, len(generated)),
+		Files:          make([]*dryRunFile, 0, len(files)),
+	}
+
+	for _, f := range generated {
+		summary.GeneratedFiles = append(summary.GeneratedFiles, f.GetName())
+	}
+
+	for _, f := range files {
+		file := &dryRunFile{
+			Name:         f.Name,
+			TSFileName:   f.TSFileName,
+			Dependencies: make([]*dryRunDependency, 0, len(f.Dependencies)),
+			Enums:        make([]string, 0, len(f.Enums)),
+			Messages:     make([]string, 0, len(f.Messages)),
+			Services:     make([]string, 0, len(f.Services)),
+		}
+
+		for _, d := range f.StableDependencies() {
+			file.Dependencies = append(file.Dependencies, &dryRunDependency{
+				ModuleIdentifier: d.ModuleIdentifier,
+				SourceFile:       d.SourceFile,
+			})
+		}
+		for _, e := range f.Enums {
+			file.Enums = append(file.Enums, e.Name)
+		}
+		for _, m := range f.Messages {
+			file.Messages = append(file.Messages, m.Name)
+		}
+		for _, s := range f.Services {
+			file.Services = append(file.Services, s.Name)
+		}
+
+		summary.Files = append(summary.Files, file)
+	}
+
+	content, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "error serializing dry run summary")
+	}
+
+	name := DryRunFileName
+	contentStr := string(content)
+	return &plugin.CodeGeneratorResponse_File{
+		Name:    &name,
+		Content: &contentStr,
+	}, nil
+}
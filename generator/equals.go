@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// equalsHelpers lists the helper functions the equals functions of the messages call, which are only generated when they are called
+type equalsHelpers struct {
+	Value      bool
+	Array      bool
+	Map        bool
+	Date       bool
+	Bytes      bool
+	JSON       bool
+	TypedArray bool
+}
+
+// renderEqualsCheck renders a javascript expression checking whether the field of the messages a and b are equal
+func renderEqualsCheck(r *registry.Registry) func(f *data.Field) string {
+	return func(f *data.Field) string {
+		expression, _ := equalsCheck(r, f)
+		return expression
+	}
+}
+
+// getEqualsHelpers returns the helper functions called by the equals functions of the messages
+func getEqualsHelpers(r *registry.Registry) func(messages []*data.Message) *equalsHelpers {
+	return func(messages []*data.Message) *equalsHelpers {
+		helpers := &equalsHelpers{}
+		for _, m := range messages {
+			for _, f := range m.Fields {
+				_, used := equalsCheck(r, f)
+				helpers.Value = helpers.Value || used.Value
+				helpers.Array = helpers.Array || used.Array
+				helpers.Map = helpers.Map || used.Map
+				helpers.Date = helpers.Date || used.Date
+				helpers.Bytes = helpers.Bytes || used.Bytes
+				helpers.JSON = helpers.JSON || used.JSON
+				helpers.TypedArray = helpers.TypedArray || used.TypedArray
+			}
+		}
+
+		// JSON values are compared element by element
+		helpers.Array = helpers.Array || helpers.JSON
+		helpers.Map = helpers.Map || helpers.JSON
+		return helpers
+	}
+}
+
+// equalsCheck renders the expression comparing the field of the messages a and b, along with the helper functions it calls.
+// repeated fields are compared element by element, and maps regardless of the order of their keys
+func equalsCheck(r *registry.Registry, f *data.Field) (string, *equalsHelpers) {
+	helpers := &equalsHelpers{}
+	a, b := "a"+propertyAccess(r, f), "b"+propertyAccess(r, f)
+
+	if typeInfo, ok := r.Types[f.Type]; ok && typeInfo.IsMapEntry {
+		helpers.Map = true
+		return fmt.Sprintf("equalsMap(%s, %s, %s)", a, b, equalsComparator(r, typeInfo.ValueType, helpers)), helpers
+	}
+
+	if typedArrayType(r, f) != "" {
+		helpers.Value = true
+		helpers.TypedArray = true
+		return fmt.Sprintf("equalsValue(%s, %s, equalsTypedArray)", a, b), helpers
+	}
+
+	comparator := equalsComparator(r, f, helpers)
+	if f.IsRepeated {
+		helpers.Array = true
+		return fmt.Sprintf("equalsArray(%s, %s, %s)", a, b, comparator), helpers
+	}
+
+	if comparator == strictEqualsComparator {
+		return fmt.Sprintf("%s === %s", a, b), helpers
+	}
+
+	helpers.Value = true
+	return fmt.Sprintf("equalsValue(%s, %s, %s)", a, b, comparator), helpers
+}
+
+// strictEqualsComparator compares the values of the primitive types and the enums
+const strictEqualsComparator = "(x, y) => x === y"
+
+// equalsComparator renders the function comparing two values of the type, e.g. equalsFoo for messages.
+// bytes are compared by value, both as base64 strings and as Uint8Array
+func equalsComparator(r *registry.Registry, fieldType data.Type, helpers *equalsHelpers) string {
+	info := fieldType.GetType()
+	if strings.Index(info.Type, ".") != 0 {
+		switch mapScalaType(r, info.Type) {
+		case "string", "number", "bigint", "boolean", "null":
+			return strictEqualsComparator
+		case "Date":
+			helpers.Date = true
+			return "equalsDate"
+		case "Uint8Array":
+			helpers.Bytes = true
+			return "equalsBytes"
+		default:
+			// the JSON values of structs, values, lists and Any
+			helpers.JSON = true
+			return "equalsJSON"
+		}
+	}
+
+	typeInfo, ok := r.Types[info.Type]
+	if !ok || typeInfo.ProtoType == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+		return strictEqualsComparator
+	}
+
+	equals := "equals" + typeInfo.PackageIdentifier
+	if info.IsExternal && !r.IsBundled(typeInfo.File) {
+		equals = data.GetModuleName(typeInfo.Package, typeInfo.File) + "." + equals
+	}
+	return equals
+}
+
+// propertyAccess renders the access of the field on an object, names which aren't identifiers are accessed with brackets
+func propertyAccess(r *registry.Registry, f *data.Field) string {
+	name := renderFieldName(r, f.Name, f.JSONName)
+	if !identifierRegexp.MatchString(name) {
+		return "[" + strconv.Quote(name) + "]"
+	}
+
+	return "." + name
+}
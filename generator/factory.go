@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"fmt"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// renderDefaultValue renders the proto3 default value of the field as a typescript expression.
+// an empty string is returned for the fields left undefined, which are messages, oneof fields, optional fields and extensions
+func renderDefaultValue(r *registry.Registry) func(f *data.Field) string {
+	return func(f *data.Field) string {
+		typeInfo, ok := r.Types[f.Type]
+		if ok && typeInfo.IsMapEntry {
+			return "{}"
+		}
+
+		if typedArray := typedArrayType(r, f); typedArray != "" {
+			return "new " + typedArray + "()"
+		}
+
+		if f.IsRepeated {
+			return "[]"
+		}
+
+		if f.IsMessage || f.IsOneOfField || f.IsOptional || f.IsExtension {
+			return ""
+		}
+
+		if ok && typeInfo.ProtoType == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+			if len(typeInfo.EnumValues) == 0 {
+				return ""
+			}
+
+			if r.EnumStyle == registry.EnumStyleStringUnion {
+				return fmt.Sprintf("%q", typeInfo.EnumValues[0])
+			}
+			return tsType(r, f) + "." + typeInfo.EnumValues[0]
+		}
+
+		if isInt64Type(f.Type) {
+			switch r.Int64Type {
+			case registry.Int64TypeBigInt:
+				return "BigInt(0)"
+			case registry.Int64TypeNumber:
+				return "0"
+			}
+			// 64-bit integers are encoded as strings in JSON
+			return `"0"`
+		}
+
+		switch mapScalaType(r, f.Type) {
+		case "string":
+			return `""`
+		case "number":
+			return "0"
+		case "boolean":
+			return "false"
+		case "Uint8Array":
+			return "new Uint8Array()"
+		}
+
+		return ""
+	}
+}
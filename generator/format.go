@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// codeState is where the formatter is inside the generated code
+type codeState int
+
+const (
+	stateCode codeState = iota
+	stateLineComment
+	stateBlockComment
+	stateDoubleQuoted
+	stateSingleQuoted
+	stateTemplate
+)
+
+// formatCode re-indents the generated code and re-quotes its strings according to ts_indent and ts_quote_style.
+// the templates are written with two spaces and double quotes, the code is only tokenized as far as needed to leave
+// the comments, the content of the strings and the template literals as they are
+func formatCode(code, indent, quoteStyle string) string {
+	if indent == registry.DefaultIndent && quoteStyle == registry.QuoteStyleDouble {
+		return code
+	}
+
+	singleQuotes := quoteStyle == registry.QuoteStyleSingle
+	var b strings.Builder
+	b.Grow(len(code))
+
+	state := stateCode
+	// templateBraces are the braces opened inside each of the nested ${} of template literals
+	templateBraces := make([]int, 0)
+	lineStart := true
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if lineStart && (state == stateCode || state == stateBlockComment) {
+			spaces := 0
+			for i+spaces < len(code) && code[i+spaces] == ' ' {
+				spaces++
+			}
+			i += spaces
+			if i == len(code) {
+				break
+			}
+			c = code[i]
+			if c != '\n' {
+				b.WriteString(strings.Repeat(indent, spaces/len(registry.DefaultIndent)))
+				b.WriteString(strings.Repeat(" ", spaces%len(registry.DefaultIndent)))
+			}
+		}
+		lineStart = c == '\n'
+
+		next := byte(0)
+		if i+1 < len(code) {
+			next = code[i+1]
+		}
+
+		switch state {
+		case stateCode:
+			switch {
+			case c == '/' && (next == '/' || next == '*'):
+				b.WriteByte(c)
+				b.WriteByte(next)
+				i++
+				state = stateLineComment
+				if next == '*' {
+					state = stateBlockComment
+				}
+				continue
+			case c == '"':
+				state = stateDoubleQuoted
+				if singleQuotes {
+					c = '\''
+				}
+			case c == '\'':
+				state = stateSingleQuoted
+			case c == '`':
+				state = stateTemplate
+			case c == '{' && len(templateBraces) > 0:
+				templateBraces[len(templateBraces)-1]++
+			case c == '}' && len(templateBraces) > 0:
+				if templateBraces[len(templateBraces)-1] == 0 {
+					templateBraces = templateBraces[:len(templateBraces)-1]
+					state = stateTemplate
+				} else {
+					templateBraces[len(templateBraces)-1]--
+				}
+			}
+		case stateLineComment:
+			if c == '\n' {
+				state = stateCode
+			}
+		case stateBlockComment:
+			if c == '*' && next == '/' {
+				b.WriteString("*/")
+				i++
+				state = stateCode
+				continue
+			}
+		case stateDoubleQuoted:
+			switch {
+			case c == '\\' && next != 0:
+				i++
+				if singleQuotes && next == '"' {
+					b.WriteByte('"')
+				} else {
+					b.WriteByte(c)
+					b.WriteByte(next)
+				}
+				continue
+			case c == '"':
+				state = stateCode
+				if singleQuotes {
+					c = '\''
+				}
+			case c == '\'' && singleQuotes:
+				b.WriteString(`\'`)
+				continue
+			}
+		case stateSingleQuoted, stateTemplate:
+			switch {
+			case c == '\\' && next != 0:
+				b.WriteByte(c)
+				b.WriteByte(next)
+				i++
+				continue
+			case c == '\'' && state == stateSingleQuoted, c == '`' && state == stateTemplate:
+				state = stateCode
+			case c == '$' && next == '{' && state == stateTemplate:
+				b.WriteString("${")
+				i++
+				templateBraces = append(templateBraces, 0)
+				state = stateCode
+				continue
+			}
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
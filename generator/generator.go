@@ -3,7 +3,9 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -35,19 +37,93 @@ func New(paramsMap map[string]string) (*TypeScriptGRPCGatewayGenerator, error) {
 // Generate take a code generator request and returns a response. it analyse request with registry and use the generated data to render ts files
 func (t *TypeScriptGRPCGatewayGenerator) Generate(req *plugin.CodeGeneratorRequest) (*plugin.CodeGeneratorResponse, error) {
 	resp := &plugin.CodeGeneratorResponse{}
+	// proto3 optional fields are supported, protoc will refuse to run the plugin without declaring it
+	supportedFeatures := uint64(plugin.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+	resp.SupportedFeatures = &supportedFeatures
 
 	filesData, err := t.Registry.Analyse(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "error analysing proto files")
 	}
-	tmpl := GetTemplate(t.Registry)
 	log.Debugf("files to generate %v", req.GetFileToGenerate())
 
+	// files are rendered in the order of the request, so that the response is the same across runs
+	filesToRender := make([]*data.File, 0, len(req.GetFileToGenerate()))
+	for _, f := range req.GetFileToGenerate() {
+		if !t.Registry.IsFileToGenerate(f) {
+			// the file is excluded by ts_exclude
+			continue
+		}
+		filesToRender = append(filesToRender, filesData[f])
+	}
+
+	resp.File, err = t.renderFiles(filesToRender)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Render renders the files to generate out of the data returned by Analyse of the registry, and returns the content of the generated files
+// keyed by their names, so that the generator can run in-process without protoc. the files are rendered in the order of their names
+func (t *TypeScriptGRPCGatewayGenerator) Render(filesData map[string]*data.File) (map[string]string, error) {
+	names := make([]string, 0, len(filesData))
+	for name := range filesData {
+		if t.Registry.IsFileToGenerate(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	filesToRender := make([]*data.File, 0, len(names))
+	for _, name := range names {
+		filesToRender = append(filesToRender, filesData[name])
+	}
+
+	files, err := t.renderFiles(filesToRender)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make(map[string]string, len(files))
+	for _, f := range files {
+		contents[f.GetName()] = f.GetContent()
+	}
+
+	return contents, nil
+}
+
+// renderFiles renders the files along with the barrels and the fetch module they need
+func (t *TypeScriptGRPCGatewayGenerator) renderFiles(filesToRender []*data.File) ([]*plugin.CodeGeneratorResponse_File, error) {
+	tmpl := GetTemplate(t.Registry)
+	if t.Registry.TemplateDir != "" {
+		if err := LoadTemplateDir(tmpl, t.Registry.TemplateDir); err != nil {
+			return nil, errors.Wrap(err, "error loading custom templates")
+		}
+	}
+
+	if t.Registry.Bundle != "" {
+		// render all files to generate into the bundle in the order of the request
+		log.Debugf("bundling %d files into %s", len(filesToRender), t.Registry.Bundle)
+		filesToRender = []*data.File{data.NewBundleFile(t.Registry.Bundle, filesToRender)}
+	}
+
+	// the files the services are generated into are rendered right after the file of their proto file
+	withServiceFiles := make([]*data.File, 0, len(filesToRender))
+	for _, fileData := range filesToRender {
+		withServiceFiles = append(withServiceFiles, fileData)
+		withServiceFiles = append(withServiceFiles, fileData.ServiceFiles...)
+	}
+	filesToRender = withServiceFiles
+
+	files := make([]*plugin.CodeGeneratorResponse_File, 0, len(filesToRender))
 	needToGenerateFetchModule := false
+	generatedFiles := make([]*data.File, 0, len(filesToRender))
 	// feed fileData into rendering process
-	for _, fileData := range filesData {
-		if !t.Registry.IsFileToGenerate(fileData.Name) {
-			log.Debugf("file %s is not the file to generate, skipping", fileData.Name)
+	for _, fileData := range filesToRender {
+		if t.Registry.SkipEmpty && fileData.IsEmpty() {
+			log.Debugf("file %s has nothing to render, skipping", fileData.Name)
 			continue
 		}
 
@@ -56,10 +132,47 @@ func (t *TypeScriptGRPCGatewayGenerator) Generate(req *plugin.CodeGeneratorReque
 		if err != nil {
 			return nil, errors.Wrap(err, "error generating file")
 		}
-		resp.File = append(resp.File, generated)
+		files = append(files, generated)
+		generatedFiles = append(generatedFiles, fileData)
 		needToGenerateFetchModule = needToGenerateFetchModule || fileData.Services.NeedsFetchModule()
 	}
 
+	if t.Registry.EmitBarrels {
+		barrels, err := t.generateBarrels(generatedFiles)
+		if err != nil {
+			return nil, errors.Wrap(err, "error generating barrel files")
+		}
+
+		files = append(files, barrels...)
+	}
+
+	if t.Registry.EmitMocks {
+		mocks, err := t.generateMocks(generatedFiles)
+		if err != nil {
+			return nil, errors.Wrap(err, "error generating mock files")
+		}
+
+		files = append(files, mocks...)
+	}
+
+	if t.Registry.EmitReactQuery {
+		queries, err := t.generateReactQuery(generatedFiles)
+		if err != nil {
+			return nil, errors.Wrap(err, "error generating react query files")
+		}
+
+		files = append(files, queries...)
+	}
+
+	if t.Registry.EmitRollupDTS {
+		rollup, err := t.generateRollup(generatedFiles)
+		if err != nil {
+			return nil, errors.Wrap(err, "error generating rollup declaration file")
+		}
+
+		files = append(files, rollup)
+	}
+
 	if needToGenerateFetchModule {
 		// generate fetch module
 		fetchTmpl := GetFetchModuleTemplate()
@@ -69,10 +182,30 @@ func (t *TypeScriptGRPCGatewayGenerator) Generate(req *plugin.CodeGeneratorReque
 			return nil, errors.Wrap(err, "error generating fetch module")
 		}
 
-		resp.File = append(resp.File, generatedFetch)
+		files = append(files, generatedFetch)
 	}
 
-	return resp, nil
+	// the templates are written with two spaces and double quotes, the generated files are formatted once they are all rendered
+	for _, f := range files {
+		content := formatCode(f.GetContent(), t.Registry.Indent, t.Registry.QuoteStyle)
+		f.Content = &content
+	}
+
+	if t.Registry.Verbose {
+		writeSummary(os.Stderr, t.Registry, files)
+	}
+
+	if t.Registry.DryRun {
+		// nothing but the summary is written in dry run mode
+		dryRun, err := generateDryRun(files, generatedFiles)
+		if err != nil {
+			return nil, errors.Wrap(err, "error generating dry run summary")
+		}
+
+		files = []*plugin.CodeGeneratorResponse_File{dryRun}
+	}
+
+	return files, nil
 }
 
 func (t *TypeScriptGRPCGatewayGenerator) generateFile(fileData *data.File, tmpl *template.Template) (*plugin.CodeGeneratorResponse_File, error) {
@@ -88,7 +221,7 @@ func (t *TypeScriptGRPCGatewayGenerator) generateFile(fileData *data.File, tmpl
 	}
 
 	fileName := fileData.TSFileName
-	content := strings.TrimSpace(w.String())
+	content := sourceHashHeader(fileData) + strings.TrimSpace(w.String())
 
 	return &plugin.CodeGeneratorResponse_File{
 		Name:           &fileName,
@@ -97,6 +230,16 @@ func (t *TypeScriptGRPCGatewayGenerator) generateFile(fileData *data.File, tmpl
 	}, nil
 }
 
+// sourceHashHeader renders the header with the source hash of the proto file the file is generated out of, so that CI can detect
+// generated files which weren't regenerated after the proto file changed. it's empty when source hashes aren't emitted
+func sourceHashHeader(f *data.File) string {
+	if f.SourceHash == "" {
+		return ""
+	}
+
+	return "// @generated by protoc-gen-grpc-gateway-ts\n// source-hash: sha256:" + f.SourceHash + "\n"
+}
+
 func (t *TypeScriptGRPCGatewayGenerator) generateFetchModule(tmpl *template.Template) (*plugin.CodeGeneratorResponse_File, error) {
 	w := bytes.NewBufferString("")
 	fileName := filepath.Join(t.Registry.FetchModuleDirectory, t.Registry.FetchModuleFilename)
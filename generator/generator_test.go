@@ -0,0 +1,2759 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// generate runs the generator against the files described in protobuf text format, all of which are files to generate
+// it returns the generated content keyed by the generated file name
+func generate(t *testing.T, params map[string]string, files ...string) map[string]string {
+	req := &plugin.CodeGeneratorRequest{}
+	for _, f := range files {
+		fileDescriptor := &descriptorpb.FileDescriptorProto{}
+		require.NoError(t, prototext.Unmarshal([]byte(f), fileDescriptor))
+		req.ProtoFile = append(req.ProtoFile, fileDescriptor)
+		req.FileToGenerate = append(req.FileToGenerate, fileDescriptor.GetName())
+	}
+
+	g, err := New(params)
+	require.NoError(t, err)
+
+	resp, err := g.Generate(req)
+	require.NoError(t, err)
+
+	generated := make(map[string]string)
+	for _, f := range resp.GetFile() {
+		generated[f.GetName()] = f.GetContent()
+	}
+
+	return generated
+}
+
+func TestOneOfFieldsExcludeProto3Optional(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "oneof.proto"
+package: "oneof"
+syntax: "proto3"
+message_type {
+  name: "Request"
+  field { name: "application" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 json_name: "application" }
+  field { name: "service" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 json_name: "service" }
+  field { name: "host" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 1 proto3_optional: true json_name: "host" }
+  oneof_decl { name: "identifier" }
+  oneof_decl { name: "_host" }
+}
+`)
+
+	content := generated["oneof.pb.ts"]
+	assert.Contains(t, content, "& OneOf<{ application: string; service: string }>")
+	assert.Contains(t, content, "host?: string | undefined")
+	assert.NotContains(t, content, "OneOf<{ host")
+}
+
+func TestFieldsAreRenderedInDeclarationOrder(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "order.proto"
+package: "order"
+syntax: "proto3"
+message_type {
+  name: "Ordered"
+  field { name: "zeta" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "zeta" }
+  field { name: "alpha" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "alpha" }
+  field { name: "mu" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "mu" }
+  field { name: "second_b" number: 7 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "secondB" oneof_index: 1 }
+  field { name: "first_b" number: 6 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "firstB" oneof_index: 0 }
+  field { name: "second_a" number: 5 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "secondA" oneof_index: 1 }
+  field { name: "first_a" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "firstA" oneof_index: 0 }
+  oneof_decl { name: "first" }
+  oneof_decl { name: "second" }
+}
+`)
+
+	assert.Contains(t, generated["order.pb.ts"], `type BaseOrdered = {
+  zeta?: string
+  alpha?: string
+  mu?: string
+}
+
+export type Ordered = BaseOrdered
+  & OneOf<{ firstB: string; firstA: string }>
+  & OneOf<{ secondB: string; secondA: string }>`)
+}
+
+func TestReservedFieldsAndValuesAreIgnored(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_factories": "true"}, `
+name: "reserved.proto"
+package: "reserved"
+syntax: "proto3"
+enum_type {
+  name: "State"
+  value { name: "STATE_UNSPECIFIED" number: 0 }
+  value { name: "STATE_DONE" number: 3 }
+  reserved_range { start: 1 end: 2 }
+  reserved_name: "STATE_OLD"
+}
+message_type {
+  name: "Record"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }
+  field { name: "state" number: 3 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".reserved.State" json_name: "state" }
+  field { name: "note" number: 7 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "note" }
+  reserved_range { start: 2 end: 3 }
+  reserved_range { start: 4 end: 7 }
+  reserved_name: "legacy"
+}
+`)
+
+	content := generated["reserved.pb.ts"]
+	assert.Contains(t, content, "export type Record = {\n  id?: string\n  state?: State\n  note?: string\n}\n")
+	assert.Contains(t, content, "export enum State {\n  STATE_UNSPECIFIED = \"STATE_UNSPECIFIED\",\n  STATE_DONE = \"STATE_DONE\",\n}\n")
+	assert.Contains(t, content, "    id: \"\",\n    state: State.STATE_UNSPECIFIED,\n    note: \"\",\n")
+	assert.NotContains(t, content, "legacy")
+	assert.NotContains(t, content, "STATE_OLD")
+}
+
+func TestPackagelessFiles(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "vendor/a/common.proto"
+syntax: "proto3"
+message_type { name: "A" }
+`, `
+name: "vendor/b/common.proto"
+syntax: "proto3"
+message_type { name: "B" }
+`, `
+name: "pkg/c.proto"
+package: "pkg"
+syntax: "proto3"
+message_type { name: "C" }
+`, `
+name: "app/my-app.proto"
+syntax: "proto3"
+dependency: "vendor/a/common.proto"
+dependency: "vendor/b/common.proto"
+dependency: "pkg/c.proto"
+message_type {
+  name: "App"
+  field { name: "a" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".A" json_name: "a" }
+  field { name: "b" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".B" json_name: "b" }
+  field { name: "c" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".pkg.C" json_name: "c" }
+  field { name: "nested" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".App.Nested" json_name: "nested" }
+  nested_type { name: "Nested" }
+}
+`)
+
+	content := generated["app/my-app.pb.ts"]
+	// packageless files are told apart by their directories
+	assert.Contains(t, content, `import * as VendorACommon from "../vendor/a/common.pb"`)
+	assert.Contains(t, content, `import * as VendorBCommon from "../vendor/b/common.pb"`)
+	assert.Contains(t, content, `import * as PkgC from "../pkg/c.pb"`)
+	assert.Contains(t, content, "a?: VendorACommon.A")
+	assert.Contains(t, content, "b?: VendorBCommon.B")
+	assert.Contains(t, content, "c?: PkgC.C")
+	assert.Contains(t, content, "nested?: AppNested")
+}
+
+func TestSkipEmpty(t *testing.T) {
+	files := []string{`
+name: "empty.proto"
+package: "empty"
+syntax: "proto3"
+`, `
+name: "message.proto"
+package: "message"
+syntax: "proto3"
+message_type { name: "Message" }
+`, `
+name: "service.proto"
+package: "service"
+syntax: "proto3"
+dependency: "message.proto"
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".message.Message" output_type: ".message.Message" }
+}
+`}
+
+	generated := generate(t, map[string]string{}, files...)
+	assert.Equal(t, "export default {}", generated["empty.pb.ts"])
+	assert.Contains(t, generated, "service.pb.ts")
+
+	generated = generate(t, map[string]string{"ts_skip_empty": "true"}, files...)
+	assert.NotContains(t, generated, "empty.pb.ts")
+	assert.Contains(t, generated, "service.pb.ts")
+}
+
+func TestBundleResolvesCollidingIdentifiers(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_bundle": "bundle.pb.ts"}, `
+name: "a.proto"
+package: "a"
+syntax: "proto3"
+message_type { name: "Request" }
+`, `
+name: "b.proto"
+package: "b"
+syntax: "proto3"
+dependency: "a.proto"
+message_type {
+  name: "Request"
+  field { name: "a" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".a.Request" json_name: "a" }
+}
+`)
+
+	require.Contains(t, generated, "bundle.pb.ts")
+	assert.NotContains(t, generated, "a.pb.ts")
+	assert.NotContains(t, generated, "b.pb.ts")
+
+	content := generated["bundle.pb.ts"]
+	assert.Contains(t, content, "export type ARequest = {")
+	assert.Contains(t, content, "export type BRequest = {")
+	assert.Contains(t, content, "a?: ARequest")
+	assert.NotContains(t, content, "import")
+}
+
+func TestServiceClientMergesDefaultInitReq(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" }
+}
+`)
+
+	content := generated["svc.pb.ts"]
+	assert.Contains(t, content, "constructor(initReq?: fm.InitReq) {")
+	assert.Contains(t, content, "static Call(req: Request, initReq?: fm.InitReq): Promise<Request> {")
+	assert.Contains(t, content, "return Service.Call(req, fm.mergeInitReq(this.initReq, initReq))")
+	assert.Contains(t, generated["fetch.pb.ts"], "export function mergeInitReq(defaults?: InitReq, init?: InitReq): InitReq {")
+}
+
+func TestServerStreamingIterable(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" }
+  method { name: "Watch" input_type: ".svc.Request" output_type: ".svc.Request" server_streaming: true }
+}
+`)
+
+	content := generated["svc.pb.ts"]
+	assert.Contains(t, content, "  static WatchIterable(req: Request, initReq?: fm.InitReq): AsyncIterable<Request> {\n    return fm.fetchStreamingIterable<Request, Request>(`/svc.Service/Watch`, {...initReq, method: \"POST\", body: JSON.stringify(req)})\n  }\n")
+	assert.Contains(t, content, "  WatchIterable(req: Request, initReq?: fm.InitReq): AsyncIterable<Request> {\n    return Service.WatchIterable(req, fm.mergeInitReq(this.initReq, initReq))\n  }\n")
+	// unary methods have no iterable variant
+	assert.NotContains(t, content, "CallIterable")
+	assert.Contains(t, generated["fetch.pb.ts"], "export async function* fetchStreamingIterable<S, R>(path: string, init?: InitReq): AsyncIterable<R> {")
+}
+
+func TestFetchModuleRejectsWithRpcError(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" }
+}
+`)
+
+	content := generated["fetch.pb.ts"]
+	assert.Contains(t, content, "export class RpcError extends Error {")
+	assert.Contains(t, content, "    if (!r.ok) {\n      throw await getRpcError(r)\n    }")
+	assert.Contains(t, content, "    throw new RpcError(response.error, httpStatus)")
+	// responses without a body aren't parsed
+	assert.Contains(t, content, "    const body = r.status === 204 ? \"\" : await r.text()\n    return body ? JSON.parse(body) : {}\n")
+}
+
+func TestFetchModuleTimeouts(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" }
+}
+`)
+
+	content := generated["fetch.pb.ts"]
+	assert.Contains(t, content, "  timeoutMs?: number\n")
+	assert.Contains(t, content, "  firstByteTimeoutMs?: number\n")
+	assert.Contains(t, content, "export class TimeoutError extends Error {")
+	assert.Contains(t, content, "  }).catch(e => {\n    throw timeout.error(e)\n  }).finally(timeout.dispose) as Promise<O>\n")
+	// streaming calls are only timed out until the response headers arrive
+	assert.Contains(t, content, "  } finally {\n    timeout.stop()\n  }\n")
+}
+
+func TestFetchModuleBasePath(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" }
+}
+`)
+
+	content := generated["fetch.pb.ts"]
+	assert.Contains(t, content, "  basePath?: string\n")
+	assert.Contains(t, content, `  const base = basePath ? basePath.replace(/^\/+|\/+$/g, "") : ""
+  if (base) {
+    path = "/" + base + "/" + path.replace(/^\/+/, "")
+  }`)
+	// unary, streaming and WebSocket calls all join the base path
+	assert.Equal(t, 3, strings.Count(content, "getURL(path, "))
+}
+
+func TestIdempotencyLevelMakesCallsRetryable(t *testing.T) {
+	content := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method {
+    name: "Put"
+    input_type: ".svc.Request"
+    output_type: ".svc.Request"
+    options {
+      idempotency_level: IDEMPOTENT
+      [google.api.http] { post: "/v1/items" body: "*" }
+    }
+  }
+  method {
+    name: "Create"
+    input_type: ".svc.Request"
+    output_type: ".svc.Request"
+    options { [google.api.http] { post: "/v1/items:create" body: "*" } }
+  }
+  method {
+    name: "Lookup"
+    input_type: ".svc.Request"
+    output_type: ".svc.Request"
+    options { idempotency_level: NO_SIDE_EFFECTS }
+  }
+}
+`)["svc.pb.ts"]
+	// POST methods are only retried when they are declared idempotent or free of side effects
+	assert.Contains(t, content, "`/v1/items`, {...initReq, method: \"POST\", body: JSON.stringify(req), idempotent: true}")
+	assert.Contains(t, content, "`/svc.Service/Lookup`, {...initReq, method: \"POST\", body: JSON.stringify(req), idempotent: true}")
+	assert.Contains(t, content, "`/v1/items:create`, {...initReq, method: \"POST\", body: JSON.stringify(req)}")
+}
+
+func TestFetchModuleInterceptors(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" }
+}
+`)
+
+	content := generated["fetch.pb.ts"]
+	assert.Contains(t, content, "  interceptor?: RequestInterceptor\n")
+	assert.Contains(t, content, "  responseInterceptor?: ResponseInterceptor\n")
+	// the request interceptor runs before fetch, and the response interceptor before the response is parsed
+	assert.Contains(t, content, "  const response = interceptor ? await fetchFn(await interceptor(new Request(url, init))) : await fetchFn(url, init)\n  return responseInterceptor ? responseInterceptor(response) : response\n")
+	assert.Contains(t, content, "  return fetchWithRetry(() => sendRequest(fetchFn, url, requestInit, interceptor, responseInterceptor), idempotent ? retry : undefined, req.signal).then(async r => {\n")
+	assert.Contains(t, content, "    result = await sendRequest(fetchFn, url, getRequestInit(req, contentType, accept), interceptor, responseInterceptor)\n")
+	// unary and streaming calls are both sent through the interceptors
+	assert.Equal(t, 2, strings.Count(content, "sendRequest(fetchFn, "))
+}
+
+func TestFetchModuleContentTypes(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" }
+}
+`)
+
+	content := generated["fetch.pb.ts"]
+	// both default to application/json for unary and streaming calls
+	assert.Equal(t, 2, strings.Count(content, `contentType = "application/json", accept = "application/json"`))
+	// the headers passed explicitly are not overwritten, and only requests with a body get a Content-Type
+	assert.Contains(t, content, `  const headers = new Headers(req.headers)
+  if (req.body !== undefined && req.body !== null && !headers.has("Content-Type")) {
+    headers.set("Content-Type", contentType)
+  }
+  if (!headers.has("Accept")) {
+    headers.set("Accept", accept)
+  }
+`)
+	// streaming responses of another content type are rejected before they are parsed
+	assert.Contains(t, content, `  const responseType = result.headers.get("Content-Type")
+  if (responseType && getMediaType(responseType) !== getMediaType(accept)) {
+    throw new Error("unexpected content type " + responseType + " of the streaming response, expected " + accept)
+  }
+`)
+}
+
+func TestFetchModuleDecompressesStreamingResponses(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" server_streaming: true }
+}
+`)
+
+	content := generated["fetch.pb.ts"]
+	assert.Contains(t, content, "  decompress?: (encoding: string) => TransformStream<Uint8Array, Uint8Array> | undefined\n")
+	assert.Contains(t, content, "  const reader = getDecodedBody(result.body, result.headers, decompress).getReader()\n")
+	// the encodings are undone in reverse through the transforms returned by decompress
+	assert.Contains(t, content, `  return encodings.reverse().reduce((stream, encoding) => {
+    const transform = decompress ? decompress(encoding) : undefined
+    if (transform) {
+      return stream.pipeThrough(transform)
+    }
+`)
+	// encodings neither decoded by fetch nor by decompress are rejected
+	assert.Contains(t, content, `const fetchDecodedEncodings = ["gzip", "x-gzip", "deflate", "br"]`)
+	assert.Contains(t, content, `    if (!fetchDecodedEncodings.includes(encoding)) {
+      throw new Error("unsupported content encoding " + encoding + " of the streaming response, it can be decompressed with decompress of InitReq")
+    }
+`)
+}
+
+func TestFetchModuleAbortsStreamingReads(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+message_type { name: "Request" }
+service {
+  name: "Service"
+  method { name: "Watch" input_type: ".svc.Request" output_type: ".svc.Request" server_streaming: true }
+}
+`)
+
+	content := generated["fetch.pb.ts"]
+	// a pending read is cancelled by the abort, and every read checks the signal before the chunk is decoded
+	assert.Contains(t, content, "  const onAbort = () => {\n    reader.cancel(req.signal?.reason).catch(() => {})\n  }\n  req.signal?.addEventListener(\"abort\", onAbort)\n")
+	assert.Contains(t, content, "      const {done, value} = await reader.read()\n      req.signal?.throwIfAborted()\n      if (done) {\n")
+	assert.Contains(t, content, "    req.signal?.removeEventListener(\"abort\", onAbort)\n")
+}
+
+func TestFieldCase(t *testing.T) {
+	file := `
+name: "case.proto"
+package: "fieldcase"
+syntax: "proto3"
+message_type {
+  name: "Request"
+  field { name: "host_name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "hostName" }
+  field { name: "IPAddress" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "IPAddress" }
+}
+`
+	tests := []struct {
+		fieldCase string
+		expected  []string
+	}{
+		{fieldCase: "camel", expected: []string{"hostName?: string", "IPAddress?: string"}},
+		{fieldCase: "snake", expected: []string{"host_name?: string", "ip_address?: string"}},
+		{fieldCase: "original", expected: []string{"host_name?: string", "IPAddress?: string"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fieldCase, func(t *testing.T) {
+			content := generate(t, map[string]string{"ts_field_case": tt.fieldCase}, file)["case.pb.ts"]
+			for _, expected := range tt.expected {
+				assert.Contains(t, content, expected)
+			}
+		})
+	}
+}
+
+func TestNestedIdentifierCollisions(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "nested.proto"
+package: "nested"
+syntax: "proto3"
+message_type {
+  name: "A"
+  nested_type { name: "BC" }
+}
+message_type {
+  name: "AB"
+  nested_type { name: "C" }
+}
+message_type {
+  name: "Outer"
+  nested_type { name: "Inner" }
+  field { name: "bc" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".nested.A.BC" json_name: "bc" }
+  field { name: "c" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".nested.AB.C" json_name: "c" }
+  field { name: "inner" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".nested.Outer.Inner" json_name: "inner" }
+}
+`)
+
+	content := generated["nested.pb.ts"]
+	assert.Contains(t, content, "export type A_BC = {")
+	assert.Contains(t, content, "export type AB_C = {")
+	assert.NotContains(t, content, "export type ABC = {")
+	assert.Contains(t, content, "bc?: A_BC")
+	assert.Contains(t, content, "c?: AB_C")
+	// identifiers without collisions keep their names
+	assert.Contains(t, content, "inner?: OuterInner")
+}
+
+func TestAdditionalBindings(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "bindings.proto"
+package: "bindings"
+syntax: "proto3"
+message_type {
+  name: "Request"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }
+}
+service {
+  name: "Service"
+  method {
+    name: "Get"
+    input_type: ".bindings.Request"
+    output_type: ".bindings.Request"
+    options {
+      [google.api.http] {
+        get: "/v1/items/{id}"
+        additional_bindings { post: "/v1/items:get" body: "*" }
+      }
+    }
+  }
+}
+`)
+
+	content := generated["bindings.pb.ts"]
+	assert.Contains(t, content, "static Get(req: Request, initReq?: fm.InitReq): Promise<Request> {")
+	assert.Contains(t, content, "`/v1/items/${req[\"id\"]}?${fm.renderURLSearchParams(req, [\"id\"])}`, {...initReq, method: \"GET\", idempotent: true}")
+	assert.Contains(t, content, "static GetBinding1(req: Request, initReq?: fm.InitReq): Promise<Request> {")
+	assert.Contains(t, content, "`/v1/items:get`, {...initReq, method: \"POST\", body: JSON.stringify(req)}")
+}
+
+func TestNamedBody(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "body.proto"
+package: "body"
+syntax: "proto3"
+message_type {
+  name: "Data"
+  field { name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+}
+message_type {
+  name: "Request"
+  field { name: "item_id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "itemId" }
+  field { name: "item_data" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".body.Data" json_name: "itemData" }
+  field { name: "force" number: 3 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "force" }
+}
+service {
+  name: "Service"
+  method {
+    name: "Update"
+    input_type: ".body.Request"
+    output_type: ".body.Request"
+    options { [google.api.http] { patch: "/v1/items/{item_id}" body: "item_data" } }
+  }
+  method {
+    name: "Delete"
+    input_type: ".body.Request"
+    output_type: ".body.Request"
+    options { [google.api.http] { delete: "/v1/items/{item_id}" } }
+  }
+}
+`)
+
+	content := generated["body.pb.ts"]
+	assert.Contains(t, content, "`/v1/items/${req[\"itemId\"]}?${fm.renderURLSearchParams(req, [\"itemId\", \"itemData\"])}`, {...initReq, method: \"PATCH\", body: JSON.stringify(req[\"itemData\"])}")
+	assert.Contains(t, content, "`/v1/items/${req[\"itemId\"]}?${fm.renderURLSearchParams(req, [\"itemId\"])}`, {...initReq, method: \"DELETE\"}")
+}
+
+func TestBigIntAndDateQueryParameters(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_message_kind": "class", "ts_wkt_mapping": "true", "ts_timestamp_type": "Date", "ts_int64_type": "bigint"}, `
+name: "query.proto"
+package: "query"
+syntax: "proto3"
+dependency: "google/protobuf/timestamp.proto"
+message_type {
+  name: "Request"
+  field { name: "since_id" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "sinceId" }
+  field { name: "after" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "after" }
+}
+service {
+  name: "Service"
+  method {
+    name: "List"
+    input_type: ".query.Request"
+    output_type: ".query.Request"
+    options { [google.api.http] { get: "/v1/items" } }
+  }
+}
+`)
+
+	content := generated["query.pb.ts"]
+	assert.Contains(t, content, "  sinceId?: bigint\n  after?: Date\n")
+	assert.Contains(t, content, "`/v1/items?${fm.renderURLSearchParams(fm.jsonPayload(req), [])}`, {...initReq, method: \"GET\", idempotent: true}")
+
+	content = generated["fetch.pb.ts"]
+	// dates are sent in RFC 3339, and bigint values as decimal strings unless they are zero
+	assert.Contains(t, content, "      if (value instanceof Date) {\n        objectToMerge = { [newPath]: value.toISOString() };\n")
+	assert.Contains(t, content, `  return ["string", "number", "boolean", "bigint"].some(t => typeof value === t);`)
+	assert.Contains(t, content, `(typeof value === "bigint" && value.toString() === "0")`)
+	assert.Contains(t, content, "        ? [...acc, ...value.map(m => [key, m.toString()])]\n        : (acc = [...acc, [key, value.toString()]]);\n")
+}
+
+func TestEnumHelpers(t *testing.T) {
+	file := `
+name: "color.proto"
+package: "color"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+  value { name: "GREEN" number: 2 }
+}
+`
+	content := generate(t, map[string]string{}, file)["color.pb.ts"]
+	assert.Contains(t, content, "export function colorFromJSON(object: string | number): Color {")
+	assert.Contains(t, content, "    case 2:\n    case \"GREEN\":\n      return Color.GREEN")
+	assert.Contains(t, content, "    default:\n      return Color.RED")
+	assert.Contains(t, content, "export function colorToJSON(object: Color): string {")
+	assert.Contains(t, content, "export function colorToNumber(object: Color): number {")
+	assert.Contains(t, content, "    case Color.GREEN:\n      return 2")
+
+	content = generate(t, map[string]string{"ts_enum_style": "string_union"}, file)["color.pb.ts"]
+	assert.Contains(t, content, "    case \"GREEN\":\n      return \"GREEN\"")
+
+	content = generate(t, map[string]string{"ts_enum_unknown": "preserve"}, file)["color.pb.ts"]
+	assert.Contains(t, content, "export function colorFromJSON(object: string | number): Color | string | number {")
+	assert.Contains(t, content, "    default:\n      return object")
+	assert.Contains(t, content, "export function colorToJSON(object: Color | string | number): string {")
+	assert.Contains(t, content, "    default:\n      return String(object)")
+	assert.Contains(t, content, "    default:\n      return typeof object === \"number\" ? object : -1")
+
+	content = generate(t, map[string]string{"ts_enum_unknown": "throw"}, file)["color.pb.ts"]
+	assert.Contains(t, content, "    default:\n      throw new Error(\"unrecognized value \" + object + \" of Color\")")
+
+	_, err := New(map[string]string{"ts_enum_unknown": "preserve", "ts_message_kind": "class"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting enum unknown information: ts_enum_unknown preserve is not supported with ts_message_kind class")
+}
+
+func TestEnumValues(t *testing.T) {
+	file := `
+name: "color.proto"
+package: "color"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+  value { name: "GREEN" number: 2 }
+  value { name: "CRIMSON" number: 0 }
+  options { allow_alias: true }
+}
+`
+	content := generate(t, map[string]string{}, file)["color.pb.ts"]
+	assert.Contains(t, content, "export const ColorValues = [Color.RED, Color.GREEN] as const")
+
+	content = generate(t, map[string]string{"ts_enum_style": "string_union"}, file)["color.pb.ts"]
+	assert.Contains(t, content, `export const ColorValues = ["RED", "GREEN"] as const`)
+}
+
+func TestEnumSatisfies(t *testing.T) {
+	file := `
+name: "color.proto"
+package: "color"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+  value { name: "GREEN" number: 2 }
+  value { name: "BLUE" number: -1 }
+}
+`
+	content := generate(t, map[string]string{}, file)["color.pb.ts"]
+	assert.Contains(t, content, `export const ColorValues = [Color.RED, Color.GREEN, Color.BLUE] as const
+`)
+	assert.Contains(t, content, `export const ColorByNumber: Record<number, Color> = {
+  0: Color.RED,
+  2: Color.GREEN,
+  [-1]: Color.BLUE,
+}
+`)
+
+	// the literal types are kept while the shape is checked from typescript 4.9
+	for _, target := range []string{"4.9", "5"} {
+		content = generate(t, map[string]string{"ts_target": target}, file)["color.pb.ts"]
+		assert.Contains(t, content, "export const ColorValues = [Color.RED, Color.GREEN, Color.BLUE] as const satisfies readonly Color[]")
+		assert.Contains(t, content, `export const ColorByNumber = {
+  0: Color.RED,
+  2: Color.GREEN,
+  [-1]: Color.BLUE,
+} as const satisfies Record<number, Color>`)
+	}
+
+	_, err := New(map[string]string{"ts_target": "latest"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting target information: unsupported value latest for ts_target, valid values are typescript versions such as 4.9 or 5")
+}
+
+func TestEnumStyles(t *testing.T) {
+	file := `
+name: "color.proto"
+package: "color"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+  value { name: "GREEN" number: 2 }
+  value { name: "CRIMSON" number: 0 }
+  options { allow_alias: true }
+}
+message_type {
+  name: "Paint"
+  field { name: "color" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".color.Color" json_name: "color" }
+}
+`
+	for _, test := range []struct {
+		style       string
+		declaration string
+		alias       string
+	}{
+		{style: "enum", declaration: "export enum Color {\n  RED = \"RED\",\n  GREEN = \"GREEN\",\n  CRIMSON = \"CRIMSON\",\n}\n", alias: "Color.CRIMSON"},
+		{style: "const_enum", declaration: "export const enum Color {\n  RED = \"RED\",\n  GREEN = \"GREEN\",\n  CRIMSON = \"CRIMSON\",\n}\n", alias: "Color.CRIMSON"},
+		{style: "string_union", declaration: "export type Color =\n  | \"RED\"\n  | \"GREEN\"\n  | \"CRIMSON\"\n", alias: `"CRIMSON"`},
+	} {
+		t.Run(test.style, func(t *testing.T) {
+			content := generate(t, map[string]string{"ts_enum_style": test.style}, file)["color.pb.ts"]
+			// aliases are declared as values of their own, named after the alias
+			assert.Contains(t, content, test.declaration)
+			assert.Contains(t, content, "  color?: Color\n")
+			// the alias resolves to its own name, while its number resolves to the value declared first
+			assert.Contains(t, content, "    case 0:\n    case \"RED\":\n")
+			assert.Contains(t, content, "    case \"CRIMSON\":\n      return "+test.alias+"\n")
+			assert.Contains(t, content, "    case "+test.alias+":\n      return 0\n")
+			assert.Equal(t, 1, strings.Count(content, "    case 0:\n"))
+		})
+	}
+
+	// the default is a typescript enum
+	content := generate(t, map[string]string{}, file)["color.pb.ts"]
+	assert.Contains(t, content, "export enum Color {\n")
+
+	_, err := New(map[string]string{"ts_enum_style": "union"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting enum style information: unsupported value union for ts_enum_style, valid values are enum, string_union and const_enum")
+}
+
+func TestEnumAliases(t *testing.T) {
+	file := `
+name: "status.proto"
+package: "status"
+syntax: "proto3"
+enum_type {
+  name: "Status"
+  value { name: "UNKNOWN" number: 0 }
+  value { name: "STARTED" number: 1 }
+  value { name: "RUNNING" number: 1 }
+  options { allow_alias: true }
+}
+`
+	for _, style := range []string{"enum", "const_enum", "string_union"} {
+		t.Run(style, func(t *testing.T) {
+			content := generate(t, map[string]string{"ts_enum_style": style}, file)["status.pb.ts"]
+			if style == "string_union" {
+				assert.Contains(t, content, "export type Status =\n  | \"UNKNOWN\"\n  | \"STARTED\"\n  | \"RUNNING\"\n")
+				assert.Contains(t, content, `export const StatusValues = ["UNKNOWN", "STARTED"] as const`)
+			} else {
+				// both names of the aliased number are kept as members
+				assert.Contains(t, content, "  STARTED = \"STARTED\",\n  RUNNING = \"RUNNING\",\n")
+				assert.Contains(t, content, "export const StatusValues = [Status.UNKNOWN, Status.STARTED] as const")
+			}
+
+			// the number of an alias resolves to the value declared first
+			assert.Equal(t, 1, strings.Count(content, "    case 1:\n"))
+			assert.Contains(t, content, "    case 1:\n    case \"STARTED\":\n")
+		})
+	}
+}
+
+func TestRepeatedAndMapFields(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "repeated.proto"
+package: "repeated"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+}
+message_type {
+  name: "Item"
+}
+message_type {
+  name: "Request"
+  field { name: "items" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".repeated.Item" json_name: "items" }
+  field { name: "colors" number: 2 label: LABEL_REPEATED type: TYPE_ENUM type_name: ".repeated.Color" json_name: "colors" }
+  field { name: "names" number: 3 label: LABEL_REPEATED type: TYPE_STRING json_name: "names" }
+  field { name: "items_by_name" number: 4 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".repeated.Request.ItemsByNameEntry" json_name: "itemsByName" }
+  nested_type {
+    name: "ItemsByNameEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".repeated.Item" json_name: "value" }
+    options { map_entry: true }
+  }
+}
+`)
+
+	content := generated["repeated.pb.ts"]
+	assert.Contains(t, content, "items?: Item[]")
+	assert.Contains(t, content, "colors?: Color[]")
+	assert.Contains(t, content, "names?: string[]")
+	assert.Contains(t, content, "itemsByName?: {[key: string]: Item}\n")
+}
+
+func TestMapValuesFromAnotherFile(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_guards": "true"}, `
+name: "things/thing.proto"
+package: "things"
+syntax: "proto3"
+message_type {
+  name: "RepeatedThing"
+  field { name: "values" number: 1 label: LABEL_REPEATED type: TYPE_STRING json_name: "values" }
+  field { name: "tags" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".things.RepeatedThing.TagsEntry" json_name: "tags" }
+  nested_type {
+    name: "TagsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+    options { map_entry: true }
+  }
+}
+`, `
+name: "holder.proto"
+package: "holder"
+syntax: "proto3"
+dependency: "things/thing.proto"
+message_type {
+  name: "Holder"
+  field { name: "things" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".holder.Holder.ThingsEntry" json_name: "things" }
+  nested_type {
+    name: "ThingsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".things.RepeatedThing" json_name: "value" }
+    options { map_entry: true }
+  }
+}
+`)
+
+	assert.Contains(t, generated["things/thing.pb.ts"], "  values?: string[]\n  tags?: {[key: string]: string}\n")
+
+	content := generated["holder.pb.ts"]
+	assert.Contains(t, content, `import * as ThingsThing from "./things/thing.pb"`)
+	assert.Contains(t, content, "  things?: {[key: string]: ThingsThing.RepeatedThing}\n")
+	assert.Contains(t, content, "Object.values(v).every(e => ThingsThing.isRepeatedThing(e))")
+}
+
+func TestEnumMapKeys(t *testing.T) {
+	file := `
+name: "enumkeys.proto"
+package: "enumkeys"
+syntax: "proto3"
+enum_type {
+  name: "MyEnum"
+  value { name: "FIRST" number: 0 }
+  value { name: "SECOND" number: 1 }
+}
+message_type {
+  name: "Labels"
+  field { name: "labels" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".enumkeys.Labels.LabelsEntry" json_name: "labels" }
+  nested_type {
+    name: "LabelsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".enumkeys.MyEnum" json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+    options { map_entry: true }
+  }
+}
+`
+	content := generate(t, map[string]string{}, file)["enumkeys.pb.ts"]
+	assert.Contains(t, content, "labels?: {[key in MyEnum]?: string}\n")
+
+	content = generate(t, map[string]string{"ts_enum_style": "string_union", "ts_readonly": "true"}, file)["enumkeys.pb.ts"]
+	assert.Contains(t, content, "readonly labels?: {readonly [key in MyEnum]?: string}\n")
+}
+
+func TestInt64MapKeys(t *testing.T) {
+	file := `
+name: "int64keys.proto"
+package: "int64keys"
+syntax: "proto3"
+message_type { name: "Foo" }
+message_type {
+  name: "Index"
+  field { name: "foos_by_id" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".int64keys.Index.FoosByIdEntry" json_name: "foosById" }
+  nested_type {
+    name: "FoosByIdEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".int64keys.Foo" json_name: "value" }
+    options { map_entry: true }
+  }
+}
+`
+	content := generate(t, map[string]string{}, file)["int64keys.pb.ts"]
+	assert.Contains(t, content, "foosById?: {[key: string]: Foo}\n")
+	assert.NotContains(t, content, "indexFoosByIdEntries")
+
+	content = generate(t, map[string]string{"ts_message_kind": "class", "ts_int64_type": "bigint"}, file)["int64keys.pb.ts"]
+	assert.Contains(t, content, "foosById?: {[key: string]: Foo}\n")
+	assert.Contains(t, content, `export function indexFoosByIdEntries(map?: {readonly [key: string]: Foo}): [bigint, Foo][] {
+  return Object.entries(map || {}).map(([key, value]): [bigint, Foo] => [BigInt(key), value])
+}`)
+
+	content = generate(t, map[string]string{"ts_message_kind": "class", "ts_int64_type": "number"}, file)["int64keys.pb.ts"]
+	assert.Contains(t, content, "foosById?: {[key: string]: Foo}\n")
+	assert.Contains(t, content, "[Number(key), value]")
+}
+
+func TestInt64TypesAreConvertedByMessageClasses(t *testing.T) {
+	file := `
+name: "counter.proto"
+package: "counter"
+syntax: "proto3"
+message_type {
+  name: "Counter"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "id" }
+  field { name: "totals" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".counter.Counter.TotalsEntry" json_name: "totals" }
+  field { name: "counts" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".counter.Counter.CountsEntry" json_name: "counts" }
+  nested_type {
+    name: "TotalsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_UINT64 json_name: "value" }
+    options { map_entry: true }
+  }
+  nested_type {
+    name: "CountsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "value" }
+    options { map_entry: true }
+  }
+}
+service {
+  name: "CounterService"
+  method {
+    name: "Increase"
+    input_type: ".counter.Counter"
+    output_type: ".counter.Counter"
+    options { [google.api.http] { post: "/v1/counters" body: "*" } }
+  }
+}
+`
+	content := generate(t, map[string]string{"ts_message_kind": "class", "ts_int64_type": "bigint"}, file)["counter.pb.ts"]
+	assert.Contains(t, content, "  id?: bigint\n  totals?: {[key: string]: bigint}\n  counts?: {[key: string]: bigint}\n")
+	// request bodies are serialized by toJSON, which turns bigint into the strings of JSON
+	assert.Contains(t, content, "{...initReq, method: \"POST\", body: JSON.stringify(req)}")
+	assert.Contains(t, content, `json["id"] = String(this["id"])`)
+	assert.Contains(t, content, `json["totals"] = Object.fromEntries(Object.entries(this["totals"]).map(([k, e]) => [k, String(e)]))`)
+	// responses are decoded by fromJSON, map values included
+	assert.Contains(t, content, "      .then(resp => Counter.fromJSON(resp))\n")
+	assert.Contains(t, content, `m["id"] = BigInt(v)`)
+	assert.Contains(t, content, `m["totals"] = Object.fromEntries(Object.entries(v).map(([k, e]: [string, any]) => [k, BigInt(e)]))`)
+	// 64-bit integer map keys stay strings, and are converted by a helper along with the values
+	assert.Contains(t, content, `export function counterCountsEntries(map?: {readonly [key: string]: bigint}): [bigint, bigint][] {
+  return Object.entries(map || {}).map(([key, value]): [bigint, bigint] => [BigInt(key), value])
+}`)
+
+	content = generate(t, map[string]string{"ts_message_kind": "class", "ts_int64_type": "number"}, file)["counter.pb.ts"]
+	assert.Contains(t, content, "  id?: number\n  totals?: {[key: string]: number}\n  counts?: {[key: string]: number}\n")
+	assert.Contains(t, content, `m["id"] = Number(v)`)
+	assert.Contains(t, content, `m["counts"] = Object.fromEntries(Object.entries(v).map(([k, e]: [string, any]) => [k, Number(e)]))`)
+
+	// plain objects would be sent and received as they are
+	for _, int64Type := range []string{"bigint", "number"} {
+		_, err := New(map[string]string{"ts_int64_type": int64Type})
+		assert.EqualError(t, err, "error instantiating a new registry: ts_int64_type "+int64Type+" is only supported with ts_message_kind class")
+	}
+}
+
+func TestBarrels(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_barrels": "true", "ts_file_extension": ".gen.ts"}, `
+name: "protos/a.proto"
+package: "a"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+}
+message_type { name: "Request" }
+`, `
+name: "protos/b.proto"
+package: "b"
+syntax: "proto3"
+message_type { name: "Request" }
+message_type { name: "Response" }
+`)
+
+	content, ok := generated["protos/index.gen.ts"]
+	require.True(t, ok)
+	assert.Contains(t, content, `export { Color, ColorValues, ColorByNumber, colorFromJSON, colorToJSON, colorToNumber } from "./a.pb.gen"`)
+	assert.Contains(t, content, `export type { Request } from "./a.pb.gen"`)
+	// Request has been exported from a.pb.gen already
+	assert.Contains(t, content, `export type { Response } from "./b.pb.gen"`)
+}
+
+func TestDeclarationFiles(t *testing.T) {
+	files := []string{`
+name: "a.proto"
+package: "a"
+syntax: "proto3"
+message_type { name: "Item" field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" } }
+`, `
+name: "b/b.proto"
+package: "b"
+syntax: "proto3"
+dependency: "a.proto"
+message_type {
+  name: "Order"
+  field { name: "item" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".a.Item" json_name: "item" }
+  field { name: "code" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING oneof_index: 0 json_name: "code" }
+  field { name: "id" number: 3 label: LABEL_OPTIONAL type: TYPE_INT64 oneof_index: 0 json_name: "id" }
+  oneof_decl { name: "ref" }
+}
+`}
+	generated := generate(t, map[string]string{"ts_file_extension": ".d.ts"}, files...)
+	require.Contains(t, generated, "a.pb.d.ts")
+	require.Contains(t, generated, "b/b.pb.d.ts")
+	assert.NotContains(t, generated, "a.pb.ts")
+
+	// the extension is trimmed from the imports as a whole, rather than only its .ts suffix
+	content := generated["b/b.pb.d.ts"]
+	assert.Contains(t, content, `import * as AA from "../a.pb"`)
+	assert.Contains(t, content, "export type Order = BaseOrder\n")
+	// only types are declared
+	for _, f := range generated {
+		assert.NotContains(t, f, "function")
+		assert.NotContains(t, f, "class")
+		assert.NotContains(t, f, "export const")
+	}
+
+	// services and the options generating implementations are rejected, unless only the types are generated
+	withService := strings.Replace(files[0], "message_type", `service {
+  name: "ItemService"
+  method { name: "Get" input_type: ".a.Item" output_type: ".a.Item" }
+}
+message_type`, 1)
+	g, err := New(map[string]string{"ts_file_extension": ".d.ts"})
+	require.NoError(t, err)
+	fileDescriptor := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, prototext.Unmarshal([]byte(withService), fileDescriptor))
+	_, err = g.Generate(&plugin.CodeGeneratorRequest{FileToGenerate: []string{"a.proto"}, ProtoFile: []*descriptorpb.FileDescriptorProto{fileDescriptor}})
+	assert.EqualError(t, err, "error analysing proto files: error analysing file a.proto: service ItemService can't be generated into declaration files with ts_file_extension .d.ts, only the types are generated with ts_emit types")
+
+	generated = generate(t, map[string]string{"ts_file_extension": ".d.ts", "ts_emit": "types"}, withService)
+	assert.NotContains(t, generated["a.pb.d.ts"], "ItemService")
+
+	_, err = New(map[string]string{"ts_file_extension": ".d.ts", "ts_message_kind": "class"})
+	assert.EqualError(t, err, "error instantiating a new registry: ts_message_kind class is not supported with ts_file_extension .d.ts")
+	_, err = New(map[string]string{"ts_file_extension": ".d.ts", "ts_emit_guards": "true"})
+	assert.EqualError(t, err, "error instantiating a new registry: ts_emit_guards is not supported with ts_file_extension .d.ts")
+}
+
+func TestDeclarationFileEnums(t *testing.T) {
+	file := `
+name: "color.proto"
+package: "color"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+  value { name: "GREEN" number: 2 }
+}
+`
+	for _, style := range []string{"enum", "string_union", "const_enum"} {
+		t.Run(style, func(t *testing.T) {
+			content := generate(t, map[string]string{"ts_file_extension": ".d.ts", "ts_enum_style": style}, file)["color.pb.d.ts"]
+			// the helpers are implementations, which can't be rendered into declaration files
+			for _, helper := range []string{"colorFromJSON", "colorToJSON", "colorToNumber", "ColorByNumber"} {
+				assert.NotContains(t, content, helper)
+			}
+			assert.NotContains(t, content, "function")
+			// neither are the values, declaration files can't initialize constants
+			assert.NotContains(t, content, "ColorValues")
+			assert.NotContains(t, content, "= [")
+			assert.NotContains(t, content, "as const")
+		})
+	}
+
+	// the helpers are still generated into the other files
+	content := generate(t, map[string]string{"ts_enum_style": "string_union"}, file)["color.pb.ts"]
+	assert.Contains(t, content, "export function colorFromJSON(")
+	assert.Contains(t, content, "export const ColorByNumber")
+	assert.Contains(t, content, "export const ColorValues = [\"RED\", \"GREEN\"] as const")
+}
+
+func TestTypeOnlyExports(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_barrels": "true", "ts_enum_style": "string_union"}, `
+name: "protos/a.proto"
+package: "a"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+}
+message_type {
+  name: "Request"
+  field { name: "color" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".a.Color" json_name: "color" }
+}
+`)
+
+	// types are declared as type aliases and re-exported with export type, which isolatedModules and verbatimModuleSyntax require
+	assert.Contains(t, generated["protos/a.pb.ts"], "export type Request = {")
+	assert.NotContains(t, generated["protos/a.pb.ts"], "export interface")
+	assert.Contains(t, generated["protos/index.ts"], `export { ColorValues, ColorByNumber, colorFromJSON, colorToJSON, colorToNumber } from "./a.pb"
+export type { Color, Request } from "./a.pb"`)
+}
+
+func TestCommonJSModuleSystem(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_module_system": "commonjs", "ts_emit_barrels": "true"}, `
+name: "protos/a.proto"
+package: "a"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+}
+message_type { name: "Request" }
+`, `
+name: "protos/b.proto"
+package: "b"
+syntax: "proto3"
+dependency: "protos/a.proto"
+message_type {
+  name: "Response"
+  field { name: "request" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".a.Request" json_name: "request" }
+}
+`)
+
+	assert.Contains(t, generated["protos/b.pb.ts"], `import AA = require("./a.pb")`)
+	assert.Contains(t, generated["protos/b.pb.ts"], "request?: AA.Request")
+	assert.Contains(t, generated["protos/index.ts"], `import APb = require("./a.pb")
+export import Color = APb.Color
+export import ColorValues = APb.ColorValues
+export import ColorByNumber = APb.ColorByNumber
+export import colorFromJSON = APb.colorFromJSON
+export import colorToJSON = APb.colorToJSON
+export import colorToNumber = APb.colorToNumber
+export type Request = APb.Request
+import BPb = require("./b.pb")
+export type Response = BPb.Response`)
+}
+
+func TestBytesType(t *testing.T) {
+	file := `
+name: "bytes.proto"
+package: "bin"
+syntax: "proto3"
+message_type {
+  name: "Blob"
+  field { name: "data" number: 1 label: LABEL_OPTIONAL type: TYPE_BYTES json_name: "data" }
+  field { name: "chunks" number: 2 label: LABEL_REPEATED type: TYPE_BYTES json_name: "chunks" }
+  field { name: "children" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".bin.Blob" json_name: "children" }
+}
+message_type { name: "Plain" }
+service {
+  name: "BlobService"
+  method { name: "Put" input_type: ".bin.Blob" output_type: ".bin.Plain" }
+  method { name: "Get" input_type: ".bin.Plain" output_type: ".bin.Blob" }
+}
+`
+	content := generate(t, map[string]string{}, file)["bytes.pb.ts"]
+	assert.Contains(t, content, "data?: string")
+	assert.Contains(t, content, "chunks?: string[]")
+	assert.NotContains(t, content, "convertBytes")
+
+	content = generate(t, map[string]string{"ts_bytes_type": "uint8array"}, file)["bytes.pb.ts"]
+	assert.Contains(t, content, "data?: Uint8Array")
+	assert.Contains(t, content, "chunks?: Uint8Array[]")
+	assert.Contains(t, content, `".bin.Blob": {"children": ".bin.Blob", "chunks": "bytes", "data": "bytes"},`)
+	assert.NotContains(t, content, `".bin.Plain"`)
+	assert.Contains(t, content, `req = fm.convertBytes(req, ".bin.Blob", bytesFields, fm.base64Encode)`)
+	assert.Contains(t, content, `.then(resp => fm.convertBytes(resp, ".bin.Blob", bytesFields, fm.base64Decode))`)
+}
+
+func TestReadonly(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_readonly": "true"}, `
+name: "readonly.proto"
+package: "readonly"
+syntax: "proto3"
+message_type {
+  name: "Request"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "tags" number: 2 label: LABEL_REPEATED type: TYPE_STRING json_name: "tags" }
+  field { name: "labels" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".readonly.Request.LabelsEntry" json_name: "labels" }
+  nested_type {
+    name: "LabelsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+    options { map_entry: true }
+  }
+}
+`)
+
+	content := generated["readonly.pb.ts"]
+	assert.Contains(t, content, "readonly name?: string")
+	assert.Contains(t, content, "readonly tags?: ReadonlyArray<string>")
+	assert.Contains(t, content, "readonly labels?: {readonly [key: string]: string}")
+}
+
+func TestRecursiveMessages(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_bytes_type": "uint8array"}, `
+name: "tree.proto"
+package: "tree"
+syntax: "proto3"
+message_type {
+  name: "Node"
+  field { name: "children" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".tree.Node" json_name: "children" }
+  field { name: "parent" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".tree.Node" json_name: "parent" }
+  field { name: "leaf" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".tree.Leaf" json_name: "leaf" }
+}
+message_type {
+  name: "Leaf"
+  field { name: "node" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".tree.Node" json_name: "node" }
+  field { name: "data" number: 2 label: LABEL_OPTIONAL type: TYPE_BYTES json_name: "data" }
+}
+service {
+  name: "TreeService"
+  method { name: "Get" input_type: ".tree.Node" output_type: ".tree.Node" }
+}
+`, `
+name: "forest.proto"
+package: "forest"
+syntax: "proto3"
+dependency: "tree.proto"
+message_type {
+  name: "Forest"
+  field { name: "trees" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".tree.Node" json_name: "trees" }
+  field { name: "root" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".tree.Node" json_name: "root" }
+}
+`)
+
+	content := generated["tree.pb.ts"]
+	assert.Contains(t, content, "children?: Node[]")
+	assert.Contains(t, content, "parent?: Node")
+	assert.Contains(t, content, "node?: Node")
+	assert.Contains(t, content, `".tree.Leaf": {"data": "bytes", "node": ".tree.Node"},`)
+	assert.Contains(t, content, `".tree.Node": {"children": ".tree.Node", "leaf": ".tree.Leaf", "parent": ".tree.Node"},`)
+
+	content = generated["forest.pb.ts"]
+	assert.Equal(t, 1, strings.Count(content, `import * as TreeTree from "./tree.pb"`))
+	assert.Contains(t, content, "trees?: TreeTree.Node[]")
+}
+
+func TestOptionalStyle(t *testing.T) {
+	proto := `
+name: "optional.proto"
+package: "optional"
+syntax: "proto3"
+message_type {
+  name: "Item"
+  field { name: "parent" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".optional.Item" json_name: "parent" }
+  field { name: "children" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".optional.Item" json_name: "children" }
+  field { name: "note" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "note" oneof_index: 0 proto3_optional: true }
+  field { name: "name" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  oneof_decl { name: "_note" }
+}
+`
+
+	tests := []struct {
+		style    string
+		expected string
+	}{
+		{style: "undefined", expected: `export type Item = {
+  parent?: Item
+  children?: Item[]
+  note?: string | undefined
+  name?: string
+}`},
+		{style: "null", expected: `export type Item = {
+  parent?: Item | null
+  children?: Item[]
+  note?: string | null
+  name?: string
+}`},
+		{style: "both", expected: `export type Item = {
+  parent?: Item | null | undefined
+  children?: Item[]
+  note?: string | null | undefined
+  name?: string
+}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			generated := generate(t, map[string]string{"ts_optional_style": tt.style}, proto)
+			assert.Contains(t, generated["optional.pb.ts"], tt.expected)
+		})
+	}
+
+	_, err := New(map[string]string{"ts_optional_style": "nil"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting optional style information: unsupported value nil for ts_optional_style, valid values are undefined, null and both")
+}
+
+func TestFactories(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_factories": "true", "ts_message_kind": "class", "ts_int64_type": "bigint"}, `
+name: "factory.proto"
+package: "factory"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+}
+message_type {
+  name: "Item"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "count" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "count" }
+  field { name: "size" number: 3 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "size" }
+  field { name: "done" number: 4 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "done" }
+  field { name: "color" number: 5 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".factory.Color" json_name: "color" }
+  field { name: "tags" number: 6 label: LABEL_REPEATED type: TYPE_STRING json_name: "tags" }
+  field { name: "labels" number: 7 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".factory.Item.LabelsEntry" json_name: "labels" }
+  field { name: "parent" number: 8 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".factory.Item" json_name: "parent" }
+  field { name: "note" number: 9 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "note" oneof_index: 0 proto3_optional: true }
+  nested_type {
+    name: "LabelsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+    options { map_entry: true }
+  }
+  oneof_decl { name: "_note" }
+}
+`)
+
+	content := generated["factory.pb.ts"]
+	assert.Contains(t, content, `export function createItem(): Item {
+  return Object.assign(new Item(), {
+    name: "",
+    count: 0,
+    size: BigInt(0),
+    done: false,
+    color: Color.RED,
+    tags: [],
+    labels: {},
+  })
+}`)
+}
+
+func TestMocks(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_mocks": "true"}, `
+name: "line.proto"
+package: "line"
+syntax: "proto3"
+enum_type {
+  name: "Status"
+  value { name: "STATUS_UNSPECIFIED" number: 0 }
+  value { name: "STATUS_OPEN" number: 1 }
+}
+message_type {
+  name: "Line"
+  field { name: "sku" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "sku" }
+  field { name: "count" number: 2 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "count" }
+  field { name: "status" number: 3 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".line.Status" json_name: "status" }
+}
+`, `
+name: "order.proto"
+package: "order"
+syntax: "proto3"
+dependency: "line.proto"
+message_type {
+  name: "Order"
+  field { name: "lines" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".line.Line" json_name: "lines" }
+  field { name: "by_sku" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".order.Order.BySkuEntry" json_name: "bySku" }
+  field { name: "parent" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".order.Order" json_name: "parent" }
+  field { name: "customer" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".order.Customer" json_name: "customer" }
+  field { name: "email" number: 5 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "email" oneof_index: 0 }
+  field { name: "phone" number: 6 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "phone" oneof_index: 0 }
+  nested_type {
+    name: "BySkuEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".line.Line" json_name: "value" }
+    options { map_entry: true }
+  }
+  oneof_decl { name: "contact" }
+}
+message_type {
+  name: "Customer"
+  field { name: "last_order" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".order.Order" json_name: "lastOrder" }
+}
+`)
+
+	assert.NotContains(t, generated["order.pb.ts"], "makeOrder")
+	assert.Contains(t, generated["line.pb.mock.ts"], `export function makeLine(overrides?: Partial<Types.Line>): Types.Line {
+  const mock: Types.Line = {
+    sku: "sku",
+    count: "1",
+    status: Types.Status.STATUS_OPEN,
+  }
+  return {...mock, ...overrides}
+}`)
+
+	content := generated["order.pb.mock.ts"]
+	assert.Contains(t, content, `import * as LineLineMock from "./line.pb.mock"
+import * as Types from "./order.pb"`)
+	// messages referring to each other are only populated by the one with the greater name, recursive fields are left undefined
+	assert.Contains(t, content, `export function makeOrder(overrides?: Partial<Types.Order>): Types.Order {
+  const mock: Types.Order = {
+    lines: [LineLineMock.makeLine()],
+    bySku: {"key": LineLineMock.makeLine()},
+    customer: makeCustomer(),
+    email: "email",
+  }
+  return {...mock, ...overrides}
+}`)
+	assert.Contains(t, content, `export function makeCustomer(overrides?: Partial<Types.Customer>): Types.Customer {
+  const mock: Types.Customer = {}
+  return {...mock, ...overrides}
+}`)
+}
+
+func TestBidiStreamingOverWebSockets(t *testing.T) {
+	proto := `
+name: "chat.proto"
+package: "chat"
+syntax: "proto3"
+dependency: "google/api/annotations.proto"
+message_type {
+  name: "Message"
+  field { name: "text" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "text" }
+}
+service {
+  name: "Chat"
+  method { name: "Talk" input_type: ".chat.Message" output_type: ".chat.Message" client_streaming: true server_streaming: true }
+  method { name: "Upload" input_type: ".chat.Message" output_type: ".chat.Message" client_streaming: true }
+  method {
+    name: "Join"
+    input_type: ".chat.Message"
+    output_type: ".chat.Message"
+    client_streaming: true
+    server_streaming: true
+    options { [google.api.http] { post: "/rooms/{text}" body: "*" } }
+  }
+}
+`
+
+	content := generate(t, map[string]string{}, proto)["chat.pb.ts"]
+	assert.NotContains(t, content, "Talk")
+
+	generated := generate(t, map[string]string{"ts_ws_bidi": "true"}, proto)
+	content = generated["chat.pb.ts"]
+	assert.Contains(t, content, `  static Talk(initReq?: fm.InitReq): fm.DuplexStream<Message, Message> {
+    return fm.openDuplexStream<Message, Message>("/chat.Chat/Talk", "POST", initReq)
+  }
+  Talk(initReq?: fm.InitReq): fm.DuplexStream<Message, Message> {
+    return Chat.Talk(fm.mergeInitReq(this.initReq, initReq))
+  }`)
+	// client streaming methods and bidirectional streaming methods with path parameters are still skipped
+	assert.NotContains(t, content, "Upload")
+	assert.NotContains(t, content, "Join")
+	assert.Contains(t, generated["fetch.pb.ts"], "export function openDuplexStream<S, R>(")
+}
+
+func TestGuards(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_guards": "true"}, `
+name: "guard.proto"
+package: "guard"
+syntax: "proto2"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+}
+message_type {
+  name: "Item"
+  field { name: "name" number: 1 label: LABEL_REQUIRED type: TYPE_STRING json_name: "name" }
+  field { name: "colors" number: 2 label: LABEL_REPEATED type: TYPE_ENUM type_name: ".guard.Color" json_name: "colors" }
+  field { name: "children" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".guard.Item" json_name: "children" }
+}
+`)
+
+	content := generated["guard.pb.ts"]
+	assert.Contains(t, content, "export function isItem(x: unknown): x is Item {")
+	assert.Contains(t, content, "  v = m[\"name\"]\n  if (v === undefined || v === null || !(typeof v === \"string\")) {")
+	assert.Contains(t, content, `if (v !== undefined && v !== null && !(Array.isArray(v) && v.every(e => ["RED"].includes(e as string)))) {`)
+	assert.Contains(t, content, `if (v !== undefined && v !== null && !(Array.isArray(v) && v.every(e => isItem(e)))) {`)
+	assert.NotContains(t, generate(t, map[string]string{}, `
+name: "guard.proto"
+package: "guard"
+message_type { name: "Item" }
+`)["guard.pb.ts"], "isItem")
+}
+
+func TestEquals(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_equals": "true", "ts_bytes_type": "uint8array"}, `
+name: "equals.proto"
+package: "equals"
+syntax: "proto3"
+message_type {
+  name: "Item"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "data" number: 2 label: LABEL_OPTIONAL type: TYPE_BYTES json_name: "data" }
+  field { name: "children" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".equals.Item" json_name: "children" }
+  field { name: "labels" number: 4 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".equals.Item.LabelsEntry" json_name: "labels" }
+  nested_type {
+    name: "LabelsEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+    options { map_entry: true }
+  }
+}
+`)
+
+	content := generated["equals.pb.ts"]
+	assert.Contains(t, content, `export function equalsItem(a: Item, b: Item): boolean {
+  return a.name === b.name &&
+    equalsValue(a.data, b.data, equalsBytes) &&
+    equalsArray(a.children, b.children, equalsItem) &&
+    equalsMap(a.labels, b.labels, (x, y) => x === y)
+}`)
+	// only the helpers called by the equals functions are generated
+	assert.Contains(t, content, "function equalsBytes(a: Uint8Array, b: Uint8Array): boolean {")
+	assert.NotContains(t, content, "function equalsDate")
+	assert.NotContains(t, content, "function equalsJSON")
+}
+
+// encodedMessage encodes the fields of a message, each field is appended by a function of protowire
+func encodedMessage(fields ...func([]byte) []byte) []byte {
+	b := []byte{}
+	for _, f := range fields {
+		b = f(b)
+	}
+	return b
+}
+
+// embedded appends the embedded message as the field
+func embedded(number protowire.Number, message []byte) func([]byte) []byte {
+	return func(b []byte) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(b, number, protowire.BytesType), message)
+	}
+}
+
+// varint appends the varint as the field
+func varint(number protowire.Number, v uint64) func([]byte) []byte {
+	return func(b []byte) []byte {
+		return protowire.AppendVarint(protowire.AppendTag(b, number, protowire.VarintType), v)
+	}
+}
+
+func TestValidators(t *testing.T) {
+	fileDescriptor := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, prototext.Unmarshal([]byte(`
+name: "user.proto"
+package: "user"
+syntax: "proto3"
+message_type {
+  name: "User"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "age" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "age" }
+  field { name: "tags" number: 3 label: LABEL_REPEATED type: TYPE_STRING json_name: "tags" }
+  field { name: "address" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".user.Address" json_name: "address" }
+}
+message_type {
+  name: "Address"
+  field { name: "city" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "city" }
+}
+`), fileDescriptor))
+
+	// validate.rules of protoc-gen-validate, or buf.validate.field of protovalidate, on each field
+	fields := fileDescriptor.MessageType[0].Field
+	options := map[int][]byte{
+		0: encodedMessage(embedded(1071, encodedMessage(embedded(14, encodedMessage(varint(2, 3), varint(3, 20), func(b []byte) []byte {
+			return protowire.AppendString(protowire.AppendTag(b, 6, protowire.BytesType), "^[a-z]+$")
+		}))))),
+		1: encodedMessage(embedded(1159, encodedMessage(embedded(3, encodedMessage(varint(5, 18), varint(2, 150)))))),
+		2: encodedMessage(embedded(1159, encodedMessage(embedded(18, encodedMessage(varint(2, 5)))))),
+		3: encodedMessage(embedded(1071, encodedMessage(embedded(17, encodedMessage(varint(2, 1)))))),
+	}
+	for i, option := range options {
+		fields[i].Options = &descriptorpb.FieldOptions{}
+		fields[i].Options.ProtoReflect().SetUnknown(option)
+	}
+
+	generated := func(params map[string]string) string {
+		g, err := New(params)
+		require.NoError(t, err)
+		resp, err := g.Generate(&plugin.CodeGeneratorRequest{
+			FileToGenerate: []string{"user.proto"},
+			ProtoFile:      []*descriptorpb.FileDescriptorProto{fileDescriptor},
+		})
+		require.NoError(t, err)
+		return resp.GetFile()[0].GetContent()
+	}
+
+	assert.NotContains(t, generated(map[string]string{}), "validateUser")
+
+	content := generated(map[string]string{"ts_emit_validators": "true"})
+	assert.Contains(t, content, `export function validateUser(m: User): string[] {
+  const violations: string[] = []
+  let v: any
+  v = m["name"]
+  if ([...(v ?? "")].length < 3) {
+    violations.push("name must be at least 3 characters")
+  }
+  if ([...(v ?? "")].length > 20) {
+    violations.push("name must be at most 20 characters")
+  }
+  if (!new RegExp("^[a-z]+$").test(v ?? "")) {
+    violations.push("name must match the pattern ^[a-z]+$")
+  }
+  v = m["age"]
+  if (Number(v ?? 0) < 18) {
+    violations.push("age must be greater than or equal to 18")
+  }
+  if (Number(v ?? 0) >= 150) {
+    violations.push("age must be less than 150")
+  }
+  v = m["tags"]
+  if ((v ?? []).length > 5) {
+    violations.push("tags must have at most 5 items")
+  }
+  v = m["address"]
+  if (v === undefined || v === null) {
+    violations.push("address is required")
+  }
+  return violations
+}`)
+	// messages without constraints have no validators
+	assert.NotContains(t, content, "validateAddress")
+}
+
+func TestDeprecated(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "deprecated.proto"
+package: "deprecated"
+syntax: "proto3"
+message_type {
+  name: "Old"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" options { deprecated: true } }
+  field { name: "kept" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "kept" }
+  options { deprecated: true }
+}
+source_code_info {
+  location { path: 4 path: 0 span: 0 span: 0 span: 1 leading_comments: " Old is old.\n" }
+}
+`)
+
+	content := generated["deprecated.pb.ts"]
+	assert.Contains(t, content, "/**\n * Old is old.\n *\n * @deprecated\n */\nexport type Old = {")
+	assert.Contains(t, content, "  /**\n   * @deprecated\n   */\n  name?: string")
+	assert.Contains(t, content, "*/\n  name?: string\n  kept?: string")
+}
+
+// fieldMetadataFiles declare fields whose json names differ from their proto names, along with a required, a proto3 optional
+// and a deprecated field
+var fieldMetadataFiles = []string{`
+name: "item.proto"
+package: "item"
+syntax: "proto2"
+message_type {
+  name: "Item"
+  field { name: "item_id" number: 1 label: LABEL_REQUIRED type: TYPE_STRING json_name: "itemId" }
+  field { name: "display_name" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "title" }
+  field { name: "old_name" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "oldName" options { deprecated: true } }
+}
+`, `
+name: "patch.proto"
+package: "item"
+syntax: "proto3"
+message_type {
+  name: "Patch"
+  field { name: "display_name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "title" proto3_optional: true oneof_index: 0 }
+  oneof_decl { name: "_display_name" }
+}
+`}
+
+func TestFieldMetadataWithJSONNames(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_message_kind": "class", "ts_emit_guards": "true"}, fieldMetadataFiles...)
+
+	// the custom json name is rendered rather than the camelCase of the proto name
+	content := generated["item.pb.ts"]
+	assert.Contains(t, content, "export class Item {\n  itemId?: string\n  title?: string\n  /**\n   * @deprecated\n   */\n  oldName?: string\n")
+	assert.NotContains(t, content, "displayName")
+	assert.Contains(t, content, `    v = object["title"] ?? object["display_name"]`)
+	assert.Contains(t, content, `      json["title"] = this["title"]`)
+	// only the required field must be present
+	assert.Contains(t, content, "  v = m[\"itemId\"]\n  if (v === undefined || v === null || !(typeof v === \"string\")) {\n")
+	assert.Contains(t, content, "  v = m[\"title\"]\n  if (v !== undefined && v !== null && !(typeof v === \"string\")) {\n")
+
+	// proto3 optional fields can be absent, without being rendered as a oneof
+	content = generated["patch.pb.ts"]
+	assert.Contains(t, content, "  title?: string | undefined\n")
+	assert.NotContains(t, content, "only one of the fields can be set")
+}
+
+func TestFieldMetadataWithProtoNames(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_message_kind": "class", "ts_emit_guards": "true", "use_proto_names": "true"}, fieldMetadataFiles...)
+
+	// the proto names are rendered and sent, the json names are ignored
+	content := generated["item.pb.ts"]
+	assert.Contains(t, content, "export class Item {\n  item_id?: string\n  display_name?: string\n  /**\n   * @deprecated\n   */\n  old_name?: string\n")
+	assert.NotContains(t, content, "title")
+	assert.Contains(t, content, `    v = object["display_name"]`)
+	assert.Contains(t, content, `      json["display_name"] = this["display_name"]`)
+	assert.Contains(t, content, "  v = m[\"item_id\"]\n  if (v === undefined || v === null || !(typeof v === \"string\")) {\n")
+	assert.Contains(t, content, "  v = m[\"display_name\"]\n  if (v !== undefined && v !== null && !(typeof v === \"string\")) {\n")
+
+	content = generated["patch.pb.ts"]
+	assert.Contains(t, content, "  display_name?: string | undefined\n")
+	assert.NotContains(t, content, "only one of the fields can be set")
+}
+
+func TestSourceLocations(t *testing.T) {
+	proto := `
+name: "protos/located.proto"
+package: "located"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+}
+message_type { name: "Item" }
+service {
+  name: "Items"
+  method { name: "Get" input_type: ".located.Item" output_type: ".located.Item" }
+}
+source_code_info {
+  location { path: 5 path: 0 span: 3 span: 0 span: 6 span: 1 }
+  location { path: 4 path: 0 span: 8 span: 0 span: 20 leading_comments: " Item is an item.\n" }
+  location { path: 6 path: 0 span: 10 span: 0 span: 12 span: 1 }
+}
+`
+
+	content := generate(t, map[string]string{"ts_emit_source_locations": "true"}, proto)["protos/located.pb.ts"]
+	assert.Contains(t, content, "// from protos/located.proto:4\nexport enum Color {")
+	// the location is kept above the jsdoc, so that the jsdoc stays attached to the type
+	assert.Contains(t, content, "// from protos/located.proto:9\n/**\n * Item is an item.\n */\nexport type Item = {")
+	assert.Contains(t, content, "// from protos/located.proto:11\nexport class Items {")
+
+	assert.NotContains(t, generate(t, map[string]string{}, proto)["protos/located.pb.ts"], "// from")
+}
+
+func TestTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "messages.tmpl"), []byte(`{{define "messages"}}{{range .}}
+export interface {{.Name}} {
+{{- range .Fields}}
+  {{fieldName .}}?: {{tsType .}}
+{{- end}}
+}
+{{end}}{{end}}`), 0644))
+
+	generated := generate(t, map[string]string{"ts_template_dir": dir}, `
+name: "custom.proto"
+package: "custom"
+syntax: "proto3"
+enum_type {
+  name: "Color"
+  value { name: "RED" number: 0 }
+}
+message_type {
+  name: "Item"
+  field { name: "item_name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "itemName" }
+}
+`)
+
+	content := generated["custom.pb.ts"]
+	assert.Contains(t, content, "export interface Item {\n  itemName?: string\n}")
+	// the templates which are not overridden are still the built-in ones
+	assert.Contains(t, content, "export enum Color {")
+}
+
+func TestDryRun(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_dry_run": "true"}, `
+name: "a.proto"
+package: "a"
+syntax: "proto3"
+message_type { name: "A" }
+`, `
+name: "b/b.proto"
+package: "b"
+syntax: "proto3"
+dependency: "a.proto"
+message_type {
+  name: "B"
+  field { name: "a" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".a.A" json_name: "a" }
+}
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".b.B" output_type: ".b.B" }
+}
+`)
+
+	require.Len(t, generated, 1)
+	assert.JSONEq(t, `{
+  "generatedFiles": ["a.pb.ts", "b/b.pb.ts", "fetch.pb.ts"],
+  "files": [
+    {"name": "a.proto", "tsFileName": "a.pb.ts", "dependencies": [], "enums": [], "messages": ["A"], "services": []},
+    {
+      "name": "b/b.proto",
+      "tsFileName": "b/b.pb.ts",
+      "dependencies": [
+        {"moduleIdentifier": "AA", "sourceFile": "../a.pb"},
+        {"moduleIdentifier": "fm", "sourceFile": "../fetch.pb"}
+      ],
+      "enums": [],
+      "messages": ["B"],
+      "services": ["Service"]
+    }
+  ]
+}`, generated["ts_dry_run.json"])
+}
+
+func TestExtensions(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "base.proto"
+package: "base"
+syntax: "proto2"
+message_type {
+  name: "Base"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }
+  extension_range { start: 100 end: 200 }
+}
+`, `
+name: "ext.proto"
+package: "ext"
+syntax: "proto2"
+dependency: "base.proto"
+message_type {
+  name: "Holder"
+  field { name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+  extension { name: "holder" number: 101 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".ext.Holder" extendee: ".base.Base" json_name: "holder" }
+}
+extension { name: "note" number: 100 label: LABEL_OPTIONAL type: TYPE_STRING extendee: ".base.Base" json_name: "note" }
+`)
+
+	content := generated["base.pb.ts"]
+	assert.Contains(t, content, `import * as ExtExt from "./ext.pb"`)
+	assert.Contains(t, content, `
+export type Base = {
+  id?: string
+  "[ext.Holder.holder]"?: ExtExt.Holder
+  "[ext.note]"?: string
+}`)
+	assert.NotContains(t, generated["ext.pb.ts"], "note")
+}
+
+func TestAnyWellKnownTypeMapping(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_wkt_mapping": "true", "ts_emit_guards": "true"}, `
+name: "envelope.proto"
+package: "envelope"
+syntax: "proto3"
+dependency: "google/protobuf/any.proto"
+message_type {
+  name: "Envelope"
+  field { name: "payload" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Any" json_name: "payload" oneof_index: 0 }
+  field { name: "text" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "text" oneof_index: 0 }
+  field { name: "details" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".google.protobuf.Any" json_name: "details" }
+  oneof_decl { name: "body" }
+}
+`)
+
+	content := generated["envelope.pb.ts"]
+	assert.NotContains(t, content, "import")
+	assert.Contains(t, content, `  details?: {"@type": string, [key: string]: any}[]`)
+	assert.Contains(t, content, `OneOf<{ payload: {"@type": string, [key: string]: any}; text: string }>`)
+	// the type URL is checked by the guards
+	assert.Contains(t, content, `v.every(e => typeof e === "object" && e !== null && typeof (e as {[key: string]: unknown})["@type"] === "string")`)
+}
+
+func TestFieldMaskPaths(t *testing.T) {
+	file := `
+name: "update.proto"
+package: "update"
+syntax: "proto3"
+dependency: "google/protobuf/field_mask.proto"
+message_type {
+  name: "UpdateRequest"
+  field { name: "update_mask" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.FieldMask" json_name: "updateMask" }
+}
+`
+	content := generate(t, map[string]string{"ts_wkt_mapping": "true"}, file)["update.pb.ts"]
+	assert.Contains(t, content, "updateMask?: string\n")
+	assert.NotContains(t, content, "fieldMaskFromJSON")
+
+	// the message classes convert the paths from and into the comma-joined string
+	content = generate(t, map[string]string{"ts_wkt_mapping": "true", "ts_message_kind": "class", "ts_field_mask_type": "array"}, file)["update.pb.ts"]
+	assert.Contains(t, content, "updateMask?: string[]\n")
+	assert.Contains(t, content, `m["updateMask"] = fieldMaskFromJSON(v)`)
+	assert.Contains(t, content, `json["updateMask"] = fieldMaskToJSON(this["updateMask"])`)
+	assert.Contains(t, content, "function fieldMaskFromJSON(mask: string): string[] {")
+	assert.Contains(t, content, "function fieldMaskToJSON(paths: readonly string[]): string {")
+
+	_, err := New(map[string]string{"ts_field_mask_type": "array"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting field mask type information: ts_field_mask_type array is only supported with ts_message_kind class")
+}
+
+func TestExclude(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_exclude": "third_party/**;*.internal.proto"}, `
+name: "third_party/vendor/v1/money.proto"
+package: "vendor"
+syntax: "proto3"
+message_type { name: "Money" }
+`, `
+name: "admin.internal.proto"
+package: "admin"
+syntax: "proto3"
+message_type { name: "Admin" }
+`, `
+name: "order.proto"
+package: "order"
+syntax: "proto3"
+dependency: "third_party/vendor/v1/money.proto"
+message_type {
+  name: "Order"
+  field { name: "total" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".vendor.Money" json_name: "total" }
+}
+`)
+
+	assert.NotContains(t, generated, "third_party/vendor/v1/money.pb.ts")
+	assert.NotContains(t, generated, "admin.internal.pb.ts")
+	// the types of the excluded files are still resolved
+	assert.Contains(t, generated["order.pb.ts"], `import * as VendorMoney from "./third_party/vendor/v1/money.pb"`)
+	assert.Contains(t, generated["order.pb.ts"], "total?: VendorMoney.Money")
+}
+
+func TestImportsBetweenFilesInTheCurrentDirectory(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "bar.proto"
+package: "bar"
+syntax: "proto3"
+message_type { name: "Bar" }
+`, `
+name: "foo.proto"
+package: "foo"
+syntax: "proto3"
+dependency: "bar.proto"
+message_type {
+  name: "Foo"
+  field { name: "bar" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".bar.Bar" json_name: "bar" }
+}
+service {
+  name: "FooService"
+  method { name: "Get" input_type: ".foo.Foo" output_type: ".foo.Foo" }
+}
+`)
+
+	require.Contains(t, generated, "foo.pb.ts")
+	content := generated["foo.pb.ts"]
+	// bare specifiers would be resolved as packages rather than files in the same directory
+	assert.Contains(t, content, `import * as fm from "./fetch.pb"`)
+	assert.Contains(t, content, `import * as BarBar from "./bar.pb"`)
+}
+
+func TestEnumNamespaces(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_enum_namespaces": "true"}, `
+name: "protos/a.proto"
+package: "a"
+syntax: "proto3"
+message_type {
+  name: "Msg"
+  field { name: "status" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".a.Msg.Status" json_name: "status" }
+  enum_type { name: "Status" value { name: "UNKNOWN" number: 0 } }
+  nested_type { name: "Inner" enum_type { name: "Kind" value { name: "K0" number: 0 } } }
+}
+`, `
+name: "protos/b.proto"
+package: "b"
+syntax: "proto3"
+dependency: "protos/a.proto"
+message_type {
+  name: "Ref"
+  field { name: "status" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".a.Msg.Status" json_name: "status" }
+  field { name: "kind" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".a.Msg.Inner.Kind" json_name: "kind" }
+}
+`)
+
+	assert.Contains(t, generated["protos/a.pb.ts"], `
+export namespace Msg {
+  export type Status = MsgStatus
+  export namespace Inner {
+    export type Kind = MsgInnerKind
+  }
+}`)
+
+	// nested enums of other packages are imported from the file of the top level message
+	content := generated["protos/b.pb.ts"]
+	assert.Contains(t, content, `import * as AA from "./a.pb"`)
+	assert.Contains(t, content, "  status?: AA.MsgStatus\n  kind?: AA.MsgInnerKind\n")
+	assert.NotContains(t, content, "namespace")
+}
+
+func TestAngularClientStyle(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_client_style": "angular"}, `
+name: "item.proto"
+package: "item"
+syntax: "proto3"
+message_type {
+  name: "Item"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }
+}
+service {
+  name: "ItemService"
+  method { name: "Create" input_type: ".item.Item" output_type: ".item.Item" }
+  method { name: "Get" input_type: ".item.Item" output_type: ".item.Item" options { [google.api.http] { get: "/v1/items/{id}" } } }
+  method { name: "Watch" input_type: ".item.Item" output_type: ".item.Item" server_streaming: true }
+}
+`)
+
+	content := generated["item.pb.ts"]
+	assert.Contains(t, content, `import * as fm from "./fetch.pb"
+import { Injectable } from "@angular/core"
+import { HttpClient } from "@angular/common/http"
+import { Observable } from "rxjs"`)
+	assert.Contains(t, content, `@Injectable({providedIn: "root"})
+export class ItemService {
+  constructor(private readonly http: HttpClient) {}`)
+	assert.Contains(t, content, "  Create(req: Item): Observable<Item> {\n    return this.http.request<Item>(\"POST\", `/item.ItemService/Create`, {body: req})")
+	assert.Contains(t, content, "    return this.http.request<Item>(\"GET\", `/v1/items/${req[\"id\"]}?${fm.renderURLSearchParams(req, [\"id\"])}`)\n")
+	assert.Contains(t, content, "fm.fetchStreamingRequest<Item, Item>(`/item.ItemService/Watch`, resp => subscriber.next(resp), {signal: controller.signal, method: \"POST\", body: JSON.stringify(req)})")
+	// the url building and the streaming calls are shared with the fetch clients
+	assert.Contains(t, generated, "fetch.pb.ts")
+
+	_, err := New(map[string]string{"ts_client_style": "angular", "ts_module_system": "commonjs"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting client style information: ts_client_style angular is only supported with ts_module_system esm")
+}
+
+func TestReactQueryHooks(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_react_query": "true"}, `
+name: "item.proto"
+package: "item"
+syntax: "proto3"
+message_type {
+  name: "Item"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }
+}
+`, `
+name: "store.proto"
+package: "store"
+syntax: "proto3"
+dependency: "item.proto"
+dependency: "google/protobuf/empty.proto"
+message_type {
+  name: "GetRequest"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }
+}
+service {
+  name: "Store"
+  method { name: "Get" input_type: ".store.GetRequest" output_type: ".item.Item" options { [google.api.http] { get: "/v1/items/{id}" } } }
+  method { name: "Put" input_type: ".item.Item" output_type: ".item.Item" options { [google.api.http] { post: "/v1/items" body: "*" } } }
+  method { name: "Ping" input_type: ".google.protobuf.Empty" output_type: ".google.protobuf.Empty" }
+  method { name: "Watch" input_type: ".store.GetRequest" output_type: ".item.Item" server_streaming: true }
+}
+`)
+
+	assert.NotContains(t, generated, "item.pb.query.ts")
+	content := generated["store.pb.query.ts"]
+	assert.Contains(t, content, `import * as Client from "./store.pb"
+import * as ItemItem from "./item.pb"
+import * as ReactQuery from "@tanstack/react-query"
+import * as fm from "./fetch.pb"`)
+	assert.Contains(t, content, `export function getQueryKey(req: Client.GetRequest) {
+  return ["store.Store", "Get", req] as const
+}`)
+	assert.Contains(t, content, `export function useGetQuery(req: Client.GetRequest, initReq?: fm.InitReq, options?: Omit<ReactQuery.UseQueryOptions<ItemItem.Item>, "queryKey" | "queryFn">) {
+  return ReactQuery.useQuery({
+    ...options,
+    queryKey: getQueryKey(req),
+    queryFn: ({signal}) => Client.Store.Get(req, {...initReq, signal}),
+  })
+}`)
+	assert.Contains(t, content, `export function usePutMutation(initReq?: fm.InitReq, options?: Omit<ReactQuery.UseMutationOptions<ItemItem.Item, Error, ItemItem.Item>, "mutationFn">) {
+  return ReactQuery.useMutation({
+    ...options,
+    mutationFn: (req: ItemItem.Item) => Client.Store.Put(req, initReq),
+  })
+}`)
+	assert.Contains(t, content, "mutationFn: () => Client.Store.Ping({}, initReq),")
+	// streaming methods have no hooks
+	assert.NotContains(t, content, "Watch")
+
+	_, err := New(map[string]string{"ts_emit_react_query": "true", "ts_client_style": "angular"})
+	assert.EqualError(t, err, "error instantiating a new registry: ts_emit_react_query is only supported with ts_client_style fetch")
+}
+
+func TestMethodMetadata(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "item.proto"
+package: "item"
+syntax: "proto3"
+message_type {
+  name: "Item"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" }
+}
+service {
+  name: "ItemService"
+  method { name: "Create" input_type: ".item.Item" output_type: ".item.Item" }
+  method { name: "Get" input_type: ".item.Item" output_type: ".item.Item" options { [google.api.http] { get: "/v1/items/{id}" } } }
+  method { name: "Watch" input_type: ".item.Item" output_type: ".item.Item" server_streaming: true }
+}
+`)
+
+	assert.Contains(t, generated["item.pb.ts"], `
+export const ItemServiceMethods = {
+  Create: {name: "Create", httpMethod: "POST", path: "/item.ItemService/Create", requestType: "item.Item", responseType: "item.Item", serverStreaming: false},
+  Get: {name: "Get", httpMethod: "GET", path: "/v1/items/{id}", requestType: "item.Item", responseType: "item.Item", serverStreaming: false},
+  Watch: {name: "Watch", httpMethod: "POST", path: "/item.ItemService/Watch", requestType: "item.Item", responseType: "item.Item", serverStreaming: true},
+} as const`)
+}
+
+func TestPathTemplatePatterns(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "library.proto"
+package: "library"
+syntax: "proto3"
+message_type {
+  name: "Book"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "title" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "title" }
+}
+service {
+  name: "LibraryService"
+  method { name: "GetBook" input_type: ".library.Book" output_type: ".library.Book" options { [google.api.http] { get: "/v1/{name=shelves/*/books/*}" } } }
+  method { name: "UpdateBook" input_type: ".library.Book" output_type: ".library.Book" options { [google.api.http] { patch: "/v1/{name=shelves/*/books/*}" body: "*" } } }
+  method { name: "GetShelf" input_type: ".library.Book" output_type: ".library.Book" options { [google.api.http] { get: "/v1/{name=*}" } } }
+}
+`)
+
+	content := generated["library.pb.ts"]
+	// the whole value is substituted, only the field is left out of the query parameters and the body
+	assert.Contains(t, content, `fm.fetchReq<Book, Book>(`+"`"+`/v1/${fm.renderPathParam(req["name"], "name", "shelves/*/books/*")}?${fm.renderURLSearchParams(req, ["name"])}`+"`")
+	assert.Contains(t, content, `body: JSON.stringify(fm.omitPathParams(req, ["name"]))`)
+	// a single wildcard matches a single segment like a variable without a pattern
+	assert.Contains(t, content, "`/v1/${req[\"name\"]}?")
+}
+
+func TestAPIObject(t *testing.T) {
+	file := `
+name: "shop.proto"
+package: "shop"
+syntax: "proto3"
+message_type { name: "Item" }
+service {
+  name: "ItemService"
+  method { name: "Get" input_type: ".shop.Item" output_type: ".shop.Item" }
+}
+service {
+  name: "CartService"
+  method { name: "Add" input_type: ".shop.Item" output_type: ".shop.Item" }
+}
+`
+	assert.NotContains(t, generate(t, map[string]string{}, file)["shop.pb.ts"], "createApi")
+
+	content := generate(t, map[string]string{"ts_api_object": "true"}, file)["shop.pb.ts"]
+	assert.Contains(t, content, `
+export function createApi(initReq?: fm.InitReq) {
+  return {
+    ItemService: new ItemService(initReq),
+    CartService: new CartService(initReq),
+  }
+}`)
+	assert.Contains(t, content, "export const api = createApi()")
+
+	_, err := New(map[string]string{"ts_api_object": "true", "ts_client_style": "angular"})
+	assert.EqualError(t, err, "error instantiating a new registry: ts_api_object is only supported with ts_client_style fetch")
+}
+
+func TestEmptyRequestsAndResponses(t *testing.T) {
+	generated := generate(t, map[string]string{}, `
+name: "google/protobuf/empty.proto"
+package: "google.protobuf"
+syntax: "proto3"
+message_type { name: "Empty" }
+`, `
+name: "ping.proto"
+package: "ping"
+syntax: "proto3"
+dependency: "google/protobuf/empty.proto"
+message_type {
+  name: "Pong"
+  field { name: "at" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "at" }
+}
+service {
+  name: "PingService"
+  method { name: "Ping" input_type: ".google.protobuf.Empty" output_type: ".ping.Pong" }
+  method { name: "Reset" input_type: ".ping.Pong" output_type: ".google.protobuf.Empty" }
+}
+`)
+
+	content := generated["ping.pb.ts"]
+	assert.NotContains(t, content, "empty.pb")
+	assert.Contains(t, content, "  static Ping(req: {} = {}, initReq?: fm.InitReq): Promise<Pong> {\n    return fm.fetchReq<{}, Pong>(")
+	assert.Contains(t, content, "  Ping(req: {} = {}, initReq?: fm.InitReq): Promise<Pong> {")
+	assert.Contains(t, content, "  static Reset(req: Pong, initReq?: fm.InitReq): Promise<void> {\n    return fm.fetchReq<Pong, void>(")
+}
+
+func TestMessageClasses(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_message_kind": "class", "ts_int64_type": "bigint", "ts_emit_factories": "true"}, `
+name: "item.proto"
+package: "item"
+syntax: "proto3"
+message_type {
+  name: "Item"
+  field { name: "item_id" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "itemId" }
+  field { name: "name" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "displayName" }
+  field { name: "children" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".item.Item" json_name: "children" }
+}
+service {
+  name: "ItemService"
+  method { name: "Get" input_type: ".item.Item" output_type: ".item.Item" }
+}
+`)
+
+	content := generated["item.pb.ts"]
+	assert.Contains(t, content, "export class Item {\n  itemId?: bigint\n  displayName?: string\n  children?: Item[]\n")
+	assert.Contains(t, content, "    v = object[\"itemId\"] ?? object[\"item_id\"]\n    if (v !== undefined && v !== null) {\n      m[\"itemId\"] = BigInt(v)\n    }\n")
+	assert.Contains(t, content, "      m[\"children\"] = v.map((e: any) => Item.fromJSON(e))\n")
+	assert.Contains(t, content, "    return Object.assign(new Item(), m)\n")
+	assert.Contains(t, content, "      json[\"itemId\"] = String(this[\"itemId\"])\n")
+	assert.Contains(t, content, "      json[\"displayName\"] = this[\"displayName\"]\n")
+	assert.Contains(t, content, "  return Object.assign(new Item(), {\n")
+	// the responses are decoded into instances of the class
+	assert.Contains(t, content, "      .then(resp => Item.fromJSON(resp))\n")
+
+	_, err := New(map[string]string{"ts_message_kind": "class", "ts_bytes_type": "uint8array"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting message kind information: ts_message_kind class is not supported with ts_bytes_type uint8array")
+}
+
+func TestRender(t *testing.T) {
+	req := &plugin.CodeGeneratorRequest{FileToGenerate: []string{"svc.proto"}}
+	for _, f := range []string{`
+name: "dep.proto"
+package: "dep"
+syntax: "proto3"
+message_type { name: "Dep" }
+`, `
+name: "svc.proto"
+package: "svc"
+syntax: "proto3"
+dependency: "dep.proto"
+message_type {
+  name: "Request"
+  field { name: "dep" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".dep.Dep" json_name: "dep" }
+}
+service {
+  name: "Service"
+  method { name: "Call" input_type: ".svc.Request" output_type: ".svc.Request" }
+}
+`} {
+		fileDescriptor := &descriptorpb.FileDescriptorProto{}
+		require.NoError(t, prototext.Unmarshal([]byte(f), fileDescriptor))
+		req.ProtoFile = append(req.ProtoFile, fileDescriptor)
+	}
+
+	g, err := New(map[string]string{})
+	require.NoError(t, err)
+
+	filesData, err := g.Registry.Analyse(req)
+	require.NoError(t, err)
+
+	generated, err := g.Render(filesData)
+	require.NoError(t, err)
+
+	// only the files to generate are rendered, along with the fetch module of the services
+	assert.Len(t, generated, 2)
+	assert.Contains(t, generated["svc.pb.ts"], "  dep?: DepDep.Dep\n")
+	assert.Contains(t, generated, "fetch.pb.ts")
+}
+
+func TestEmit(t *testing.T) {
+	file := `
+name: "item.proto"
+package: "item"
+syntax: "proto3"
+enum_type { name: "Kind" value { name: "KIND_A" number: 0 } }
+message_type { name: "Item" }
+service {
+  name: "ItemService"
+  method { name: "Get" input_type: ".item.Item" output_type: ".item.Item" }
+}
+`
+	generated := generate(t, map[string]string{"ts_emit": "types"}, file)
+	assert.Contains(t, generated["item.pb.ts"], "export type Item = {")
+	assert.NotContains(t, generated["item.pb.ts"], "ItemService")
+	assert.NotContains(t, generated, "fetch.pb.ts")
+
+	generated = generate(t, map[string]string{"ts_emit": "services", "ts_types_dir": "gen/types", "output_dir": "gen/clients"}, file)
+	content := generated["item.pb.ts"]
+	assert.NotContains(t, content, "export type Item")
+	assert.NotContains(t, content, "export enum Kind")
+	// the types of the file itself are imported from the types-only output
+	assert.Contains(t, content, `import * as ItemItem from "../types/item.pb"`)
+	assert.Contains(t, content, "  static Get(req: ItemItem.Item, initReq?: fm.InitReq): Promise<ItemItem.Item> {")
+
+	_, err := New(map[string]string{"ts_emit": "services"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting emit information: ts_emit services requires ts_types_dir to import the types from")
+}
+
+func TestFieldAcronyms(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_message_kind": "class", "ts_field_acronyms": "id;URL"}, `
+name: "link.proto"
+package: "link"
+syntax: "proto3"
+message_type {
+  name: "Link"
+  field { name: "user_id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "userId" }
+  field { name: "http_url" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "httpUrl" }
+  field { name: "id_token" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "idToken" }
+  field { name: "custom_id" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "customKey" }
+}
+service {
+  name: "LinkService"
+  method { name: "Get" input_type: ".link.Link" output_type: ".link.Link" options { [google.api.http] { get: "/v1/links/{user_id}" } } }
+}
+`)
+
+	content := generated["link.pb.ts"]
+	assert.Contains(t, content, "export class Link {\n  userID?: string\n  httpURL?: string\n  idToken?: string\n  customKey?: string\n")
+	// the fields are still serialized by their json names
+	assert.Contains(t, content, "      json[\"userId\"] = this[\"userID\"]\n")
+	assert.Contains(t, content, "`/v1/links/${req[\"userID\"]}?${fm.renderURLSearchParams(fm.jsonPayload(req), [\"userId\"])}`")
+
+	_, err := New(map[string]string{"ts_field_acronyms": "id"})
+	assert.EqualError(t, err, "error instantiating a new registry: error getting field acronyms information: ts_field_acronyms is only supported with ts_message_kind class, which serializes the fields by their json names")
+}
+
+func TestRollupDeclarationFile(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_emit_rollup_dts": "true"}, `
+name: "a.proto"
+package: "com.a"
+syntax: "proto3"
+message_type {
+  name: "Outer"
+  field { name: "status" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".com.a.Outer.Status" json_name: "status" }
+  enum_type { name: "Status" value { name: "UNKNOWN" number: 0 } value { name: "OK" number: 1 } }
+}
+`, `
+name: "b.proto"
+package: "b"
+syntax: "proto3"
+dependency: "a.proto"
+message_type {
+  name: "Ref"
+  field { name: "outer" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".com.a.Outer" json_name: "outer" }
+  field { name: "statuses" number: 2 label: LABEL_REPEATED type: TYPE_ENUM type_name: ".com.a.Outer.Status" json_name: "statuses" }
+}
+`)
+
+	assert.Equal(t, `/*
+* This file is a generated Typescript file for GRPC Gateway, DO NOT MODIFY
+*/
+
+export namespace B {
+  export type Ref = {
+    outer?: ComA.Outer
+    statuses?: ComA.OuterStatus[]
+  }
+}
+
+export namespace ComA {
+  export type OuterStatus = "UNKNOWN" | "OK"
+  export type Outer = {
+    status?: ComA.OuterStatus
+  }
+}`, generated["types.d.ts"])
+}
+
+func TestVerboseSummary(t *testing.T) {
+	req := &plugin.CodeGeneratorRequest{}
+	for _, f := range []string{`
+name: "protos/a.proto"
+package: "a"
+syntax: "proto3"
+message_type { name: "Item" }
+`, `
+name: "protos/b.proto"
+package: "b"
+syntax: "proto3"
+dependency: "protos/a.proto"
+message_type {
+  name: "Request"
+  field { name: "item" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".a.Item" json_name: "item" }
+  field { name: "items" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".a.Item" json_name: "items" }
+}
+`} {
+		fileDescriptor := &descriptorpb.FileDescriptorProto{}
+		require.NoError(t, prototext.Unmarshal([]byte(f), fileDescriptor))
+		req.ProtoFile = append(req.ProtoFile, fileDescriptor)
+		req.FileToGenerate = append(req.FileToGenerate, fileDescriptor.GetName())
+	}
+
+	g, err := New(map[string]string{"ts_verbose": "true"})
+	require.NoError(t, err)
+	resp, err := g.Generate(req)
+	require.NoError(t, err)
+
+	// a.proto is looked up in the import roots once, however many fields refer to its types
+	var summary strings.Builder
+	writeSummary(&summary, g.Registry, resp.GetFile())
+	assert.Equal(t, `level=info msg="generation summary" dependencies=1 files=2 import_root_lookups=1 types=2`+"\n", summary.String())
+}
+
+func TestOperationIDMethodNames(t *testing.T) {
+	proto := `
+name: "protos/a.proto"
+package: "a"
+syntax: "proto3"
+message_type { name: "Item" }
+service {
+  name: "ItemService"
+  method {
+    name: "GetItem"
+    input_type: ".a.Item"
+    output_type: ".a.Item"
+    options {
+      [google.api.http] { get: "/v1/items" additional_bindings { post: "/v1/items:get" body: "*" } }
+      [grpc.gateway.protoc_gen_swagger.options.openapiv2_operation] { operation_id: "fetchItem" }
+    }
+  }
+  method {
+    name: "ListItems"
+    input_type: ".a.Item"
+    output_type: ".a.Item"
+    options { [google.api.http] { selector: "a.ItemService.listItems" get: "/v1/items:list" } }
+  }
+  method {
+    name: "DeleteItem"
+    input_type: ".a.Item"
+    output_type: ".a.Item"
+  }
+}
+`
+	content := generate(t, map[string]string{"ts_method_names": "operation_id"}, proto)["protos/a.pb.ts"]
+	assert.Contains(t, content, "static fetchItem(req: Item, initReq?: fm.InitReq): Promise<Item> {")
+	assert.Contains(t, content, "static fetchItemBinding1(req: Item, initReq?: fm.InitReq): Promise<Item> {")
+	assert.Contains(t, content, "static listItems(req: Item, initReq?: fm.InitReq): Promise<Item> {")
+	assert.Contains(t, content, "static DeleteItem(req: Item, initReq?: fm.InitReq): Promise<Item> {")
+
+	// the rpc names are used by default
+	content = generate(t, map[string]string{}, proto)["protos/a.pb.ts"]
+	assert.Contains(t, content, "static GetItem(req: Item, initReq?: fm.InitReq): Promise<Item> {")
+	assert.Contains(t, content, "static ListItems(req: Item, initReq?: fm.InitReq): Promise<Item> {")
+}
+
+func TestTopLevelAndNestedEnumImports(t *testing.T) {
+	generated := generate(t, map[string]string{"ts_message_kind": "class"}, `
+name: "protos/a/a.proto"
+package: "com.a"
+syntax: "proto3"
+enum_type { name: "Color" value { name: "RED" number: 0 } }
+message_type {
+  name: "Outer"
+  enum_type { name: "Status" value { name: "OK" number: 0 } }
+  nested_type {
+    name: "Inner"
+    enum_type { name: "Level" value { name: "LOW" number: 0 } }
+  }
+}
+message_type { name: "Out" enum_type { name: "erStatus" value { name: "ER" number: 0 } } }
+`, `
+name: "protos/b/b.proto"
+package: "com.b"
+syntax: "proto3"
+dependency: "protos/a/a.proto"
+message_type {
+  name: "Request"
+  field { name: "color" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".com.a.Color" json_name: "color" }
+  field { name: "level" number: 2 label: LABEL_REPEATED type: TYPE_ENUM type_name: ".com.a.Outer.Inner.Level" json_name: "level" }
+}
+`, `
+name: "protos/c/c.proto"
+package: "com.c"
+syntax: "proto3"
+dependency: "protos/a/a.proto"
+message_type {
+  name: "Request"
+  field { name: "color" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".com.a.Color" json_name: "color" }
+  field { name: "status" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".com.a.Outer.Status" json_name: "status" }
+  field { name: "er_status" number: 3 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".com.a.Out.erStatus" json_name: "erStatus" }
+}
+`)
+
+	// top level and nested enums are both referred to as <ModuleIdentifier>.<PackageIdentifier>, whichever file imports them
+	content := generated["protos/b/b.pb.ts"]
+	assert.Contains(t, content, `import * as ComAA from "../a/a.pb"`)
+	assert.Contains(t, content, "color?: ComAA.Color\n")
+	assert.Contains(t, content, "level?: ComAA.OuterInnerLevel[]\n")
+	assert.Contains(t, content, `m["color"] = ComAA.colorFromJSON(v)`)
+	assert.Contains(t, content, `m["level"] = v.map((e: any) => ComAA.outerInnerLevelFromJSON(e))`)
+
+	// nested enums whose identifiers collide are imported by their renamed identifiers
+	content = generated["protos/c/c.pb.ts"]
+	assert.Contains(t, content, `import * as ComAA from "../a/a.pb"`)
+	assert.Contains(t, content, "color?: ComAA.Color\n")
+	assert.Contains(t, content, "status?: ComAA.Outer_Status\n")
+	assert.Contains(t, content, "erStatus?: ComAA.Out_erStatus\n")
+	assert.Contains(t, content, `m["status"] = ComAA.outer_StatusFromJSON(v)`)
+	assert.Contains(t, generated["protos/a/a.pb.ts"], "export enum Outer_Status {")
+}
+
+func TestSourceHash(t *testing.T) {
+	file := `
+name: "protos/a.proto"
+package: "a"
+syntax: "proto3"
+message_type {
+  name: "Item"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+}
+`
+	params := map[string]string{"ts_emit_source_hash": "true", "ts_emit_mocks": "true"}
+	generated := generate(t, params, file)
+	header := generated["protos/a.pb.ts"][:strings.Index(generated["protos/a.pb.ts"], "/*")]
+	assert.Regexp(t, "^// @generated by protoc-gen-grpc-gateway-ts\n// source-hash: sha256:[0-9a-f]{64}\n$", header)
+	assert.True(t, strings.HasPrefix(generated["protos/a.pb.mock.ts"], header))
+
+	// the hash is the same across runs, and changes along with the proto file
+	assert.Equal(t, generated["protos/a.pb.ts"], generate(t, params, file)["protos/a.pb.ts"])
+	changed := generate(t, params, strings.Replace(file, `number: 1`, `number: 2`, 1))
+	assert.NotContains(t, changed["protos/a.pb.ts"], header)
+
+	assert.NotContains(t, generate(t, map[string]string{}, file)["protos/a.pb.ts"], "@generated")
+}
+
+func TestTypedArrays(t *testing.T) {
+	file := `
+name: "series.proto"
+package: "series"
+syntax: "proto3"
+message_type {
+  name: "Series"
+  field { name: "values" number: 1 label: LABEL_REPEATED type: TYPE_DOUBLE json_name: "values" }
+  field { name: "weights" number: 2 label: LABEL_REPEATED type: TYPE_FLOAT json_name: "weights" }
+  field { name: "counts" number: 3 label: LABEL_REPEATED type: TYPE_INT32 json_name: "counts" }
+}
+`
+	generated := generate(t, map[string]string{"ts_typed_arrays": "true", "ts_message_kind": "class", "ts_emit_guards": "true", "ts_emit_factories": "true"}, file)
+
+	content := generated["series.pb.ts"]
+	assert.Contains(t, content, "  values?: Float64Array\n")
+	assert.Contains(t, content, "  weights?: Float32Array\n")
+	assert.Contains(t, content, "  counts?: number[]\n")
+	// the JSON arrays are converted from and into the typed arrays by the message class
+	assert.Contains(t, content, `m["values"] = Float64Array.from(v)`)
+	assert.Contains(t, content, `json["weights"] = Array.from(this["weights"], e => Number.isFinite(e) ? e : String(e))`)
+	assert.Contains(t, content, "!(v instanceof Float64Array)")
+	assert.Contains(t, content, "    values: new Float64Array(),\n")
+
+	_, err := New(map[string]string{"ts_typed_arrays": "true"})
+	assert.EqualError(t, err, "error instantiating a new registry: ts_typed_arrays is only supported with ts_message_kind class")
+}
+
+func TestWellKnownTypeMappingOfJSONValues(t *testing.T) {
+	content := generate(t, map[string]string{"ts_wkt_mapping": "true"}, `
+name: "wkt.proto"
+package: "wkt"
+syntax: "proto3"
+dependency: "google/protobuf/struct.proto"
+message_type {
+  name: "Doc"
+  field { name: "s" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Struct" json_name: "s" }
+  field { name: "v" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Value" json_name: "v" }
+  field { name: "l" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.ListValue" json_name: "l" }
+  field { name: "n" number: 4 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".google.protobuf.NullValue" json_name: "n" }
+  field { name: "vs" number: 5 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".google.protobuf.Value" json_name: "vs" }
+}
+`)["wkt.pb.ts"]
+	assert.Contains(t, content, "  s?: {[key: string]: any}\n")
+	assert.Contains(t, content, "  v?: any\n")
+	assert.Contains(t, content, "  l?: any[]\n")
+	assert.Contains(t, content, "  n?: null\n")
+	assert.Contains(t, content, "  vs?: any[]\n")
+}
+
+func TestWellKnownTypeMapping(t *testing.T) {
+	file := `
+name: "wkt.proto"
+package: "wkt"
+syntax: "proto3"
+dependency: "google/protobuf/duration.proto"
+dependency: "google/protobuf/field_mask.proto"
+dependency: "google/protobuf/struct.proto"
+dependency: "google/protobuf/any.proto"
+dependency: "google/protobuf/wrappers.proto"
+message_type {
+  name: "Doc"
+  field { name: "ttl" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Duration" json_name: "ttl" }
+  field { name: "mask" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.FieldMask" json_name: "mask" }
+  field { name: "v" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Value" json_name: "v" }
+  field { name: "l" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.ListValue" json_name: "l" }
+  field { name: "n" number: 5 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".google.protobuf.NullValue" json_name: "n" }
+  field { name: "detail" number: 6 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Any" json_name: "detail" }
+  field { name: "count" number: 7 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Int64Value" json_name: "count" }
+  field { name: "total" number: 8 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.UInt64Value" json_name: "total" }
+}
+`
+	content := generate(t, map[string]string{"ts_wkt_mapping": "true"}, file)["wkt.pb.ts"]
+	assert.NotContains(t, content, "import")
+	assert.Contains(t, content, `export type Doc = {
+  ttl?: string
+  mask?: string
+  v?: any
+  l?: any[]
+  n?: null
+  detail?: {"@type": string, [key: string]: any}
+  count?: string
+  total?: string
+}`)
+
+	// the 64-bit wrappers follow ts_int64_type, and are converted by the message classes like the scalars they wrap
+	for _, test := range []struct {
+		int64Type string
+		fromJSON  string
+		toJSON    string
+	}{
+		{int64Type: "string", fromJSON: `m["count"] = v`, toJSON: `json["count"] = this["count"]`},
+		{int64Type: "number", fromJSON: `m["count"] = Number(v)`, toJSON: `json["count"] = this["count"]`},
+		{int64Type: "bigint", fromJSON: `m["count"] = BigInt(v)`, toJSON: `json["count"] = String(this["count"])`},
+	} {
+		t.Run(test.int64Type, func(t *testing.T) {
+			content := generate(t, map[string]string{"ts_wkt_mapping": "true", "ts_message_kind": "class", "ts_int64_type": test.int64Type}, file)["wkt.pb.ts"]
+			assert.Contains(t, content, "  count?: "+test.int64Type+"\n  total?: "+test.int64Type+"\n")
+			assert.Contains(t, content, test.fromJSON)
+			assert.Contains(t, content, test.toJSON)
+		})
+	}
+}
+
+func TestWellKnownTypeImports(t *testing.T) {
+	file := `
+name: "wkt.proto"
+package: "wkt"
+syntax: "proto3"
+dependency: "google/protobuf/timestamp.proto"
+dependency: "google/protobuf/wrappers.proto"
+dependency: "google/protobuf/struct.proto"
+message_type {
+  name: "Doc"
+  field { name: "created" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "created" }
+  field { name: "title" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.StringValue" json_name: "title" }
+  field { name: "attributes" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".wkt.Doc.AttributesEntry" json_name: "attributes" }
+  nested_type {
+    name: "AttributesEntry"
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Struct" json_name: "value" }
+    options { map_entry: true }
+  }
+}
+`
+	// fields mapped to their JSON representation don't leave any import behind
+	content := generate(t, map[string]string{"ts_wkt_mapping": "true"}, file)["wkt.pb.ts"]
+	assert.NotContains(t, content, "import")
+	assert.Contains(t, content, "created?: string\n")
+	assert.Contains(t, content, "title?: string\n")
+
+	// methods still refer to the message types of their requests and responses
+	content = generate(t, map[string]string{"ts_wkt_mapping": "true"}, strings.Replace(file, "message_type", `service {
+  name: "Docs"
+  method { name: "Title" input_type: ".wkt.Doc" output_type: ".google.protobuf.StringValue" }
+}
+message_type`, 1), `
+name: "google/protobuf/wrappers.proto"
+package: "google.protobuf"
+syntax: "proto3"
+message_type {
+  name: "StringValue"
+  field { name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+}
+`)["wkt.pb.ts"]
+	assert.Contains(t, content, `import * as GoogleProtobufWrappers from "./google/protobuf/wrappers.pb"`)
+	assert.NotContains(t, content, "GoogleProtobufTimestamp")
+	assert.Contains(t, content, "Promise<GoogleProtobufWrappers.StringValue>")
+}
+
+func TestServiceFiles(t *testing.T) {
+	files := []string{`
+name: "shop.proto"
+package: "shop"
+syntax: "proto3"
+dependency: "common/money.proto"
+message_type { name: "Item" }
+service {
+  name: "ItemService"
+  method { name: "Get" input_type: ".shop.Item" output_type: ".shop.Item" }
+}
+service {
+  name: "CartService"
+  method { name: "Total" input_type: ".shop.Item" output_type: ".common.Money" }
+}
+`, `
+name: "common/money.proto"
+package: "common"
+syntax: "proto3"
+message_type { name: "Money" }
+`}
+
+	generated := generate(t, map[string]string{"ts_emit_service_files": "true"}, files...)
+
+	// the generated file of the proto file only declares the types
+	content := generated["shop.pb.ts"]
+	assert.Contains(t, content, "export type Item = {")
+	assert.NotContains(t, content, "import")
+	assert.NotContains(t, content, "ItemService")
+
+	// each service imports the types from the generated file of its proto file
+	content = generated["shop.pb.ItemService.client.ts"]
+	assert.Contains(t, content, `import * as ShopShop from "./shop.pb"`)
+	assert.Contains(t, content, `import * as fm from "./fetch.pb"`)
+	assert.Contains(t, content, "static Get(req: ShopShop.Item, initReq?: fm.InitReq): Promise<ShopShop.Item> {")
+	assert.NotContains(t, content, "CartService")
+	assert.NotContains(t, content, "CommonMoney")
+
+	content = generated["shop.pb.CartService.client.ts"]
+	assert.Contains(t, content, `import * as CommonMoney from "./common/money.pb"`)
+	assert.Contains(t, content, "static Total(req: ShopShop.Item, initReq?: fm.InitReq): Promise<CommonMoney.Money> {")
+	assert.NotContains(t, content, "ItemService")
+	assert.Contains(t, generated, "fetch.pb.ts")
+
+	// barrels re-export the services from their own files
+	generated = generate(t, map[string]string{"ts_emit_service_files": "true", "ts_emit_barrels": "true"}, files...)
+	content = generated["index.ts"]
+	assert.Contains(t, content, `export { CartService, CartServiceMethods } from "./shop.pb.CartService.client"`)
+	assert.Contains(t, content, `export { ItemService, ItemServiceMethods } from "./shop.pb.ItemService.client"`)
+	assert.Contains(t, content, `export type { Item } from "./shop.pb"`)
+
+	// the hooks are generated next to the file of each service
+	generated = generate(t, map[string]string{"ts_emit_service_files": "true", "ts_emit_react_query": "true"}, files...)
+	assert.NotContains(t, generated, "shop.pb.query.ts")
+	content = generated["shop.pb.ItemService.client.query.ts"]
+	assert.Contains(t, content, `import * as Client from "./shop.pb.ItemService.client"`)
+	assert.NotContains(t, content, "CartService")
+	assert.Contains(t, generated["shop.pb.CartService.client.query.ts"], `import * as Client from "./shop.pb.CartService.client"`)
+
+	// service files are headed with the hash of their proto file as well
+	generated = generate(t, map[string]string{"ts_emit_service_files": "true", "ts_emit_source_hash": "true"}, files...)
+	header := generated["shop.pb.ts"][:strings.Index(generated["shop.pb.ts"], "/*")]
+	assert.Contains(t, header, "// source-hash: sha256:")
+	assert.True(t, strings.HasPrefix(generated["shop.pb.ItemService.client.ts"], header))
+	assert.True(t, strings.HasPrefix(generated["shop.pb.CartService.client.ts"], header))
+
+	_, err := New(map[string]string{"ts_emit_service_files": "true", "ts_bundle": "bundle.pb.ts"})
+	assert.EqualError(t, err, "error instantiating a new registry: ts_emit_service_files is not supported with ts_bundle")
+}
@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// renderGuardCheck renders a javascript expression checking whether the value stored in the variable matches the type of the field.
+// repeated fields and maps check every element of them
+func renderGuardCheck(r *registry.Registry) func(f *data.Field, variable string) string {
+	return func(f *data.Field, variable string) string {
+		typeInfo, ok := r.Types[f.Type]
+		if ok && typeInfo.IsMapEntry {
+			return fmt.Sprintf("typeof %s === \"object\" && %s !== null && Object.values(%s).every(e => %s)",
+				variable, variable, variable, renderGuardTypeCheck(r, typeInfo.ValueType, "e"))
+		}
+
+		if typedArray := typedArrayType(r, f); typedArray != "" {
+			return fmt.Sprintf("%s instanceof %s", variable, typedArray)
+		}
+
+		if f.IsRepeated {
+			return fmt.Sprintf("Array.isArray(%s) && %s.every(e => %s)", variable, variable, renderGuardTypeCheck(r, f, "e"))
+		}
+
+		return renderGuardTypeCheck(r, f, variable)
+	}
+}
+
+// renderGuardTypeCheck renders a javascript expression checking whether a single value matches the type, regardless of whether it's repeated.
+// the expression is not wrapped in parentheses, it's up to the caller
+func renderGuardTypeCheck(r *registry.Registry, fieldType data.Type, variable string) string {
+	info := fieldType.GetType()
+	if info.Type == "any" {
+		return fmt.Sprintf("typeof %s === \"object\" && %s !== null && typeof (%s as {[key: string]: unknown})[\"@type\"] === \"string\"", variable, variable, variable)
+	}
+
+	if strings.Index(info.Type, ".") != 0 {
+		switch tsScalarType := mapScalaType(r, info.Type); tsScalarType {
+		case "string", "number", "bigint", "boolean":
+			return fmt.Sprintf("typeof %s === %q", variable, tsScalarType)
+		case "Date", "Uint8Array":
+			return fmt.Sprintf("%s instanceof %s", variable, tsScalarType)
+		case "null":
+			return fmt.Sprintf("%s === null", variable)
+		case "any[]", "ReadonlyArray<any>":
+			return fmt.Sprintf("Array.isArray(%s)", variable)
+		case "string[]", "ReadonlyArray<string>":
+			return fmt.Sprintf("Array.isArray(%s) && %s.every(p => typeof p === \"string\")", variable, variable)
+		case "any":
+			return "true"
+		default:
+			// structs are plain objects
+			return fmt.Sprintf("typeof %s === \"object\" && %s !== null && !Array.isArray(%s)", variable, variable, variable)
+		}
+	}
+
+	typeInfo, ok := r.Types[info.Type]
+	if !ok {
+		return "true"
+	}
+
+	if typeInfo.ProtoType == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+		values := make([]string, 0, len(typeInfo.EnumValues))
+		for _, v := range typeInfo.EnumValues {
+			values = append(values, fmt.Sprintf("%q", v))
+		}
+		return fmt.Sprintf("[%s].includes(%s as string)", strings.Join(values, ", "), variable)
+	}
+
+	guard := "is" + typeInfo.PackageIdentifier
+	if info.IsExternal && !r.IsBundled(typeInfo.File) {
+		guard = data.GetModuleName(typeInfo.Package, typeInfo.File) + "." + guard
+	}
+	return fmt.Sprintf("%s(%s)", guard, variable)
+}
@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"path"
+	"sort"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// typeImports renders the references of the enums and the messages inside a file generated next to a generated file,
+// e.g. its mock file, and tracks the imports they need
+type typeImports struct {
+	r    *registry.Registry
+	file *data.File
+	// identifier is the name the generated file is imported as
+	identifier string
+	// dependencies are the imports needed by the rendered references keyed by their module identifiers
+	dependencies map[string]*data.Dependency
+}
+
+// newTypeImports returns the imports of a file generated next to the generated file, which always imports the generated file
+func newTypeImports(r *registry.Registry, file *data.File, identifier string) *typeImports {
+	return &typeImports{
+		r:          r,
+		file:       file,
+		identifier: identifier,
+		dependencies: map[string]*data.Dependency{
+			identifier: {ModuleIdentifier: identifier, SourceFile: "./" + path.Base(data.TrimTSExtension(file.TSFileName))},
+		},
+	}
+}
+
+// typeRef renders the reference of the enum or the message, and tracks the import it needs
+func (i *typeImports) typeRef(typeInfo *registry.TypeInformation) string {
+	if i.isLocal(typeInfo) {
+		return i.identifier + "." + typeInfo.PackageIdentifier
+	}
+
+	dependency := i.dependency(typeInfo)
+	if dependency == nil {
+		return i.identifier + "." + typeInfo.PackageIdentifier
+	}
+
+	i.dependencies[dependency.ModuleIdentifier] = dependency
+	return dependency.ModuleIdentifier + "." + typeInfo.PackageIdentifier
+}
+
+// isLocal returns whether the type is declared in the generated file, the types of the services only output are declared
+// in the types only output instead, and the types of a service file in the generated file of its proto file
+func (i *typeImports) isLocal(typeInfo *registry.TypeInformation) bool {
+	if i.r.Emit == registry.EmitServices || i.file.IsServiceFile {
+		return false
+	}
+
+	return typeInfo.File == i.file.Name || i.r.IsBundled(typeInfo.File)
+}
+
+// dependency returns the import of the generated file declaring the type, the files sit next to the generated file
+// so that the same import path is used
+func (i *typeImports) dependency(typeInfo *registry.TypeInformation) *data.Dependency {
+	return i.findDependency(data.GetModuleName(typeInfo.Package, typeInfo.File))
+}
+
+// findDependency returns the import of the generated file with the module identifier
+func (i *typeImports) findDependency(moduleIdentifier string) *data.Dependency {
+	for _, d := range i.file.Dependencies {
+		if d.ModuleIdentifier == moduleIdentifier {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// sortedDependencies returns the tracked imports in the order of their module identifiers, so that the output is the same across runs
+func (i *typeImports) sortedDependencies() []*data.Dependency {
+	dependencies := make([]*data.Dependency, 0, len(i.dependencies))
+	for _, d := range i.dependencies {
+		dependencies = append(dependencies, d)
+	}
+	sort.Slice(dependencies, func(a, b int) bool {
+		return dependencies[a].ModuleIdentifier < dependencies[b].ModuleIdentifier
+	})
+
+	return dependencies
+}
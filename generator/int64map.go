@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"github.com/iancoleman/strcase"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// isInt64Type returns whether the proto type is a 64-bit integer, which is encoded as a string in JSON
+func isInt64Type(protoType string) bool {
+	switch protoType {
+	case "uint64", "sint64", "int64", "fixed64", "sfixed64":
+		return true
+	}
+
+	return false
+}
+
+// getInt64KeyedMaps returns the map fields of the message keyed by 64-bit integers, which need helpers converting their keys.
+// there are no such fields when 64-bit integers are rendered as strings, the keys of the index signature are strings already
+func getInt64KeyedMaps(r *registry.Registry) func(m *data.Message) []*data.Field {
+	return func(m *data.Message) []*data.Field {
+		fields := make([]*data.Field, 0)
+		if r.Int64Type == registry.Int64TypeString {
+			return fields
+		}
+
+		for _, f := range m.Fields {
+			typeInfo, ok := r.Types[f.Type]
+			if ok && typeInfo.IsMapEntry && isInt64Type(typeInfo.KeyType.Type) {
+				fields = append(fields, f)
+			}
+		}
+
+		return fields
+	}
+}
+
+// int64MapEntriesName returns the name of the helper converting the entries of a map field keyed by 64-bit integers, e.g. fooItemsEntries
+func int64MapEntriesName(m *data.Message, f *data.Field) string {
+	return untitle(m.Name) + strcase.ToCamel(f.Name) + "Entries"
+}
+
+// mapValueType renders the typescript type of the values of a map field
+func mapValueType(r *registry.Registry) func(f *data.Field) string {
+	return func(f *data.Field) string {
+		return tsType(r, r.Types[f.Type].ValueType)
+	}
+}
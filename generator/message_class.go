@@ -0,0 +1,211 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/iancoleman/strcase"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// jsonFieldName returns the key of the field in the JSON encoded by grpc-gateway, which is the proto name with use_proto_names
+// and the json name otherwise
+func jsonFieldName(r *registry.Registry, f *data.Field) string {
+	if r.UseProtoNames || f.IsExtension {
+		return f.Name
+	}
+
+	if f.JSONName != "" {
+		return f.JSONName
+	}
+
+	return strcase.ToLowerCamel(f.Name)
+}
+
+// renderJSONLookup renders the expression reading the field out of a JSON object,
+// both the json name and the proto name are accepted like protojson does
+func renderJSONLookup(r *registry.Registry) func(f *data.Field, object string) string {
+	return func(f *data.Field, object string) string {
+		jsonName := jsonFieldName(r, f)
+		lookup := fmt.Sprintf("%s[%q]", object, jsonName)
+		if jsonName != f.Name {
+			lookup += fmt.Sprintf(" ?? %s[%q]", object, f.Name)
+		}
+
+		return lookup
+	}
+}
+
+// renderFromJSONValue renders the expression converting the JSON value of the field into its typescript type,
+// element by element for repeated fields and value by value for maps
+func renderFromJSONValue(r *registry.Registry) func(f *data.Field, value string) string {
+	return func(f *data.Field, value string) string {
+		valueType, isExternal := f.Type, f.IsExternal
+		isMap := false
+		if typeInfo, ok := r.Types[f.Type]; ok && typeInfo.IsMapEntry {
+			valueType, isExternal = typeInfo.ValueType.Type, typeInfo.ValueType.IsExternal
+			isMap = true
+		}
+
+		if typedArray := typedArrayType(r, f); typedArray != "" {
+			return fmt.Sprintf("%s.from(%s)", typedArray, value)
+		}
+
+		convert := renderFromJSONConversion(r, valueType, isExternal)
+		switch {
+		case convert == "":
+			return value
+		case isMap:
+			return fmt.Sprintf("Object.fromEntries(Object.entries(%s).map(([k, e]: [string, any]) => [k, %s]))", value, fmt.Sprintf(convert, "e"))
+		case f.IsRepeated:
+			return fmt.Sprintf("%s.map((e: any) => %s)", value, fmt.Sprintf(convert, "e"))
+		}
+
+		return fmt.Sprintf(convert, value)
+	}
+}
+
+// renderFromJSONConversion returns the format converting a single JSON value of the type, an empty string when it's kept as is
+func renderFromJSONConversion(r *registry.Registry, protoType string, isExternal bool) string {
+	if typeInfo, ok := r.Types[protoType]; ok {
+		name := tsType(r, &data.MethodArgument{Type: protoType, IsExternal: isExternal})
+		if typeInfo.ProtoType == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+			// enums are accepted by both their names and numbers
+			dot := strings.LastIndex(name, ".")
+			return name[:dot+1] + untitle(name[dot+1:]) + "FromJSON(%s)"
+		}
+
+		return name + ".fromJSON(%s)"
+	}
+
+	if isInt64Type(protoType) {
+		switch r.Int64Type {
+		case registry.Int64TypeBigInt:
+			return "BigInt(%s)"
+		case registry.Int64TypeNumber:
+			return "Number(%s)"
+		}
+	}
+
+	if protoType == "timestamp" && r.TimestampType == registry.TimestampTypeDate {
+		return "new Date(%s)"
+	}
+
+	if protoType == "fieldmask" && r.FieldMaskType == registry.FieldMaskTypeArray {
+		return "fieldMaskFromJSON(%s)"
+	}
+
+	return ""
+}
+
+// renderToJSONValue renders the expression converting the field into its JSON value.
+// only bigint, field mask paths and typed arrays need to be converted, JSON.stringify calls toJSON of nested messages and dates
+// by itself, while it would encode typed arrays as objects keyed by the indices
+func renderToJSONValue(r *registry.Registry) func(f *data.Field, value string) string {
+	return func(f *data.Field, value string) string {
+		if typedArrayType(r, f) != "" {
+			// NaN and the infinities are encoded as strings like protojson does, JSON.stringify would turn them into null
+			return fmt.Sprintf("Array.from(%s, e => Number.isFinite(e) ? e : String(e))", value)
+		}
+
+		valueType := f.Type
+		isMap := false
+		if typeInfo, ok := r.Types[f.Type]; ok && typeInfo.IsMapEntry {
+			valueType = typeInfo.ValueType.Type
+			isMap = true
+		}
+
+		convert := ""
+		switch {
+		case isInt64Type(valueType) && r.Int64Type == registry.Int64TypeBigInt:
+			convert = "String"
+		case valueType == "fieldmask" && r.FieldMaskType == registry.FieldMaskTypeArray:
+			convert = "fieldMaskToJSON"
+		default:
+			return value
+		}
+
+		switch {
+		case isMap:
+			return fmt.Sprintf("Object.fromEntries(Object.entries(%s).map(([k, e]) => [k, %s(e)]))", value, convert)
+		case f.IsRepeated:
+			return fmt.Sprintf("%s.map(%s)", value, convert)
+		}
+
+		return fmt.Sprintf("%s(%s)", convert, value)
+	}
+}
+
+// hasFieldMaskPaths returns whether the messages have field mask fields rendered as arrays of paths, which need the helpers
+// converting them from and into JSON
+func hasFieldMaskPaths(r *registry.Registry) func(messages []*data.Message) bool {
+	return func(messages []*data.Message) bool {
+		if r.FieldMaskType != registry.FieldMaskTypeArray {
+			return false
+		}
+
+		for _, m := range messages {
+			for _, f := range m.Fields {
+				valueType := f.Type
+				if typeInfo, ok := r.Types[f.Type]; ok && typeInfo.IsMapEntry {
+					valueType = typeInfo.ValueType.Type
+				}
+				if valueType == "fieldmask" {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// requestPayload renders the request the url query parameters and the body without the path parameters are built from.
+// the properties of the message classes might not be named after the json names, so their JSON representation is used instead
+func requestPayload(r *registry.Registry) string {
+	if r.MessageKind == registry.MessageKindClass {
+		return "fm.jsonPayload(req)"
+	}
+
+	return "req"
+}
+
+// payloadFieldName returns the name of the field inside the request payload rendered by requestPayload
+func payloadFieldName(r *registry.Registry, method data.Method, name string) string {
+	if r.MessageKind != registry.MessageKindClass {
+		return pathParamFieldName(r, method, name)
+	}
+
+	jsonName := ""
+	if typeInfo, ok := r.Types[method.Input.Type]; ok {
+		jsonName = typeInfo.FieldJSONNames[name]
+	}
+
+	return jsonFieldName(r, &data.Field{Name: name, JSONName: jsonName})
+}
+
+// needsResponseDecoding returns whether the client needs to decode the JSON response into the method output,
+// either to convert its bytes fields or to create the message class
+func needsResponseDecoding(r *registry.Registry) func(arg *data.MethodArgument) bool {
+	return func(arg *data.MethodArgument) bool {
+		if r.MessageKind == registry.MessageKindClass && !arg.IsEmpty {
+			return true
+		}
+
+		return needsBytesConversion(r)(arg)
+	}
+}
+
+// decodeResponse renders the expression decoding the JSON response into the method output
+func decodeResponse(r *registry.Registry) func(arg *data.MethodArgument, value string) string {
+	return func(arg *data.MethodArgument, value string) string {
+		if r.MessageKind == registry.MessageKindClass {
+			return fmt.Sprintf("%s.fromJSON(%s)", tsType(r, arg), value)
+		}
+
+		return fmt.Sprintf("fm.convertBytes(%s, %q, bytesFields, fm.base64Decode)", value, arg.Type)
+	}
+}
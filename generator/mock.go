@@ -0,0 +1,286 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pkg/errors"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// mockTypesIdentifier is the name the generated file is imported as inside its mock file
+const mockTypesIdentifier = "Types"
+
+// mockFile is the mock file of a generated file, with a make function for each of its messages
+type mockFile struct {
+	// Dependencies are the generated file, the files of the types referenced by the fields and the mock files of their messages
+	Dependencies []*data.Dependency
+	// Messages are the make functions of the messages of the generated file
+	Messages []*mockMessage
+}
+
+// mockMessage is the make function of a message
+type mockMessage struct {
+	// Name is the identifier of the message inside its file
+	Name string
+	// Type is the message referenced from the mock file
+	Type string
+	// Fields are the fields populated by the make function
+	Fields []*mockField
+}
+
+// mockField is a field populated with a dummy value
+type mockField struct {
+	// Name is the property name of the field
+	Name string
+	// Value is the typescript expression of the dummy value
+	Value string
+}
+
+// mockRenderer renders the dummy values of the messages of a single generated file and tracks the imports they need
+type mockRenderer struct {
+	*typeImports
+}
+
+// generateMocks generates a mock file next to each generated file with messages, so that the make functions of the mock files
+// are only bundled by the tests importing them
+func (t *TypeScriptGRPCGatewayGenerator) generateMocks(files []*data.File) ([]*plugin.CodeGeneratorResponse_File, error) {
+	tmpl := GetMockTemplate(t.Registry)
+	generated := make([]*plugin.CodeGeneratorResponse_File, 0, len(files))
+	for _, f := range files {
+		if len(f.Messages) == 0 {
+			continue
+		}
+
+		renderer := &mockRenderer{newTypeImports(t.Registry, f, mockTypesIdentifier)}
+		mock := &mockFile{}
+		for _, m := range f.Messages {
+			mock.Messages = append(mock.Messages, renderer.renderMessage(m))
+		}
+		mock.Dependencies = renderer.sortedDependencies()
+
+		tsFileName := data.TrimTSExtension(f.TSFileName)
+		w := bytes.NewBufferString("")
+		fileName := tsFileName + ".mock" + strings.TrimPrefix(f.TSFileName, tsFileName)
+		if err := tmpl.Execute(w, mock); err != nil {
+			return nil, errors.Wrapf(err, "error generating mock file %s", fileName)
+		}
+
+		content := sourceHashHeader(f) + strings.TrimSpace(w.String())
+		generated = append(generated, &plugin.CodeGeneratorResponse_File{
+			Name:    &fileName,
+			Content: &content,
+		})
+	}
+
+	return generated, nil
+}
+
+// renderMessage renders the make function of the message. only the first field of each oneof is populated,
+// and extensions are left undefined
+func (m *mockRenderer) renderMessage(message *data.Message) *mockMessage {
+	mock := &mockMessage{
+		Name: message.Name,
+		Type: m.typeRef(m.r.Types[message.FQType]),
+	}
+
+	populatedOneOfs := make(map[int32]bool)
+	for _, f := range message.Fields {
+		if f.IsExtension {
+			continue
+		}
+
+		if f.IsOneOfField {
+			if populatedOneOfs[f.OneOfIndex] {
+				continue
+			}
+			populatedOneOfs[f.OneOfIndex] = true
+		}
+
+		value := m.renderFieldValue(message.FQType, f)
+		if value == "" {
+			continue
+		}
+
+		mock.Fields = append(mock.Fields, &mockField{Name: propertyName(m.r)(f), Value: value})
+	}
+
+	return mock
+}
+
+// renderFieldValue renders the dummy value of the field, repeated fields and maps contain a single dummy element
+func (m *mockRenderer) renderFieldValue(messageType string, f *data.Field) string {
+	if typeInfo, ok := m.r.Types[f.Type]; ok && typeInfo.IsMapEntry {
+		value := m.renderValue(messageType, typeInfo.ValueType.Type, f.Name)
+		if value == "" {
+			return ""
+		}
+
+		key := `"key"`
+		switch mapScalaType(m.r, typeInfo.KeyType.Type) {
+		case "string":
+		case "boolean":
+			key = `"true"`
+		default:
+			key = `"1"`
+		}
+		return fmt.Sprintf("{%s: %s}", key, value)
+	}
+
+	value := m.renderValue(messageType, f.Type, f.Name)
+	if value == "" || !f.IsRepeated {
+		return value
+	}
+
+	if typedArray := typedArrayType(m.r, f); typedArray != "" {
+		return "new " + typedArray + "([" + value + "])"
+	}
+
+	return "[" + value + "]"
+}
+
+// renderValue renders a single dummy value of the type, an empty string is returned for the values left undefined
+func (m *mockRenderer) renderValue(messageType, protoType, fieldName string) string {
+	if typeInfo, ok := m.r.Types[protoType]; ok {
+		if typeInfo.ProtoType == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+			return m.renderEnumValue(typeInfo)
+		}
+
+		return m.renderMessageValue(messageType, typeInfo)
+	}
+
+	if isInt64Type(protoType) {
+		switch m.r.Int64Type {
+		case registry.Int64TypeBigInt:
+			return "BigInt(1)"
+		case registry.Int64TypeNumber:
+			return "1"
+		}
+		return `"1"`
+	}
+
+	switch protoType {
+	case "string":
+		return strconv.Quote(fieldName)
+	case "fieldmask":
+		if m.r.FieldMaskType == registry.FieldMaskTypeArray {
+			return "[" + strconv.Quote(fieldName) + "]"
+		}
+		return strconv.Quote(fieldName)
+	case "duration":
+		return `"1s"`
+	case "timestamp":
+		if m.r.TimestampType == registry.TimestampTypeDate {
+			return `new Date("2000-01-01T00:00:00Z")`
+		}
+		return `"2000-01-01T00:00:00Z"`
+	case "struct":
+		return "{}"
+	case "any":
+		return `{"@type": "type.googleapis.com/google.protobuf.Empty"}`
+	case "listvalue":
+		return "[]"
+	case "value", "nullvalue":
+		return "null"
+	case "bool":
+		return "true"
+	case "bytes":
+		if m.r.BytesType == registry.BytesTypeUint8Array {
+			return "new Uint8Array([1])"
+		}
+		return `"AQ=="`
+	}
+
+	if mapScalaType(m.r, protoType) == "number" {
+		return "1"
+	}
+
+	return ""
+}
+
+// renderEnumValue renders the first value of the enum after the default one, the default value when it's the only one
+func (m *mockRenderer) renderEnumValue(typeInfo *registry.TypeInformation) string {
+	if len(typeInfo.EnumValues) == 0 {
+		return ""
+	}
+
+	value := typeInfo.EnumValues[0]
+	if len(typeInfo.EnumValues) > 1 {
+		value = typeInfo.EnumValues[1]
+	}
+
+	if m.r.EnumStyle == registry.EnumStyleStringUnion {
+		return strconv.Quote(value)
+	}
+
+	return m.typeRef(typeInfo) + "." + value
+}
+
+// renderMessageValue renders the call of the make function of the message, messages of the files imported from elsewhere have no mock file
+// and are left undefined. messages referring to each other would never stop populating each other, only the one with the greater
+// fully qualified name populates the other, so that the make functions populating each other are the same across the mock files
+func (m *mockRenderer) renderMessageValue(messageType string, typeInfo *registry.TypeInformation) string {
+	if typeInfo.FullyQualifiedName >= messageType && m.refersTo(typeInfo.FullyQualifiedName, messageType, map[string]bool{}) {
+		return ""
+	}
+
+	if m.isLocal(typeInfo) {
+		return "make" + typeInfo.PackageIdentifier + "()"
+	}
+
+	if !m.r.IsImportedFromOutput(typeInfo.File) {
+		return ""
+	}
+
+	dependency := m.dependency(typeInfo)
+	if dependency == nil {
+		return ""
+	}
+
+	identifier := dependency.ModuleIdentifier + "Mock"
+	m.dependencies[identifier] = &data.Dependency{ModuleIdentifier: identifier, SourceFile: dependency.SourceFile + ".mock"}
+	return identifier + ".make" + typeInfo.PackageIdentifier + "()"
+}
+
+// refersTo returns whether any field of the message refers to the target message, directly or through other messages
+func (m *mockRenderer) refersTo(messageType, target string, visited map[string]bool) bool {
+	visited[messageType] = true
+	typeInfo, ok := m.r.Types[messageType]
+	if !ok {
+		return false
+	}
+
+	for _, f := range typeInfo.Fields {
+		fieldType := f.Type
+		if fieldTypeInfo, ok := m.r.Types[fieldType]; ok && fieldTypeInfo.IsMapEntry {
+			fieldType = fieldTypeInfo.ValueType.Type
+		}
+
+		if fieldType == target || (!visited[fieldType] && m.refersTo(fieldType, target, visited)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetMockTemplate returns the go template for the mock files
+func GetMockTemplate(r *registry.Registry) *template.Template {
+	t := template.New("mock")
+	t = t.Funcs(template.FuncMap{
+		"moduleSystem": func() string {
+			return r.ModuleSystem
+		},
+		"messageKind": func() string {
+			return r.MessageKind
+		},
+	})
+	return template.Must(t.Parse(mockTmpl))
+}
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"strings"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// enumNamespace is the namespace of a message, which re-exports the types of the enums nested in the message
+// and contains the namespaces of the nested messages with nested enums
+type enumNamespace struct {
+	name     string
+	aliases  [][2]string
+	children []*enumNamespace
+}
+
+// child returns the namespace of the nested message, it's created when it doesn't exist yet
+func (n *enumNamespace) child(name string) *enumNamespace {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+
+	c := &enumNamespace{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+func (n *enumNamespace) render(b *strings.Builder, indent string) {
+	b.WriteString(indent + "export namespace " + n.name + " {\n")
+	for _, alias := range n.aliases {
+		b.WriteString(indent + "  export type " + alias[0] + " = " + alias[1] + "\n")
+	}
+	for _, c := range n.children {
+		c.render(b, indent+"  ")
+	}
+	b.WriteString(indent + "}\n")
+}
+
+// renderEnumNamespaces renders a namespace for each top level message with nested enums, following the nesting in the proto file,
+// e.g. the enum Status nested in Outer.Inner is reachable as the type Outer.Inner.Status next to OuterInnerStatus.
+// namespaces only contain types, so that the values are still accessed through the top level enums
+func renderEnumNamespaces(r *registry.Registry) func(f *data.File) string {
+	return func(f *data.File) string {
+		if !r.EnumNamespaces {
+			return ""
+		}
+
+		root := &enumNamespace{}
+		for _, e := range f.Enums {
+			typeInfo, ok := r.Types[e.FQType]
+			if !ok {
+				continue
+			}
+
+			prefix := "."
+			if typeInfo.Package != "" {
+				prefix = "." + typeInfo.Package + "."
+			}
+			parts := strings.Split(strings.TrimPrefix(e.FQType, prefix), ".")
+			if len(parts) < 2 {
+				// top level enums don't have a namespace
+				continue
+			}
+
+			// the namespace needs to merge with the rendered name of the top level message, which differs from the proto name inside bundles
+			topLevel := f.FindMessage(prefix + parts[0])
+			if topLevel == nil {
+				continue
+			}
+
+			n := root.child(topLevel.Name)
+			for _, parent := range parts[1 : len(parts)-1] {
+				n = n.child(parent)
+			}
+			n.aliases = append(n.aliases, [2]string{parts[len(parts)-1], e.Name})
+		}
+
+		var b strings.Builder
+		for i, n := range root.children {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			n.render(&b, "")
+		}
+
+		return b.String()
+	}
+}
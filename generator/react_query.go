@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pkg/errors"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// reactQueryClientIdentifier is the name the generated file is imported as inside its query file
+const reactQueryClientIdentifier = "Client"
+
+// reactQueryDependency is the import of React Query inside the query files
+var reactQueryDependency = &data.Dependency{ModuleIdentifier: "ReactQuery", SourceFile: "@tanstack/react-query"}
+
+// reactQueryFile is the query file of a generated file, with a hook for each unary method of its services
+type reactQueryFile struct {
+	// Dependencies are React Query, the fetch module, the generated file and the files of the types of the requests and responses
+	Dependencies []*data.Dependency
+	// Methods are the hooks of the unary methods of the services
+	Methods []*reactQueryMethod
+}
+
+// reactQueryMethod is the hook of a unary method
+type reactQueryMethod struct {
+	// Name is the name of the hook without the use prefix and the Query or Mutation suffix, the method name prefixed
+	// by the service name when several services of the file have a method of that name
+	Name string
+	// Service is the fully qualified name of the service, which is the first element of the query keys
+	Service string
+	// Client is the client of the service referenced from the query file
+	Client string
+	// Method is the name of the method
+	Method string
+	// IsQuery indicates the method is sent with GET and is a query, the other methods are mutations
+	IsQuery bool
+	// IsEmptyRequest indicates the request is google.protobuf.Empty, and can be left out
+	IsEmptyRequest bool
+	// RequestType is the type of the request referenced from the query file
+	RequestType string
+	// ResponseType is the type of the response referenced from the query file
+	ResponseType string
+}
+
+// generateReactQuery generates a query file next to each generated file with unary methods, so that only the React apps
+// depend on React Query
+func (t *TypeScriptGRPCGatewayGenerator) generateReactQuery(files []*data.File) ([]*plugin.CodeGeneratorResponse_File, error) {
+	tmpl := GetReactQueryTemplate(t.Registry)
+	generated := make([]*plugin.CodeGeneratorResponse_File, 0, len(files))
+	for _, f := range files {
+		if !f.Services.HasUnaryCallMethod() {
+			continue
+		}
+
+		imports := newTypeImports(t.Registry, f, reactQueryClientIdentifier)
+		imports.dependencies[reactQueryDependency.ModuleIdentifier] = reactQueryDependency
+		if fm := imports.findDependency("fm"); fm != nil {
+			imports.dependencies[fm.ModuleIdentifier] = fm
+		}
+
+		query := &reactQueryFile{Methods: renderReactQueryMethods(t.Registry, imports, f.Services)}
+		query.Dependencies = imports.sortedDependencies()
+
+		w := bytes.NewBufferString("")
+		tsFileName := data.TrimTSExtension(f.TSFileName)
+		fileName := tsFileName + ".query" + strings.TrimPrefix(f.TSFileName, tsFileName)
+		if err := tmpl.Execute(w, query); err != nil {
+			return nil, errors.Wrapf(err, "error generating query file %s", fileName)
+		}
+
+		content := sourceHashHeader(f) + strings.TrimSpace(w.String())
+		generated = append(generated, &plugin.CodeGeneratorResponse_File{
+			Name:    &fileName,
+			Content: &content,
+		})
+	}
+
+	return generated, nil
+}
+
+// renderReactQueryMethods renders the hooks of the unary methods of the services, the streaming methods are left out
+func renderReactQueryMethods(r *registry.Registry, imports *typeImports, services data.Services) []*reactQueryMethod {
+	methodCounts := make(map[string]int)
+	for _, s := range services {
+		for _, m := range s.Methods {
+			methodCounts[m.Name]++
+		}
+	}
+
+	typeRef := func(typeInfo *registry.TypeInformation, isExternal bool) string {
+		return imports.typeRef(typeInfo)
+	}
+
+	methods := make([]*reactQueryMethod, 0)
+	for _, s := range services {
+		for _, m := range s.Methods {
+			if m.ServerStreaming || m.ClientStreaming {
+				continue
+			}
+
+			method := &reactQueryMethod{
+				Name:           m.Name,
+				Service:        strings.TrimPrefix(s.FQType, "."),
+				Client:         reactQueryClientIdentifier + "." + s.Name,
+				Method:         m.Name,
+				IsQuery:        m.HTTPMethod == "GET",
+				IsEmptyRequest: m.Input.IsEmpty,
+				RequestType:    "{}",
+				ResponseType:   "void",
+			}
+			if methodCounts[m.Name] > 1 {
+				method.Name = s.Name + m.Name
+			}
+			if !m.Input.IsEmpty {
+				method.RequestType = renderTSType(r, m.Input, typeRef)
+			}
+			if !m.Output.IsEmpty {
+				method.ResponseType = renderTSType(r, m.Output, typeRef)
+			}
+
+			methods = append(methods, method)
+		}
+	}
+
+	return methods
+}
+
+// GetReactQueryTemplate returns the go template for the query files
+func GetReactQueryTemplate(r *registry.Registry) *template.Template {
+	t := template.New("reactQuery")
+	t = t.Funcs(template.FuncMap{
+		"moduleSystem": func() string {
+			return r.ModuleSystem
+		},
+		"untitle": untitle,
+	})
+	return template.Must(t.Parse(reactQueryTmpl))
+}
@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus" // nolint: depguard
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// rollupFileName is the name of the declaration file aggregating the types of all the generated files
+const rollupFileName = "types.d.ts"
+
+// rollupNamespace is the enums and the messages of a proto package declared inside the rollup
+type rollupNamespace struct {
+	// Name is the package in PascalCase, or the module name of the file for files without a package
+	Name string
+	// Enums are the enums of every generated file of the package
+	Enums []*data.Enum
+	// Messages are the messages of every generated file of the package
+	Messages []*data.Message
+}
+
+// generateRollup generates a single declaration file with a namespace for each proto package, declaring the enums and the messages
+// of the generated files inside it. references between the types are qualified by the namespaces, so that they resolve across packages
+func (t *TypeScriptGRPCGatewayGenerator) generateRollup(files []*data.File) (*plugin.CodeGeneratorResponse_File, error) {
+	namespacesByName := make(map[string]*rollupNamespace)
+	for _, f := range files {
+		for _, e := range f.Enums {
+			ns := getRollupNamespace(namespacesByName, t.Registry.Types[e.FQType])
+			ns.Enums = append(ns.Enums, e)
+		}
+		for _, m := range f.Messages {
+			ns := getRollupNamespace(namespacesByName, t.Registry.Types[m.FQType])
+			ns.Messages = append(ns.Messages, m)
+		}
+	}
+
+	namespaces := make([]*rollupNamespace, 0, len(namespacesByName))
+	for _, ns := range namespacesByName {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+
+	w := bytes.NewBufferString("")
+	fileName := rollupFileName
+	if err := GetRollupTemplate(t.Registry).Execute(w, namespaces); err != nil {
+		return nil, errors.Wrapf(err, "error generating rollup declaration file %s", fileName)
+	}
+
+	content := strings.TrimSpace(w.String())
+	return &plugin.CodeGeneratorResponse_File{
+		Name:    &fileName,
+		Content: &content,
+	}, nil
+}
+
+// getRollupNamespace returns the namespace of the package of the type, creating it when it's the first type of the package
+func getRollupNamespace(namespaces map[string]*rollupNamespace, typeInfo *registry.TypeInformation) *rollupNamespace {
+	name := rollupNamespaceName(typeInfo)
+	ns, ok := namespaces[name]
+	if !ok {
+		ns = &rollupNamespace{Name: name}
+		namespaces[name] = ns
+	}
+
+	return ns
+}
+
+// rollupNamespaceName returns the name of the namespace the type is declared in inside the rollup
+func rollupNamespaceName(typeInfo *registry.TypeInformation) string {
+	if prefix := data.GetPackagePrefix(typeInfo.Package); prefix != "" {
+		return prefix
+	}
+
+	return data.GetModuleName(typeInfo.Package, typeInfo.File)
+}
+
+// rollupType renders the typescript type of the field inside the rollup, the enums and the messages are qualified by their namespaces.
+// types of files which aren't generated are not declared in the rollup, so they are rendered as unknown
+func rollupType(r *registry.Registry) func(fieldType data.Type) string {
+	return func(fieldType data.Type) string {
+		return renderTSType(r, fieldType, func(typeInfo *registry.TypeInformation, isExternal bool) string {
+			if !r.IsFileToGenerate(typeInfo.File) {
+				log.Warnf("%s of %s is not declared in the rollup declaration file, rendering it as unknown", typeInfo.PackageIdentifier, typeInfo.File)
+				return "unknown"
+			}
+
+			return rollupNamespaceName(typeInfo) + "." + typeInfo.PackageIdentifier
+		})
+	}
+}
+
+// enumUnion renders the enum as the union of the names of its values, which is how grpc-gateway encodes enums in JSON
+func enumUnion(e *data.Enum) string {
+	values := e.UniqueValues()
+	if len(values) == 0 {
+		return "never"
+	}
+
+	names := make([]string, 0, len(values))
+	for _, v := range values {
+		names = append(names, strconv.Quote(v.Name))
+	}
+
+	return strings.Join(names, " | ")
+}
+
+// GetRollupTemplate returns the go template for the rollup declaration file
+func GetRollupTemplate(r *registry.Registry) *template.Template {
+	t := template.New("rollup")
+	t = t.Funcs(sprig.TxtFuncMap())
+	t = t.Funcs(template.FuncMap{
+		"jsdoc":        jsdoc,
+		"propertyName": propertyName(r),
+		"rollupType":   rollupType(r),
+		"optionalType": renderOptionalType(r),
+		"enumUnion":    enumUnion,
+		"readonly": func() bool {
+			return r.Readonly
+		},
+	})
+	return template.Must(t.Parse(rollupTmpl))
+}
@@ -0,0 +1,24 @@
+package generator
+
+import (
+	"io"
+
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	log "github.com/sirupsen/logrus" // nolint: depguard
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// writeSummary writes the summary of the generation as a single line of fields. it has its own logger so that the summary
+// is written whatever the log level is, as ts_verbose asks for it
+func writeSummary(w io.Writer, r *registry.Registry, generated []*plugin.CodeGeneratorResponse_File) {
+	logger := log.New()
+	logger.SetOutput(w)
+	logger.SetFormatter(&log.TextFormatter{DisableTimestamp: true, DisableColors: true})
+	logger.WithFields(log.Fields{
+		"files":               len(generated),
+		"types":               len(r.Types),
+		"dependencies":        r.Stats.Dependencies,
+		"import_root_lookups": r.Stats.ImportRootLookups,
+	}).Info("generation summary")
+}
@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
+	"unicode/utf8"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Masterminds/sprig"
-	"github.com/iancoleman/strcase"
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 
 	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
 	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
@@ -19,47 +22,450 @@ import (
 
 const tmpl = `
 {{define "dependencies"}}
-{{range .}}import * as {{.ModuleIdentifier}} from "{{.SourceFile}}"
+{{range .}}{{if eq moduleSystem "commonjs"}}import {{.ModuleIdentifier}} = require("{{.SourceFile}}"){{else}}import * as {{.ModuleIdentifier}} from "{{.SourceFile}}"{{end}}
+{{end}}{{end}}
+
+{{define "sourceLocation"}}{{with .SourceLocation}}// from {{.}}
 {{end}}{{end}}
 
 {{define "enums"}}
-{{range .}}export enum {{.Name}} {
+{{range .}}{{include "sourceLocation" .}}{{jsdoc .Comment ""}}
+{{- if eq enumStyle "string_union" -}}
+export type {{.Name}} =
+{{- range .Values}}
+{{jsdoc .Comment "  "}}  | "{{.Name}}"
+{{- end}}
+{{- else -}}
+export {{if eq enumStyle "const_enum"}}const {{end}}enum {{.Name}} {
 {{- range .Values}}
-  {{.}} = "{{.}}",
+{{jsdoc .Comment "  "}}  {{.Name}} = "{{.Name}}",
 {{- end}}
 }
-
+{{- end}}
+{{if not emitsDeclarations}}{{include "enumHelpers" .}}{{end}}
 {{end}}{{end}}
 
+{{define "enumValue"}}{{if eq enumStyle "string_union"}}"{{.Value.Name}}"{{else}}{{.Enum.Name}}.{{.Value.Name}}{{end}}{{end}}
+
+{{define "enumHelpers"}}{{$enum := .}}
+// {{.Name}}Values are all the values of {{.Name}} in declaration order, aliases are left out
+export const {{.Name}}Values = [
+{{- range $index, $value := .UniqueValues}}{{if $index}}, {{end}}{{include "enumValue" (dict "Enum" $enum "Value" $value)}}{{end -}}
+] as const{{if satisfies}} satisfies readonly {{.Name}}[]{{end}}
+
+// {{.Name}}ByNumber looks up the values of {{.Name}} by their numbers, aliases are left out
+export const {{.Name}}ByNumber{{if not satisfies}}: Record<number, {{.Name}}>{{end}} = {
+{{- range .UniqueValues}}
+  {{if lt .Number 0}}[{{.Number}}]{{else}}{{.Number}}{{end}}: {{include "enumValue" (dict "Enum" $enum "Value" .)}},
+{{- end}}
+}{{if satisfies}} as const satisfies Record<number, {{.Name}}>{{end}}
+{{if eq enumUnknown "throw"}}
+// {{untitle .Name}}FromJSON converts the name or the number of a value into {{.Name}}, unrecognized values throw an error
+{{- else if eq enumUnknown "preserve"}}
+// {{untitle .Name}}FromJSON converts the name or the number of a value into {{.Name}}, unrecognized values are returned as they are
+{{- else}}
+// {{untitle .Name}}FromJSON converts the name or the number of a value into {{.Name}}, unrecognized values fall back to the default value
+{{- end}}
+export function {{untitle .Name}}FromJSON(object: string | number): {{.Name}}{{if eq enumUnknown "preserve"}} | string | number{{end}} {
+  switch (object) {
+{{- range .Values}}
+{{- if not ($enum.IsAlias .)}}
+    case {{.Number}}:
+{{- end}}
+    case "{{.Name}}":
+      return {{include "enumValue" (dict "Enum" $enum "Value" .)}}
+{{- end}}
+    default:
+{{- if eq enumUnknown "throw"}}
+      throw new Error("unrecognized value " + object + " of {{.Name}}")
+{{- else if eq enumUnknown "preserve"}}
+      return object
+{{- else}}
+      return {{include "enumValue" (dict "Enum" $enum "Value" (index .Values 0))}}
+{{- end}}
+  }
+}
+
+// {{untitle .Name}}ToJSON converts {{.Name}} into the name of the value, which is how grpc-gateway encodes enums in JSON
+{{- if eq enumUnknown "preserve"}}
+// preserved unrecognized values are converted as they are
+export function {{untitle .Name}}ToJSON(object: {{.Name}} | string | number): string {
+{{- else}}
+export function {{untitle .Name}}ToJSON(object: {{.Name}}): string {
+{{- end}}
+  switch (object) {
+{{- range .Values}}
+    case {{include "enumValue" (dict "Enum" $enum "Value" .)}}:
+      return "{{.Name}}"
+{{- end}}
+    default:
+      return {{if eq enumUnknown "preserve"}}String(object){{else}}"UNRECOGNIZED"{{end}}
+  }
+}
+
+// {{untitle .Name}}ToNumber converts {{.Name}} into the number of the value, unrecognized values are converted into -1
+{{- if eq enumUnknown "preserve"}}
+// unless they are preserved numbers
+export function {{untitle .Name}}ToNumber(object: {{.Name}} | string | number): number {
+{{- else}}
+export function {{untitle .Name}}ToNumber(object: {{.Name}}): number {
+{{- end}}
+  switch (object) {
+{{- range .Values}}
+    case {{include "enumValue" (dict "Enum" $enum "Value" .)}}:
+      return {{.Number}}
+{{- end}}
+    default:
+      return {{if eq enumUnknown "preserve"}}typeof object === "number" ? object : -1{{else}}-1{{end}}
+  }
+}
+{{end}}
+
 {{define "messages"}}{{range .}}
-{{- if .HasOneOfFields}}
+{{- if eq messageKind "class" -}}
+{{include "messageClass" .}}
+{{- else if .HasOneOfFields}}
 type Base{{.Name}} = {
 {{- range .NonOneOfFields}}
-  {{fieldName .Name}}?: {{tsType .}}
+{{jsdoc .Comment "  " (ternary "@deprecated" "" .IsDeprecated)}}  {{if readonly}}readonly {{end}}{{propertyName .}}?: {{tsType .}}{{optionalType .}}
 {{- end}}
 }
 
-export type {{.Name}} = Base{{.Name}}
-{{range $groupId, $fields := .OneOfFieldsGroups}}  & OneOf<{ {{range $index, $field := $fields}}{{fieldName $field.Name}}: {{tsType $field}}{{if (lt (add $index 1) (len $fields))}}; {{end}}{{end}} }>
+{{include "sourceLocation" .}}{{jsdoc .Comment "" (ternary "@deprecated" "" .IsDeprecated)}}export type {{.Name}} = Base{{.Name}}
+{{range $groupId, $fields := .OneOfFieldsGroups}}  & OneOf<{ {{range $index, $field := $fields}}{{if readonly}}readonly {{end}}{{propertyName $field}}: {{tsType $field}}{{if (lt (add $index 1) (len $fields))}}; {{end}}{{end}} }>
 {{end}}
 {{- else -}}
-export type {{.Name}} = {
+{{include "sourceLocation" .}}{{jsdoc .Comment "" (ternary "@deprecated" "" .IsDeprecated)}}export type {{.Name}} = {
+{{- range .Fields}}
+{{jsdoc .Comment "  " (ternary "@deprecated" "" .IsDeprecated)}}  {{if readonly}}readonly {{end}}{{propertyName .}}?: {{tsType .}}{{optionalType .}}
+{{- end}}
+}
+{{end}}
+{{- include "int64MapHelpers" .}}
+{{- if emitFactories}}{{include "factory" .}}{{end}}
+{{- if emitGuards}}{{include "guard" .}}{{end}}
+{{- if emitEquals}}{{include "equals" .}}{{end}}
+{{- if emitValidators}}{{include "validator" .}}{{end}}
+{{end}}{{end}}
+
+{{define "messageClass"}}{{include "sourceLocation" .}}{{jsdoc .Comment "" (ternary "@deprecated" "" .IsDeprecated)}}export class {{.Name}} {
+{{- range .Fields}}
+{{jsdoc .Comment "  " (ternary "@deprecated" "" .IsDeprecated)}}  {{if readonly}}readonly {{end}}{{propertyName .}}?: {{tsType .}}{{optionalType .}}
+{{- end}}
+
+  // fromJSON creates {{.Name}} out of its JSON representation, the fields are accepted by both their json names and their proto names
+  static fromJSON(object: any): {{.Name}} {
+    const m: {[key: string]: unknown} = {}
+{{- if .Fields}}
+    let v: any
+{{- end}}
+{{- range .Fields}}
+    v = {{jsonLookup . "object"}}
+    if (v !== undefined && v !== null) {
+      m["{{fieldName .}}"] = {{fromJSONValue . "v"}}
+    }
+{{- end}}
+    return Object.assign(new {{.Name}}(), m)
+  }
+
+  // toJSON returns the JSON representation of {{.Name}}, which is called by JSON.stringify
+  toJSON(): {[key: string]: unknown} {
+    const json: {[key: string]: unknown} = {}
+{{- range .Fields}}
+    if (this["{{fieldName .}}"] !== undefined && this["{{fieldName .}}"] !== null) {
+      json["{{jsonName .}}"] = {{toJSONValue . (printf "this[%q]" (fieldName .))}}
+    }
+{{- end}}
+    return json
+  }
+}
+{{end}}
+
+{{define "fieldMaskHelpers"}}// fieldMaskFromJSON splits google.protobuf.FieldMask encoded as the comma-joined camelCase paths in JSON into the paths of the fields,
+// e.g. "displayName,address.zipCode" into ["display_name", "address.zip_code"]
+function fieldMaskFromJSON(mask: string): string[] {
+  return mask ? mask.split(",").map(path => path.replace(/[A-Z]/g, c => "_" + c.toLowerCase())) : []
+}
+
+// fieldMaskToJSON joins the paths of the fields into google.protobuf.FieldMask encoded as the comma-joined camelCase paths in JSON
+function fieldMaskToJSON(paths: readonly string[]): string {
+  return paths.map(path => path.replace(/_([a-z])/g, (_, c) => c.toUpperCase())).join(",")
+}
+
+{{end}}
+
+{{define "int64MapHelpers"}}{{$message := .}}
+{{- range int64KeyedMaps .}}
+// {{int64MapEntriesName $message .}} converts the entries of {{fieldName .}}, whose 64-bit integer keys are strings in JSON, into [{{int64Type}}, value] pairs
+export function {{int64MapEntriesName $message .}}(map?: {readonly [key: string]: {{mapValueType .}}}): [{{int64Type}}, {{mapValueType .}}][] {
+  return Object.entries(map || {}).map(([key, value]): [{{int64Type}}, {{mapValueType .}}] => [{{if eq int64Type "bigint"}}BigInt(key){{else}}Number(key){{end}}, value])
+}
+{{end}}
+{{- end}}
+
+{{define "factory"}}
+// create{{.Name}} returns {{.Name}} with every field set to its default value, messages, oneof, optional and extension fields are left undefined
+export function create{{.Name}}(): {{.Name}} {
+  return {{if eq messageKind "class"}}Object.assign(new {{.Name}}(), {{end}}{
+{{- range .NonOneOfFields}}{{$value := defaultValue .}}{{if $value}}
+    {{propertyName .}}: {{$value}},
+{{- end}}{{end}}
+  }{{if eq messageKind "class"}}){{end}}
+}
+{{end}}
+
+{{define "guard"}}
+// is{{.Name}} checks whether the value is {{.Name}}, the type of every field present in it is checked recursively
+export function is{{.Name}}(x: unknown): x is {{.Name}} {
+  if (typeof x !== "object" || x === null || Array.isArray(x)) {
+    return false
+  }
+
+  const m = x as {[key: string]: unknown}
+{{- if .Fields}}
+  let v: unknown
+{{- end}}
 {{- range .Fields}}
-  {{fieldName .Name}}?: {{tsType .}}
+  v = m["{{fieldName .}}"]
+{{- if .IsRequired}}
+  if (v === undefined || v === null || !({{guardCheck . "v"}})) {
+{{- else}}
+  if (v !== undefined && v !== null && !({{guardCheck . "v"}})) {
+{{- end}}
+    return false
+  }
+{{- end}}
+{{- range $groupId, $fields := .OneOfFieldsGroups}}
+  // only one of the fields can be set
+  if ([{{range $index, $field := $fields}}{{if $index}}, {{end}}m["{{fieldName $field}}"]{{end}}].filter(f => f !== undefined && f !== null).length > 1) {
+    return false
+  }
 {{- end}}
+
+  return true
 }
 {{end}}
+
+{{define "equals"}}
+// equals{{.Name}} checks whether the messages are equal, the fields are compared recursively and maps regardless of the order of their keys
+export function equals{{.Name}}(a: {{.Name}}, b: {{.Name}}): boolean {
+{{- if .Fields}}
+  return {{range $index, $field := .Fields}}{{if $index}} &&
+    {{end}}{{equalsCheck $field}}{{end}}
+{{- else}}
+  return true
+{{- end}}
+}
+{{end}}
+
+{{define "validator"}}{{$fields := fieldValidations .}}{{if $fields}}
+// validate{{.Name}} checks {{.Name}} against the protoc-gen-validate and buf.validate constraints of its fields before it's sent,
+// unset fields are checked as their zero values. it returns the violations, none when it's valid
+export function validate{{.Name}}(m: {{.Name}}): string[] {
+  const violations: string[] = []
+  let v: any
+{{- range $fields}}
+  v = m["{{.Name}}"]
+{{- range .Checks}}
+  if ({{.Condition}}) {
+    violations.push({{.Message}})
+  }
+{{- end}}
+{{- end}}
+  return violations
+}
 {{end}}{{end}}
 
-{{define "services"}}{{range .}}export class {{.Name}} {
+{{define "equalsHelpers"}}{{$helpers := equalsHelpers .}}
+{{- if $helpers.Value}}
+// equalsValue checks whether the values are equal, absent values are only equal to absent values
+function equalsValue<T>(a: T | undefined | null, b: T | undefined | null, equals: (a: T, b: T) => boolean): boolean {
+  if (a === undefined || a === null || b === undefined || b === null) {
+    return (a === undefined || a === null) && (b === undefined || b === null)
+  }
+
+  return equals(a, b)
+}
+{{end}}
+{{- if $helpers.Array}}
+// equalsArray checks whether the arrays have equal elements in the same order, absent arrays are only equal to absent arrays
+function equalsArray<T>(a: readonly T[] | undefined | null, b: readonly T[] | undefined | null, equals: (a: T, b: T) => boolean): boolean {
+  if (a === undefined || a === null || b === undefined || b === null) {
+    return (a === undefined || a === null) && (b === undefined || b === null)
+  }
+
+  return a.length === b.length && a.every((e, i) => equals(e, b[i]))
+}
+{{end}}
+{{- if $helpers.Map}}
+// equalsMap checks whether the maps have the same keys with equal values regardless of their order, absent maps are only equal to absent maps
+function equalsMap<T>(a: {readonly [key: string]: T | undefined} | undefined | null, b: {readonly [key: string]: T | undefined} | undefined | null, equals: (a: T, b: T) => boolean): boolean {
+  if (a === undefined || a === null || b === undefined || b === null) {
+    return (a === undefined || a === null) && (b === undefined || b === null)
+  }
+
+  const keys = Object.keys(a)
+  return keys.length === Object.keys(b).length && keys.every(key => {
+    const x = a[key]
+    const y = b[key]
+    return x === undefined || y === undefined ? x === y && Object.prototype.hasOwnProperty.call(b, key) : equals(x, y)
+  })
+}
+{{end}}
+{{- if $helpers.Date}}
+// equalsDate checks whether the dates are the same point in time
+function equalsDate(a: Date, b: Date): boolean {
+  return a.getTime() === b.getTime()
+}
+{{end}}
+{{- if $helpers.TypedArray}}
+// equalsTypedArray checks whether the typed arrays have the same elements
+function equalsTypedArray(a: Float32Array | Float64Array, b: Float32Array | Float64Array): boolean {
+  return a.length === b.length && Array.from(a).every((e, i) => e === b[i])
+}
+{{end}}
+{{- if $helpers.Bytes}}
+// equalsBytes checks whether the bytes are equal byte by byte
+function equalsBytes(a: Uint8Array, b: Uint8Array): boolean {
+  return a.length === b.length && a.every((e, i) => e === b[i])
+}
+{{end}}
+{{- if $helpers.JSON}}
+// equalsJSON checks whether the JSON values are equal, the keys of objects are compared regardless of their order
+function equalsJSON(a: unknown, b: unknown): boolean {
+  if (a === b) {
+    return true
+  }
+
+  if (typeof a !== "object" || typeof b !== "object" || a === null || b === null || Array.isArray(a) !== Array.isArray(b)) {
+    return false
+  }
+
+  if (Array.isArray(a)) {
+    return equalsArray(a, b as unknown[], equalsJSON)
+  }
+
+  return equalsMap(a as {[key: string]: unknown}, b as {[key: string]: unknown}, equalsJSON)
+}
+{{end}}
+{{- end}}
+
+{{define "openDuplexStream"}}fm.openDuplexStream<{{requestType .Input}}, {{responseType .Output}}>("{{.URL}}", "{{.HTTPMethod}}", initReq
+{{- if or (needsBytesConversion .Input) (needsResponseDecoding .Output)}}, {{if needsBytesConversion .Input}}req => fm.convertBytes(req, "{{.Input.Type}}", bytesFields, fm.base64Encode){{else}}undefined{{end}}
+{{- if needsResponseDecoding .Output}}, resp => {{decodeResponse .Output "resp"}}{{end}}{{end}})
+{{- end}}
+
+{{define "encodeBytes"}}
+{{- if needsBytesConversion .Input}}
+    req = fm.convertBytes(req, "{{.Input.Type}}", bytesFields, fm.base64Encode)
+{{- end}}
+{{- end}}
+
+{{define "services"}}{{renderBytesFields .}}{{range $service := .}}{{include "sourceLocation" .}}{{jsdoc .Comment ""}}export class {{.Name}} {
+  private readonly initReq?: fm.InitReq
+
+  // initReq is the default InitReq of the client, e.g. headers for authentication, it's merged with the InitReq of each call
+  constructor(initReq?: fm.InitReq) {
+    this.initReq = initReq
+  }
 {{- range .Methods}}  
-{{- if .ServerStreaming }}
-  static {{.Name}}(req: {{tsType .Input}}, entityNotifier?: fm.NotifyStreamEntityArrival<{{tsType .Output}}>, initReq?: fm.InitReq): Promise<void> {
-    return fm.fetchStreamingRequest<{{tsType .Input}}, {{tsType .Output}}>(` + "`{{renderURL .}}`" + `, entityNotifier, {...initReq, {{buildInitReq .}}})
+{{- if .ClientStreaming}}
+{{jsdoc .Comment "  "}}  static {{.Name}}(initReq?: fm.InitReq): fm.DuplexStream<{{requestType .Input}}, {{responseType .Output}}> {
+    return {{include "openDuplexStream" .}}
+  }
+  {{.Name}}(initReq?: fm.InitReq): fm.DuplexStream<{{requestType .Input}}, {{responseType .Output}}> {
+    return {{$service.Name}}.{{.Name}}(fm.mergeInitReq(this.initReq, initReq))
+  }
+{{- else if .ServerStreaming }}
+{{jsdoc .Comment "  "}}  static {{.Name}}({{requestParam .Input}}, entityNotifier?: fm.NotifyStreamEntityArrival<{{responseType .Output}}>, initReq?: fm.InitReq): Promise<void> {
+{{- include "encodeBytes" .}}
+{{- if needsResponseDecoding .Output}}
+    const notifier = entityNotifier && ((resp: {{responseType .Output}}) => entityNotifier({{decodeResponse .Output "resp"}}))
+    return fm.fetchStreamingRequest<{{requestType .Input}}, {{responseType .Output}}>(` + "`{{renderURL .}}`" + `, notifier, {...initReq, {{buildInitReq .}}})
+{{- else}}
+    return fm.fetchStreamingRequest<{{requestType .Input}}, {{responseType .Output}}>(` + "`{{renderURL .}}`" + `, entityNotifier, {...initReq, {{buildInitReq .}}})
+{{- end}}
+  }
+{{jsdoc .Comment "  "}}  static {{.Name}}Iterable({{requestParam .Input}}, initReq?: fm.InitReq): AsyncIterable<{{responseType .Output}}> {
+{{- include "encodeBytes" .}}
+{{- if needsResponseDecoding .Output}}
+    return fm.mapIterable(fm.fetchStreamingIterable<{{requestType .Input}}, {{responseType .Output}}>(` + "`{{renderURL .}}`" + `, {...initReq, {{buildInitReq .}}}), resp => {{decodeResponse .Output "resp"}})
+{{- else}}
+    return fm.fetchStreamingIterable<{{requestType .Input}}, {{responseType .Output}}>(` + "`{{renderURL .}}`" + `, {...initReq, {{buildInitReq .}}})
+{{- end}}
+  }
+  {{.Name}}({{requestParam .Input}}, entityNotifier?: fm.NotifyStreamEntityArrival<{{responseType .Output}}>, initReq?: fm.InitReq): Promise<void> {
+    return {{$service.Name}}.{{.Name}}(req, entityNotifier, fm.mergeInitReq(this.initReq, initReq))
+  }
+  {{.Name}}Iterable({{requestParam .Input}}, initReq?: fm.InitReq): AsyncIterable<{{responseType .Output}}> {
+    return {{$service.Name}}.{{.Name}}Iterable(req, fm.mergeInitReq(this.initReq, initReq))
   }
 {{- else }}
-  static {{.Name}}(req: {{tsType .Input}}, initReq?: fm.InitReq): Promise<{{tsType .Output}}> {
-    return fm.fetchReq<{{tsType .Input}}, {{tsType .Output}}>(` + "`{{renderURL .}}`" + `, {...initReq, {{buildInitReq .}}})
+{{jsdoc .Comment "  "}}  static {{.Name}}({{requestParam .Input}}, initReq?: fm.InitReq): Promise<{{responseType .Output}}> {
+{{- include "encodeBytes" .}}
+    return fm.fetchReq<{{requestType .Input}}, {{responseType .Output}}>(` + "`{{renderURL .}}`" + `, {...initReq, {{buildInitReq .}}})
+{{- if needsResponseDecoding .Output}}
+      .then(resp => {{decodeResponse .Output "resp"}})
+{{- end}}
+  }
+  {{.Name}}({{requestParam .Input}}, initReq?: fm.InitReq): Promise<{{responseType .Output}}> {
+    return {{$service.Name}}.{{.Name}}(req, fm.mergeInitReq(this.initReq, initReq))
+  }
+{{- end}}
+{{- end}}
+}
+{{end}}{{end}}
+
+{{define "apiObject"}}
+// createApi creates a client of each service keyed by the service name, sharing the default InitReq, e.g. headers for authentication
+export function createApi(initReq?: fm.InitReq) {
+  return {
+{{- range .}}
+    {{.Name}}: new {{.Name}}(initReq),
+{{- end}}
+  }
+}
+
+// api holds a client of each service without a default InitReq, as a single import for all of them
+export const api = createApi()
+{{end}}
+
+{{define "methodMetadata"}}{{range .}}
+// {{.Name}}Methods describes the http binding of each method of {{.Name}}, e.g. for generic logging or metrics
+export const {{.Name}}Methods = {
+{{- range .Methods}}
+  {{.Name}}: {name: "{{.Name}}", httpMethod: "{{.HTTPMethod}}", path: "{{.URL}}", requestType: "{{trimPrefix "." .Input.Type}}", responseType: "{{trimPrefix "." .Output.Type}}", serverStreaming: {{.ServerStreaming}}},
+{{- end}}
+} as const
+{{end}}{{end}}
+
+{{define "angularServices"}}{{renderBytesFields .}}{{range .}}{{include "sourceLocation" .}}{{jsdoc .Comment ""}}@Injectable({providedIn: "root"})
+export class {{.Name}} {
+  constructor(private readonly http: HttpClient) {}
+{{- range .Methods}}
+{{- if .ClientStreaming}}
+{{jsdoc .Comment "  "}}  {{.Name}}(initReq?: fm.InitReq): fm.DuplexStream<{{requestType .Input}}, {{responseType .Output}}> {
+    // HttpClient doesn't support WebSockets, bidirectional streaming calls are sent with a WebSocket of the fetch module
+    return {{include "openDuplexStream" .}}
+  }
+{{- else if .ServerStreaming}}
+{{jsdoc .Comment "  "}}  {{.Name}}({{requestParam .Input}}): Observable<{{responseType .Output}}> {
+{{- include "encodeBytes" .}}
+    // HttpClient doesn't expose the response stream, server side streaming calls are sent with fetch
+    return new Observable<{{responseType .Output}}>(subscriber => {
+      const controller = new AbortController()
+      fm.fetchStreamingRequest<{{requestType .Input}}, {{responseType .Output}}>(` + "`{{renderURL .}}`" + `, resp => subscriber.next({{if needsResponseDecoding .Output}}{{decodeResponse .Output "resp"}}{{else}}resp{{end}}), {signal: controller.signal, {{buildInitReq .}}})
+        .then(() => subscriber.complete(), err => subscriber.error(err))
+      return () => controller.abort()
+    })
+  }
+{{- else}}
+{{jsdoc .Comment "  "}}  {{.Name}}({{requestParam .Input}}): Observable<{{responseType .Output}}> {
+{{- include "encodeBytes" .}}
+    return this.http.request<{{responseType .Output}}>("{{.HTTPMethod}}", ` + "`{{renderURL .}}`" + `{{with requestBody .}}, {body: {{.}}}{{end}})
+{{- if needsResponseDecoding .Output}}
+      .pipe(map(resp => {{decodeResponse .Output "resp"}}))
+{{- end}}
   }
 {{- end}}
 {{- end}}
@@ -70,19 +476,26 @@ export type {{.Name}} = {
 * This file is a generated Typescript file for GRPC Gateway, DO NOT MODIFY
 */
 {{if .Dependencies}}{{- include "dependencies" .StableDependencies -}}{{end}}
-{{- if .NeedsOneOfSupport}}
-type Absent<T, K extends keyof T> = { [k in Exclude<keyof T, K>]?: undefined };
+{{- if and .Services (eq clientStyle "angular")}}import { Injectable } from "@angular/core"
+import { HttpClient } from "@angular/common/http"
+import { Observable{{if needsUnaryResponseDecoding .Services}}, map{{end}} } from "rxjs"
+{{end}}
+{{- if and .NeedsOneOfSupport (ne messageKind "class")}}
+type Absent<T, K extends keyof T> = { {{if readonly}}readonly {{end}}[k in Exclude<keyof T, K>]?: undefined };
 type OneOf<T> =
-  | { [k in keyof T]?: undefined }
+  | { {{if readonly}}readonly {{end}}[k in keyof T]?: undefined }
   | (
     keyof T extends infer K ?
-      (K extends string & keyof T ? { [k in K]: T[K] } & Absent<T, K>
+      (K extends string & keyof T ? { {{if readonly}}readonly {{end}}[k in K]: T[K] } & Absent<T, K>
         : never)
     : never);
 {{end}}
+{{- if and emitEquals .Messages}}{{include "equalsHelpers" .Messages}}{{end}}
 {{- if .Enums}}{{include "enums" .Enums}}{{end}}
 {{- if .Messages}}{{include "messages" .Messages}}{{end}}
-{{- if .Services}}{{include "services" .Services}}{{end}}
+{{- if hasFieldMaskPaths .Messages}}{{include "fieldMaskHelpers" .}}{{end}}
+{{- enumNamespaces .}}
+{{- if .Services}}{{if eq clientStyle "angular"}}{{include "angularServices" .Services}}{{else}}{{include "services" .Services}}{{end}}{{include "methodMetadata" .Services}}{{if apiObject}}{{include "apiObject" .Services}}{{end}}{{end}}
 `
 
 const fetchTmpl = `
@@ -92,112 +505,571 @@ const fetchTmpl = `
 
 export interface InitReq extends RequestInit {
   pathPrefix?: string
+  // basePath is the path the gateway is mounted under, e.g. /api, it's joined with the path of each method before the pathPrefix
+  // is prepended, e.g. /api/v1/items for /v1/items
+  basePath?: string
+  // fetch is a custom fetch implementation used to send the request, defaults to the global fetch
+  fetch?: typeof fetch
+  // decompress returns the transform decompressing a streaming response sent with the given Content-Encoding,
+  // e.g. new DecompressionStream("gzip") when the fetch implementation doesn't decode the response by itself.
+  // returning undefined leaves the encoding to the fetch implementation
+  decompress?: (encoding: string) => TransformStream<Uint8Array, Uint8Array> | undefined
+  // contentType is the Content-Type of request bodies, defaults to application/json.
+  // it needs to match the marshaler of the gateway, e.g. application/grpc-web+json
+  contentType?: string
+  // accept is the Accept header of requests, defaults to application/json.
+  // the content type of streaming responses is checked against it before they are parsed
+  accept?: string
+  // retry retries unary calls failing with a network error or an http status of 5xx, only when idempotent is set.
+  // streaming calls are never retried
+  retry?: RetryOptions
+  // idempotent marks the call safe to retry, the clients set it for GET bindings and methods with an idempotency_level
+  idempotent?: boolean
+  // interceptor is invoked with every outgoing request before it's sent, e.g. to add tracing headers or sign the request.
+  // the returned request is sent instead, it's invoked again for every retry
+  interceptor?: RequestInterceptor
+  // responseInterceptor is invoked with every response before it's parsed, the returned response is parsed instead
+  responseInterceptor?: ResponseInterceptor
+  // timeoutMs aborts unary calls which don't settle in time, including the retries, and rejects them with a TimeoutError
+  timeoutMs?: number
+  // firstByteTimeoutMs aborts streaming calls which don't receive the response headers in time with a TimeoutError,
+  // the stream itself can last longer, including the wait for its first entity
+  firstByteTimeoutMs?: number
+}
+
+export type RequestInterceptor = (req: Request) => Request | Promise<Request>
+
+export type ResponseInterceptor = (resp: Response) => Response | Promise<Response>
+
+export type RetryOptions = {
+  // maxRetries is the number of retries after the first attempt
+  maxRetries: number
+  // baseBackoffMs is the delay before the first retry in milliseconds, defaults to 100. it's doubled for each following retry
+  baseBackoffMs?: number
+}
+
+/**
+ * getURL joins the basePath and the path of the method with a single slash between them, whether or not the basePath
+ * starts or ends with slashes, then prepends the pathPrefix
+ **/
+function getURL(path: string, pathPrefix?: string, basePath?: string): string {
+  const base = basePath ? basePath.replace(/^\/+|\/+$/g, "") : ""
+  if (base) {
+    path = "/" + base + "/" + path.replace(/^\/+/, "")
+  }
+
+  return pathPrefix ? ` + "`${pathPrefix}${path}`" + ` : path
+}
+
+/**
+ * getRequestInit sets the Content-Type header of the request body and the Accept header of the request,
+ * unless they are set in the headers already
+ **/
+function getRequestInit(req: RequestInit, contentType: string, accept: string): RequestInit {
+  const headers = new Headers(req.headers)
+  if (req.body !== undefined && req.body !== null && !headers.has("Content-Type")) {
+    headers.set("Content-Type", contentType)
+  }
+  if (!headers.has("Accept")) {
+    headers.set("Accept", accept)
+  }
+
+  return {...req, headers}
 }
 
 export function fetchReq<I, O>(path: string, init?: InitReq): Promise<O> {
-  const {pathPrefix, ...req} = init || {}
+  const {pathPrefix, basePath, fetch: fetchFn = fetch, decompress, contentType = "application/json", accept = "application/json", retry, idempotent, interceptor, responseInterceptor, timeoutMs, firstByteTimeoutMs, ...rest} = init || {}
+
+  const url = getURL(path, pathPrefix, basePath)
+  const timeout = startTimeout(rest.signal, timeoutMs)
+  const req = {...rest, signal: timeout.signal}
+  const requestInit = getRequestInit(req, contentType, accept)
+
+  return fetchWithRetry(() => sendRequest(fetchFn, url, requestInit, interceptor, responseInterceptor), idempotent ? retry : undefined, req.signal).then(async r => {
+    // http status other than 2xx doesn't reject the promise of fetch, the error is in the body instead
+    if (!r.ok) {
+      throw await getRpcError(r)
+    }
+
+    // 204 No Content and other responses without a body, e.g. of methods returning google.protobuf.Empty,
+    // resolve with an empty message instead of failing to parse the body
+    const body = r.status === 204 ? "" : await r.text()
+    return body ? JSON.parse(body) : {}
+  }).catch(e => {
+    throw timeout.error(e)
+  }).finally(timeout.dispose) as Promise<O>
+}
+
+/**
+ * TimeoutError is thrown by the clients when a call is aborted by timeoutMs or firstByteTimeoutMs of the InitReq
+ **/
+export class TimeoutError extends Error {
+  // timeoutMs is the timeout which expired
+  readonly timeoutMs: number
+
+  constructor(timeoutMs: number) {
+    super("the call timed out after " + timeoutMs + "ms")
+    this.name = "TimeoutError"
+    this.timeoutMs = timeoutMs
+  }
+}
+
+type Timeout = {
+  // signal is aborted either by the signal of the call or by the timer
+  signal: AbortSignal | null | undefined
+  // stop stops the timer, the signal of the call is still followed
+  stop: () => void
+  // dispose stops the timer and stops following the signal of the call, once the call settles
+  dispose: () => void
+  // error returns the TimeoutError when the call failed because of the timer, the error itself otherwise
+  error: (e: unknown) => unknown
+}
+
+/**
+ * startTimeout starts a timer aborting the returned signal with a TimeoutError after timeoutMs,
+ * the signal of the call is returned as it is without a timeout
+ **/
+function startTimeout(signal: AbortSignal | null | undefined, timeoutMs?: number): Timeout {
+  if (timeoutMs === undefined) {
+    return {signal, stop: () => {}, dispose: () => {}, error: e => e}
+  }
+
+  const controller = new AbortController()
+  const onAbort = () => controller.abort(signal?.reason)
+  if (signal?.aborted) {
+    onAbort()
+  }
+  signal?.addEventListener("abort", onAbort)
+
+  const timeoutError = new TimeoutError(timeoutMs)
+  const timer = setTimeout(() => controller.abort(timeoutError), timeoutMs)
+  return {
+    signal: controller.signal,
+    stop: () => clearTimeout(timer),
+    dispose: () => {
+      clearTimeout(timer)
+      signal?.removeEventListener("abort", onAbort)
+    },
+    error: e => controller.signal.reason === timeoutError ? timeoutError : e,
+  }
+}
+
+/**
+ * sendRequest sends the request with the fetch implementation, passing the request and the response through the interceptors.
+ * the request is only built when there's a request interceptor, as relative urls can't be turned into a Request outside of browsers
+ **/
+async function sendRequest(fetchFn: typeof fetch, url: string, init: RequestInit, interceptor?: RequestInterceptor, responseInterceptor?: ResponseInterceptor): Promise<Response> {
+  const response = interceptor ? await fetchFn(await interceptor(new Request(url, init))) : await fetchFn(url, init)
+  return responseInterceptor ? responseInterceptor(response) : response
+}
+
+/**
+ * fetchWithRetry sends the request again after an exponential backoff when it fails with a network error or an http status of 5xx,
+ * until the retries run out. aborted requests are not retried
+ **/
+async function fetchWithRetry(send: () => Promise<Response>, retry?: RetryOptions, signal?: AbortSignal | null): Promise<Response> {
+  const maxRetries = retry ? retry.maxRetries : 0
+  const baseBackoffMs = retry && retry.baseBackoffMs !== undefined ? retry.baseBackoffMs : 100
+  for (let attempt = 0; ; attempt++) {
+    let r: Response
+    try {
+      r = await send()
+    } catch (e) {
+      if (attempt >= maxRetries || (signal && signal.aborted)) {
+        throw e
+      }
+      await new Promise(resolve => setTimeout(resolve, baseBackoffMs * 2 ** attempt))
+      continue
+    }
+
+    if (r.status < 500 || attempt >= maxRetries) {
+      return r
+    }
+    // the body of the failed response is discarded
+    await r.body?.cancel()
+    await new Promise(resolve => setTimeout(resolve, baseBackoffMs * 2 ** attempt))
+  }
+}
+
+/**
+ * RpcStatus is the error grpc-gateway responds with, which is google.rpc.Status in JSON.
+ * details are google.protobuf.Any in JSON, the type URL is in "@type" and the fields of the message sit next to it
+ **/
+export type RpcStatus = {
+  code?: number
+  message?: string
+  details?: {"@type"?: string, [key: string]: unknown}[]
+}
+
+/**
+ * RpcError is thrown by the clients when grpc-gateway responds with an error,
+ * either with an http status other than 2xx or in the middle of a server side streaming call
+ **/
+export class RpcError extends Error {
+  // code is the gRPC status code, e.g. 5 for NOT_FOUND
+  readonly code: number
+  // httpStatus is the http status of the response
+  readonly httpStatus: number
+  readonly details: {"@type"?: string, [key: string]: unknown}[]
+
+  constructor(status: RpcStatus, httpStatus: number) {
+    super(status.message || "")
+    this.name = "RpcError"
+    this.code = status.code || 0
+    this.httpStatus = httpStatus
+    this.details = status.details || []
+  }
+}
+
+/**
+ * getRpcError parses the error out of a response with an http status other than 2xx.
+ * responses which aren't sent by grpc-gateway, e.g. by a proxy, fall back to the http status text
+ **/
+async function getRpcError(r: Response): Promise<RpcError> {
+  let body
+  try {
+    body = await r.json()
+  } catch (e) {
+    return new RpcError({message: r.statusText}, r.status)
+  }
+
+  // errors of server side streaming calls are wrapped in the error field
+  const status = body && body.error && typeof body.error === "object" ? body.error : body
+  return new RpcError(status || {}, r.status)
+}
+
+/**
+ * mergeInitReq merges the InitReq of a call into the default InitReq of a client,
+ * fields and headers of the call take precedence over the ones of the client.
+ **/
+export function mergeInitReq(defaults?: InitReq, init?: InitReq): InitReq {
+  const headers = new Headers(defaults?.headers)
+  new Headers(init?.headers).forEach((value, key) => headers.set(key, value))
+
+  return {...defaults, ...init, headers}
+}
+
+// BytesFields describes where bytes fields are inside messages keyed by the fully qualified name of the message, then the field name.
+// the value of a field is either "bytes", the fully qualified name of a message containing bytes fields, or either of them prefixed by "map:" for maps
+export type BytesFields = {[message: string]: {[field: string]: string}}
+
+/**
+ * convertBytes converts the bytes fields inside the value of the given type with the convert function,
+ * repeated fields and map values are converted element by element
+ **/
+export function convertBytes<T>(value: T, type: string, fields: BytesFields, convert: (value: any) => any): T {
+  if (value === null || value === undefined) {
+    return value
+  }
+
+  if (Array.isArray(value)) {
+    return value.map(v => convertBytes(v, type, fields, convert)) as unknown as T
+  }
+
+  if (type === "bytes") {
+    return convert(value)
+  }
+
+  const converted: {[key: string]: any} = {...value}
+  if (type.startsWith("map:")) {
+    for (const key of Object.keys(converted)) {
+      converted[key] = convertBytes(converted[key], type.substring("map:".length), fields, convert)
+    }
+    return converted as T
+  }
+
+  for (const [name, fieldType] of Object.entries(fields[type] || {})) {
+    if (name in converted) {
+      converted[name] = convertBytes(converted[name], fieldType, fields, convert)
+    }
+  }
+  return converted as T
+}
+
+// base64Encode encodes bytes into a base64 string, which is how grpc-gateway encodes bytes in JSON
+export function base64Encode(bytes: Uint8Array): string {
+  let binary = ""
+  for (let i = 0; i < bytes.length; i++) {
+    binary += String.fromCharCode(bytes[i])
+  }
+  return btoa(binary)
+}
+
+// base64Decode decodes a base64 string into bytes, both the standard and the URL safe alphabets are accepted
+export function base64Decode(encoded: string): Uint8Array {
+  const binary = atob(encoded.replace(/-/g, "+").replace(/_/g, "/"))
+  const bytes = new Uint8Array(binary.length)
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i)
+  }
+  return bytes
+}
+
+// mapIterable applies the function to every element of the iterable
+export async function* mapIterable<T, R>(iterable: AsyncIterable<T>, fn: (value: T) => R): AsyncIterable<R> {
+  for await (const value of iterable) {
+    yield fn(value)
+  }
+}
+
+// NotifyStreamEntityArrival is a callback that will be called on streaming entity arrival
+export type NotifyStreamEntityArrival<T> = (resp: T) => void
+
+/**
+ * fetchStreamingRequest is able to handle grpc-gateway server side streaming call
+ * it takes NotifyStreamEntityArrival that lets users respond to entity arrival during the call
+ * the returned promise resolves after the call finishes.
+ **/
+export async function fetchStreamingRequest<S, R>(path: string, callback?: NotifyStreamEntityArrival<R>, init?: InitReq) {
+  for await (const entity of fetchStreamingIterable<S, R>(path, init)) {
+    if (callback) {
+      callback(entity)
+    }
+  }
+
+  // wait for the streaming to finish and return the success respond
+  return
+}
+
+/**
+ * fetchStreamingIterable is able to handle grpc-gateway server side streaming call
+ * it returns an AsyncIterable that yields every entity as soon as it arrives from the server.
+ * iterating throws an error when the server sends an error or the stream terminates in the middle of an entity.
+ **/
+export async function* fetchStreamingIterable<S, R>(path: string, init?: InitReq): AsyncIterable<R> {
+  const {firstByteTimeoutMs, ...rest} = init || {}
+  const timeout = startTimeout(rest.signal, firstByteTimeoutMs)
+  try {
+    yield* streamEntities<R>(path, {...rest, signal: timeout.signal}, timeout)
+  } finally {
+    timeout.dispose()
+  }
+}
+
+/**
+ * streamEntities sends the streaming call and yields every entity of the stream,
+ * the timeout is stopped once the response headers arrive
+ **/
+async function* streamEntities<R>(path: string, init: InitReq, timeout: Timeout): AsyncIterable<R> {
+  const {pathPrefix, basePath, fetch: fetchFn = fetch, decompress, contentType = "application/json", accept = "application/json", retry, idempotent, interceptor, responseInterceptor, timeoutMs, ...req} = init
+  const url = getURL(path, pathPrefix, basePath)
+  let result: Response
+  try {
+    result = await sendRequest(fetchFn, url, getRequestInit(req, contentType, accept), interceptor, responseInterceptor)
+  } catch (e) {
+    throw timeout.error(e)
+  } finally {
+    timeout.stop()
+  }
+  // needs to use the .ok to check the status of HTTP status code
+  // http other than 200 will not throw an error, instead the .ok will become false.
+  // see https://developer.mozilla.org/en-US/docs/Web/API/Fetch_API/Using_Fetch#
+  if (!result.ok) {
+    throw await getRpcError(result)
+  }
+
+  if (!result.body) {
+    throw new Error("response doesnt have a body")
+  }
+
+  // parsing a response of another content type, e.g. an html error page of a proxy, would yield garbage
+  const responseType = result.headers.get("Content-Type")
+  if (responseType && getMediaType(responseType) !== getMediaType(accept)) {
+    throw new Error("unexpected content type " + responseType + " of the streaming response, expected " + accept)
+  }
+
+  const reader = getDecodedBody(result.body, result.headers, decompress).getReader()
+  // aborting the request cancels the reader, so that a pending read settles and the iterator rejects with the abort reason
+  const onAbort = () => {
+    reader.cancel(req.signal?.reason).catch(() => {})
+  }
+  req.signal?.addEventListener("abort", onAbort)
+  const decoder = new TextDecoder()
+  let buf = ""
+  try {
+    while (true) {
+      const {done, value} = await reader.read()
+      req.signal?.throwIfAborted()
+      if (done) {
+        break
+      }
+
+      // entities can be split across network reads, only complete lines are decoded
+      buf += decoder.decode(value, {stream: true})
+      let pos = buf.indexOf("\n")
+      while (pos !== -1) {
+        const line = buf.substring(0, pos)
+        buf = buf.substring(pos + 1)
+        if (line.trim() !== "") {
+          yield getStreamingEntity<R>(JSON.parse(line), result.status)
+        }
+        pos = buf.indexOf("\n")
+      }
+    }
 
-  const url = pathPrefix ? ` + "`${pathPrefix}${path}`" + ` : path
+    buf += decoder.decode()
+    if (buf.trim() !== "") {
+      // grpc-gateway delimits every entity with a new line, anything left over has to be a complete entity
+      let response
+      try {
+        response = JSON.parse(buf)
+      } catch (e) {
+        throw new Error("stream terminated in the middle of an entity")
+      }
+      yield getStreamingEntity<R>(response, result.status)
+    }
+  } finally {
+    req.signal?.removeEventListener("abort", onAbort)
+    // the response might not be fully consumed when the iteration stops early, cancel it to close the connection
+    reader.cancel().catch(() => {})
+    reader.releaseLock()
+  }
+}
 
-  return fetch(url, req).then(r => r.json()) as Promise<O>
+// getMediaType returns the media type of a content type without its parameters, e.g. application/json for application/json; charset=utf-8
+function getMediaType(contentType: string): string {
+  return contentType.split(";")[0].trim().toLowerCase()
 }
 
-// NotifyStreamEntityArrival is a callback that will be called on streaming entity arrival
-export type NotifyStreamEntityArrival<T> = (resp: T) => void
+// fetchDecodedEncodings are the content encodings the fetch implementations of browsers and node decode by themselves
+const fetchDecodedEncodings = ["gzip", "x-gzip", "deflate", "br"]
 
 /**
- * fetchStreamingRequest is able to handle grpc-gateway server side streaming call
- * it takes NotifyStreamEntityArrival that lets users respond to entity arrival during the call
- * all entities will be returned as an array after the call finishes.
+ * getDecodedBody decompresses the body of a streaming response with the transforms returned by decompress.
+ * it throws an error when an encoding is neither decoded by fetch nor handled by decompress, rather than yielding garbage
  **/
-export async function fetchStreamingRequest<S, R>(path: string, callback?: NotifyStreamEntityArrival<R>, init?: InitReq) {
-  const {pathPrefix, ...req} = init || {}
-  const url = pathPrefix ?` + "`${pathPrefix}${path}`" + ` : path
-  const result = await fetch(url, req)
-  // needs to use the .ok to check the status of HTTP status code
-  // http other than 200 will not throw an error, instead the .ok will become false.
-  // see https://developer.mozilla.org/en-US/docs/Web/API/Fetch_API/Using_Fetch#
-  if (!result.ok) {
-    const resp = await result.json()
-    const errMsg = resp.error && resp.error.message ? resp.error.message : ""
-    throw new Error(errMsg)
-  }
+function getDecodedBody(body: ReadableStream<Uint8Array>, headers: Headers, decompress?: InitReq["decompress"]): ReadableStream<Uint8Array> {
+  const encodings = (headers.get("Content-Encoding") || "")
+    .split(",")
+    .map(e => e.trim().toLowerCase())
+    .filter(e => e !== "" && e !== "identity")
 
-  if (!result.body) {
-    throw new Error("response doesnt have a body")
-  }
+  // encodings are listed in the order they are applied, so they are undone in reverse
+  return encodings.reverse().reduce((stream, encoding) => {
+    const transform = decompress ? decompress(encoding) : undefined
+    if (transform) {
+      return stream.pipeThrough(transform)
+    }
 
-  await result.body
-    .pipeThrough(new TextDecoderStream())
-    .pipeThrough<R>(getNewLineDelimitedJSONDecodingStream<R>())
-    .pipeTo(getNotifyEntityArrivalSink((e: R) => {
-      if (callback) {
-        callback(e)
-      }
-    }))
+    if (!fetchDecodedEncodings.includes(encoding)) {
+      throw new Error("unsupported content encoding " + encoding + " of the streaming response, it can be decompressed with decompress of InitReq")
+    }
 
-  // wait for the streaming to finish and return the success respond
-  return
+    return stream
+  }, body)
 }
 
 /**
- * JSONStringStreamController represents the transform controller that's able to transform the incoming
- * new line delimited json content stream into entities and able to push the entity to the down stream
- */
-interface JSONStringStreamController<T> extends TransformStreamDefaultController {
-  buf?: string
-  pos?: number
-  enqueue: (s: T) => void
+ * DuplexStream is a bidirectional streaming call sent over a WebSocket,
+ * iterating it yields every entity the server sends until the WebSocket is closed
+ **/
+export interface DuplexStream<S, R> extends AsyncIterable<R> {
+  // send sends a message to the server, the messages sent before the WebSocket is open are queued
+  send(msg: S): void
+  // close closes the WebSocket, which ends the call on both sides
+  close(): void
 }
 
 /**
- * getNewLineDelimitedJSONDecodingStream returns a TransformStream that's able to handle new line delimited json stream content into parsed entities
- */
-function getNewLineDelimitedJSONDecodingStream<T>(): TransformStream<string, T> {
-  return new TransformStream({
-    start(controller: JSONStringStreamController<T>) {
-      controller.buf = ''
-      controller.pos = 0
-    },
+ * openDuplexStream opens a WebSocket to the bidirectional streaming method, e.g. bridged to grpc-gateway by grpc-websocket-proxy.
+ * the messages are sent and received as JSON, the http method of the binding is sent in the method query parameter
+ * which grpc-websocket-proxy reads it from. relative urls are resolved against the location of the page.
+ * headers can't be sent by WebSockets in browsers, only pathPrefix, basePath and signal of the InitReq are used
+ **/
+export function openDuplexStream<S, R>(path: string, httpMethod: string, init?: InitReq, encode: (msg: S) => unknown = msg => msg, decode: (entity: any) => R = entity => entity): DuplexStream<S, R> {
+  const url = new URL(getURL(path, init?.pathPrefix, init?.basePath), typeof location === "undefined" ? undefined : location.href)
+  url.protocol = url.protocol === "https:" ? "wss:" : "ws:"
+  url.searchParams.set("method", httpMethod)
+  const socket = new WebSocket(url.toString())
 
-    transform(chunk: string, controller: JSONStringStreamController<T>) {
-      if (controller.buf === undefined) {
-        controller.buf = ''
-      }
-      if (controller.pos === undefined) {
-        controller.pos = 0
-      }
-      controller.buf += chunk
-      while (controller.pos < controller.buf.length) {
-        if (controller.buf[controller.pos] === '\n') {
-          const line = controller.buf.substring(0, controller.pos)
-          const response = JSON.parse(line)
-          controller.enqueue(response.result)
-          controller.buf = controller.buf.substring(controller.pos + 1)
-          controller.pos = 0
+  const pending: string[] = []
+  const received: R[] = []
+  let failure: unknown = undefined
+  let closed = false
+  let closedByClient = false
+  let wake: (() => void) | undefined
+  const notify = () => {
+    wake?.()
+    wake = undefined
+  }
+
+  const close = () => {
+    closedByClient = true
+    init?.signal?.removeEventListener("abort", close)
+    socket.close(1000)
+  }
+  init?.signal?.addEventListener("abort", close)
+
+  socket.addEventListener("open", () => {
+    pending.splice(0).forEach(msg => socket.send(msg))
+  })
+  socket.addEventListener("message", (event: MessageEvent) => {
+    try {
+      // the WebSocket is not an http response, the errors sent by the server have no http status
+      received.push(decode(getStreamingEntity(JSON.parse(event.data), 0)))
+    } catch (e) {
+      failure = e
+      close()
+    }
+    notify()
+  })
+  socket.addEventListener("close", (event: CloseEvent) => {
+    closed = true
+    if (failure === undefined && !closedByClient && event.code !== 1000 && event.code !== 1005) {
+      failure = new Error("the WebSocket closed with code " + event.code + (event.reason ? ": " + event.reason : ""))
+    }
+    notify()
+  })
+
+  async function* entities(): AsyncIterable<R> {
+    try {
+      while (true) {
+        if (received.length > 0) {
+          yield received.shift() as R
+        } else if (failure !== undefined) {
+          throw failure
+        } else if (closed) {
+          return
         } else {
-          ++controller.pos
+          await new Promise<void>(resolve => {
+            wake = resolve
+          })
         }
       }
+    } finally {
+      // the iteration might stop early, the WebSocket isn't needed anymore
+      close()
     }
-  })
+  }
+  const iterable = entities()
 
+  return {
+    send: (msg: S) => {
+      const data = JSON.stringify(encode(msg))
+      if (socket.readyState === WebSocket.CONNECTING) {
+        pending.push(data)
+      } else {
+        socket.send(data)
+      }
+    },
+    close,
+    [Symbol.asyncIterator]: () => iterable[Symbol.asyncIterator](),
+  }
 }
 
 /**
- * getNotifyEntityArrivalSink takes the NotifyStreamEntityArrival callback and return
- * a sink that will call the callback on entity arrival
- * @param notifyCallback
+ * getStreamingEntity extracts the entity out of a single response sent by grpc-gateway during streaming
+ * it throws the error when the server sends one in the middle of the stream
  */
-function getNotifyEntityArrivalSink<T>(notifyCallback: NotifyStreamEntityArrival<T>) {
-  return new WritableStream<T>({
-    write(entity: T) {
-      notifyCallback(entity)
-    }
-  })
+function getStreamingEntity<T>(response: {result?: T, error?: RpcStatus}, httpStatus: number): T {
+  if (response.error) {
+    throw new RpcError(response.error, httpStatus)
+  }
+
+  return response.result as T
 }
 
-type Primitive = string | boolean | number;
+type Primitive = string | boolean | number | bigint;
 type RequestPayload = Record<string, unknown>;
 type FlattenedRequestPayload = Record<string, Primitive | Array<Primitive>>;
 
@@ -232,7 +1104,7 @@ function isPlainObject(value: unknown): boolean {
  * @return {boolean}
  */
 function isPrimitive(value: unknown): boolean {
-  return ["string", "number", "boolean"].some(t => typeof value === t);
+  return ["string", "number", "boolean", "bigint"].some(t => typeof value === t);
 }
 
 /**
@@ -241,13 +1113,15 @@ function isPrimitive(value: unknown): boolean {
  * @return {boolean}
  */
 function isZeroValuePrimitive(value: Primitive): boolean {
-  return value === false || value === 0 || value === "";
+  return value === false || value === 0 || value === "" || (typeof value === "bigint" && value.toString() === "0");
 }
 
 /**
  * Flattens a deeply nested request payload and returns an object
  * with only primitive values and non-empty array of primitive values
  * as per https://github.com/googleapis/googleapis/blob/master/google/api/http.proto
+ * nested messages are flattened into dotted paths, enums are sent by their names,
+ * and dates are sent in RFC 3339 like how grpc-gateway parses timestamps
  * @param  {RequestPayload} requestPayload
  * @param  {String} path
  * @return {FlattenedRequestPayload>}
@@ -271,7 +1145,9 @@ function flattenRequestPayload<T extends RequestPayload>(
 
       let objectToMerge = {};
 
-      if (isPlainObject(value)) {
+      if (value instanceof Date) {
+        objectToMerge = { [newPath]: value.toISOString() };
+      } else if (isPlainObject(value)) {
         objectToMerge = flattenRequestPayload(value as RequestPayload, newPath);
       } else if (isNonZeroValuePrimitive || isNonEmptyPrimitiveArray) {
         objectToMerge = { [newPath]: value };
@@ -283,10 +1159,71 @@ function flattenRequestPayload<T extends RequestPayload>(
   ) as FlattenedRequestPayload;
 }
 
+/**
+ * jsonPayload returns the JSON representation of the request, which calls toJSON of the message classes,
+ * so that the fields are sent by their json names in the url query parameters and the body
+ **/
+export function jsonPayload<T>(requestPayload: T): RequestPayload {
+  return JSON.parse(JSON.stringify(requestPayload))
+}
+
+/**
+ * Returns a shallow copy of the request payload without the fields which
+ * are already present in the URL path, so that they are not sent twice.
+ * @param  {RequestPayload} requestPayload
+ * @param  {string[]} urlPathParams
+ * @return {RequestPayload}
+ */
+export function omitPathParams<T extends RequestPayload>(
+  requestPayload: T,
+  urlPathParams: string[] = []
+): RequestPayload {
+  return Object.keys(requestPayload).reduce(
+    (acc: RequestPayload, key: string): RequestPayload => {
+      if (urlPathParams.find(f => f === key)) {
+        return acc;
+      }
+      return { ...acc, [key]: requestPayload[key] };
+    },
+    {} as RequestPayload
+  );
+}
+
+/**
+ * Renders the value of a field bound to a variable of the URL path with a
+ * pattern spanning several segments, e.g. {name=shelves/*}. The value is
+ * checked against the pattern, where * matches a single segment and ** the
+ * rest of the path, and each of its segments is escaped so that the slashes
+ * between them are kept.
+ * @param  {unknown} value
+ * @param  {string} field
+ * @param  {string} pattern
+ * @return {string}
+ */
+export function renderPathParam(value: unknown, field: string, pattern: string): string {
+  const segments = String(value ?? "").split("/");
+  const patternSegments = pattern.split("/");
+  const matches = patternSegments.every((patternSegment, i) => {
+    if (patternSegment === "**") {
+      return segments.slice(i).every((segment) => segment !== "");
+    }
+    if (patternSegment === "*") {
+      return i < segments.length && segments[i] !== "";
+    }
+    return segments[i] === patternSegment;
+  });
+  if (!matches || (!patternSegments.includes("**") && segments.length !== patternSegments.length)) {
+    throw new Error("the value " + JSON.stringify(value) + " of " + field + " doesn't match the path template " + pattern);
+  }
+
+  return segments.map(encodeURIComponent).join("/");
+}
+
 /**
  * Renders a deeply nested request payload into a string of URL search
  * parameters by first flattening the request payload and then removing keys
- * which are already present in the URL path.
+ * which are already present in the URL path or the body, including the fields
+ * nested inside them.
  * @param  {RequestPayload} requestPayload
  * @param  {string[]} urlPathParams
  * @return {string}
@@ -301,7 +1238,7 @@ export function renderURLSearchParams<T extends RequestPayload>(
     (acc: string[][], key: string): string[][] => {
       // key should not be present in the url path as a parameter
       const value = flattenedRequestPayload[key];
-      if (urlPathParams.find(f => f === key)) {
+      if (urlPathParams.find(f => f === key || key.startsWith(f + "."))) {
         return acc;
       }
       return Array.isArray(value)
@@ -315,6 +1252,26 @@ export function renderURLSearchParams<T extends RequestPayload>(
 }
 `
 
+const rollupTmpl = `
+/*
+* This file is a generated Typescript file for GRPC Gateway, DO NOT MODIFY
+*/
+{{range .}}
+export namespace {{.Name}} {
+{{- range .Enums}}
+{{jsdoc .Comment "  "}}  export type {{.Name}} = {{enumUnion .}}
+{{- end}}
+{{- range .Messages}}
+{{jsdoc .Comment "  " (ternary "@deprecated" "" .IsDeprecated)}}  export type {{.Name}} = {
+{{- range .Fields}}
+{{jsdoc .Comment "    " (ternary "@deprecated" "" .IsDeprecated)}}    {{if readonly}}readonly {{end}}{{propertyName .}}?: {{rollupType .}}{{optionalType .}}
+{{- end}}
+  }
+{{- end}}
+}
+{{end}}
+`
+
 // GetTemplate gets the templates to for the typescript file
 func GetTemplate(r *registry.Registry) *template.Template {
 	t := template.New("file")
@@ -325,51 +1282,276 @@ func GetTemplate(r *registry.Registry) *template.Template {
 		"tsType": func(fieldType data.Type) string {
 			return tsType(r, fieldType)
 		},
-		"renderURL":    renderURL(r),
-		"buildInitReq": buildInitReq,
-		"fieldName":    fieldName(r),
+		"requestType":                requestType(r),
+		"requestParam":               requestParam(r),
+		"responseType":               responseType(r),
+		"renderURL":                  renderURL(r),
+		"buildInitReq":               buildInitReq(r),
+		"requestBody":                requestBody(r),
+		"fieldName":                  fieldName(r),
+		"propertyName":               propertyName(r),
+		"jsdoc":                      jsdoc,
+		"untitle":                    untitle,
+		"needsBytesConversion":       needsBytesConversion(r),
+		"needsUnaryResponseDecoding": needsUnaryResponseDecoding(r),
+		"needsResponseDecoding":      needsResponseDecoding(r),
+		"decodeResponse":             decodeResponse(r),
+		"jsonLookup":                 renderJSONLookup(r),
+		"fromJSONValue":              renderFromJSONValue(r),
+		"toJSONValue":                renderToJSONValue(r),
+		"jsonName": func(f *data.Field) string {
+			return jsonFieldName(r, f)
+		},
+		"messageKind": func() string {
+			return r.MessageKind
+		},
+		"clientStyle": func() string {
+			return r.ClientStyle
+		},
+		"renderBytesFields": renderBytesFields(r),
+		"enumStyle": func() string {
+			return r.EnumStyle
+		},
+		"readonly": func() bool {
+			return r.Readonly
+		},
+		"satisfies": func() bool {
+			return r.SupportsTarget(registry.SatisfiesTarget)
+		},
+		"optionalType": renderOptionalType(r),
+		"enumUnknown": func() string {
+			return r.EnumUnknown
+		},
+		"moduleSystem": func() string {
+			return r.ModuleSystem
+		},
+		"int64KeyedMaps":      getInt64KeyedMaps(r),
+		"hasFieldMaskPaths":   hasFieldMaskPaths(r),
+		"int64MapEntriesName": int64MapEntriesName,
+		"mapValueType":        mapValueType(r),
+		"int64Type": func() string {
+			return r.Int64Type
+		},
+		"emitFactories": func() bool {
+			return r.EmitFactories
+		},
+		"emitsDeclarations": func() bool {
+			return r.EmitsDeclarations()
+		},
+		"defaultValue": renderDefaultValue(r),
+		"emitGuards": func() bool {
+			return r.EmitGuards
+		},
+		"guardCheck": renderGuardCheck(r),
+		"emitEquals": func() bool {
+			return r.EmitEquals
+		},
+		"equalsCheck":   renderEqualsCheck(r),
+		"equalsHelpers": getEqualsHelpers(r),
+		"apiObject": func() bool {
+			return r.APIObject
+		},
+		"emitValidators": func() bool {
+			return r.EmitValidators
+		},
+		"fieldValidations": getFieldValidations(r),
+		"enumNamespaces":   renderEnumNamespaces(r),
 	})
 
 	t = template.Must(t.Parse(tmpl))
 	return t
 }
 
-func fieldName(r *registry.Registry) func(name string) string {
-	return func(name string) string {
-		if r.UseProtoNames {
-			return name
+func fieldName(r *registry.Registry) func(field *data.Field) string {
+	return func(field *data.Field) string {
+		return renderFieldName(r, field.Name, field.JSONName)
+	}
+}
+
+// propertyName renders the name of the field as a property key, names which aren't identifiers like the ones of extensions are quoted
+func propertyName(r *registry.Registry) func(field *data.Field) string {
+	return func(field *data.Field) string {
+		name := renderFieldName(r, field.Name, field.JSONName)
+		if !identifierRegexp.MatchString(name) {
+			return strconv.Quote(name)
+		}
+
+		return name
+	}
+}
+
+// renderFieldName renders the name of the field in the case configured by ts_field_case,
+// extensions are keyed by their fully qualified names in brackets regardless of the case
+func renderFieldName(r *registry.Registry, name, jsonName string) string {
+	if strings.HasPrefix(name, "[") {
+		return name
+	}
+
+	return r.FieldName(name, jsonName)
+}
+
+// pathParamFieldName returns the rendered name of the field bound to the url path of the method
+func pathParamFieldName(r *registry.Registry, method data.Method, name string) string {
+	jsonName := ""
+	if typeInfo, ok := r.Types[method.Input.Type]; ok {
+		jsonName = typeInfo.FieldJSONNames[name]
+	}
+
+	return renderFieldName(r, name, jsonName)
+}
+
+// jsdoc renders the comment from the proto file as a JSDoc block with every line prefixed by indent.
+// the common indentation of the comment lines is removed so that the content is reflowed inside the block.
+// non empty tags, e.g. @deprecated, are appended after the comment.
+// it returns an empty string when there isn't any comment or tag.
+func jsdoc(comment, indent string, tags ...string) string {
+	lines := strings.Split(strings.ReplaceAll(comment, "*/", "*\\/"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+
+	// strip empty lines at the start and the end of the comment
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	commonIndent := -1
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+		lineIndent := len(l) - len(strings.TrimLeft(l, " \t"))
+		if commonIndent == -1 || lineIndent < commonIndent {
+			commonIndent = lineIndent
+		}
+	}
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = l[commonIndent:]
+		}
+	}
+
+	for _, tag := range tags {
+		if tag == "" {
+			continue
+		}
+		if len(lines) > 0 && !strings.HasPrefix(lines[len(lines)-1], "@") {
+			lines = append(lines, "")
+		}
+		lines = append(lines, tag)
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	buf := bytes.NewBufferString(indent + "/**\n")
+	for _, l := range lines {
+		if l == "" {
+			buf.WriteString(indent + " *\n")
+			continue
 		}
+		buf.WriteString(indent + " * " + l + "\n")
+	}
+	buf.WriteString(indent + " */\n")
+
+	return buf.String()
+}
+
+// urlPathParamsRegexp matches the variables inside the url path template
+var urlPathParamsRegexp = regexp.MustCompile("{([^}]+)}")
+
+// identifierRegexp matches the property names which don't need to be quoted
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// renderURLPathParams renders the list of field names bound to the url path as a typescript array literal
+func renderURLPathParams(r *registry.Registry, method data.Method) string {
+	return renderFieldNames(getURLPathParams(r, method))
+}
 
-		return strcase.ToLowerCamel(name)
+// getURLPathParams returns the names of the fields bound to the url path inside the request payload
+func getURLPathParams(r *registry.Registry, method data.Method) []string {
+	matches := urlPathParamsRegexp.FindAllStringSubmatch(method.URL, -1)
+	fieldsInPath := make([]string, 0, len(matches))
+	for _, m := range matches {
+		field, _ := parsePathVariable(m[1])
+		fieldsInPath = append(fieldsInPath, payloadFieldName(r, method, field))
 	}
+
+	return fieldsInPath
+}
+
+// parsePathVariable splits a variable of the url path template, e.g. name=shelves/*/books/*, into the field bound to it
+// and the pattern its value matches, the pattern is empty for the variables without one, which match a single segment
+func parsePathVariable(variable string) (string, string) {
+	parts := strings.SplitN(variable, "=", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// renderFieldNames renders the field names as a typescript array literal
+func renderFieldNames(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf(`"%s"`, name))
+	}
+
+	return fmt.Sprintf("[%s]", strings.Join(quoted, ", "))
+}
+
+// hasNamedBody returns whether only a single field of the request is sent as the body
+func hasNamedBody(method data.Method) bool {
+	return method.HTTPRequestBody != nil && *method.HTTPRequestBody != "" && *method.HTTPRequestBody != "*"
+}
+
+// hasURLSearchParams returns whether the fields not bound to the url path or the body are sent as url query parameters.
+// that's the case for GET requests and requests whose http rule doesn't send the whole request as the body
+func hasURLSearchParams(method data.Method) bool {
+	if method.ClientStreaming {
+		return false
+	}
+
+	return method.HTTPMethod == "GET" || (method.HTTPRequestBody != nil && *method.HTTPRequestBody != "*")
 }
 
 func renderURL(r *registry.Registry) func(method data.Method) string {
-	fieldNameFn := fieldName(r)
 	return func(method data.Method) string {
 		methodURL := method.URL
-		reg := regexp.MustCompile("{([^}]+)}")
-		matches := reg.FindAllStringSubmatch(methodURL, -1)
-		fieldsInPath := make([]string, 0, len(matches))
+		matches := urlPathParamsRegexp.FindAllStringSubmatch(methodURL, -1)
 		if len(matches) > 0 {
 			log.Debugf("url matches %v", matches)
 			for _, m := range matches {
 				expToReplace := m[0]
-				fieldName := fieldNameFn(m[1])
+				field, pattern := parsePathVariable(m[1])
+				fieldName := pathParamFieldName(r, method, field)
 				part := fmt.Sprintf(`${req["%s"]}`, fieldName)
+				if pattern != "" && pattern != "*" {
+					// the value spans several segments, it's checked against the pattern and its slashes are kept
+					part = fmt.Sprintf(`${fm.renderPathParam(req["%s"], "%s", "%s")}`, fieldName, fieldName, pattern)
+				}
 				methodURL = strings.ReplaceAll(methodURL, expToReplace, part)
-				fieldsInPath = append(fieldsInPath, fmt.Sprintf(`"%s"`, fieldName))
 			}
 		}
-		urlPathParams := fmt.Sprintf("[%s]", strings.Join(fieldsInPath, ", "))
 
-		if !method.ClientStreaming && method.HTTPMethod == "GET" {
+		if hasURLSearchParams(method) {
+			// fields bound to the url path or the body are not query parameters
+			excluded := getURLPathParams(r, method)
+			if hasNamedBody(method) {
+				excluded = append(excluded, payloadFieldName(r, method, *method.HTTPRequestBody))
+			}
+
 			// parse the url to check for query string
 			parsedURL, err := url.Parse(methodURL)
 			if err != nil {
 				return methodURL
 			}
-			renderURLSearchParamsFn := fmt.Sprintf("${fm.renderURLSearchParams(req, %s)}", urlPathParams)
+			renderURLSearchParamsFn := fmt.Sprintf("${fm.renderURLSearchParams(%s, %s)}", requestPayload(r), renderFieldNames(excluded))
 			// prepend "&" if query string is present otherwise prepend "?"
 			// trim leading "&" if present before prepending it
 			if parsedURL.RawQuery != "" {
@@ -383,19 +1565,131 @@ func renderURL(r *registry.Registry) func(method data.Method) string {
 	}
 }
 
-func buildInitReq(method data.Method) string {
-	httpMethod := method.HTTPMethod
-	m := `method: "` + httpMethod + `"`
-	fields := []string{m}
-	if method.HTTPRequestBody == nil || *method.HTTPRequestBody == "*" {
-		fields = append(fields, "body: JSON.stringify(req)")
-	} else if *method.HTTPRequestBody != "" {
-		fields = append(fields, `body: JSON.stringify(req["`+*method.HTTPRequestBody+`"])`)
+// requestBody renders the expression of the value sent as the request body, an empty string is returned when there's no body
+func requestBody(r *registry.Registry) func(method data.Method) string {
+	return func(method data.Method) string {
+		if method.HTTPRequestBody == nil || *method.HTTPRequestBody == "*" {
+			if urlPathParamsRegexp.MatchString(method.URL) {
+				// fields bound to the url path are not part of the body
+				return fmt.Sprintf("fm.omitPathParams(%s, %s)", requestPayload(r), renderURLPathParams(r, method))
+			}
+			return "req"
+		}
+
+		if hasNamedBody(method) {
+			// only the field selected by the http rule is sent as the body
+			return fmt.Sprintf(`req["%s"]`, pathParamFieldName(r, method, *method.HTTPRequestBody))
+		}
+
+		return ""
+	}
+}
+
+func buildInitReq(r *registry.Registry) func(method data.Method) string {
+	return func(method data.Method) string {
+		httpMethod := method.HTTPMethod
+		m := `method: "` + httpMethod + `"`
+		fields := []string{m}
+		if body := requestBody(r)(method); body != "" {
+			fields = append(fields, "body: JSON.stringify("+body+")")
+		}
+
+		if !method.ServerStreaming && (httpMethod == "GET" || method.Idempotent) {
+			// the call is safe to retry with the retry options of the InitReq
+			fields = append(fields, "idempotent: true")
+		}
+
+		return strings.Join(fields, ", ")
 	}
+}
+
+const barrelTmpl = `
+/*
+* This file is a generated Typescript file for GRPC Gateway, DO NOT MODIFY
+*/
+{{range .}}
+{{- if eq moduleSystem "commonjs"}}
+import {{.ModuleIdentifier}} = require("{{.SourceFile}}")
+{{- $module := .ModuleIdentifier}}
+{{- range .Values}}
+export import {{.}} = {{$module}}.{{.}}
+{{- end}}
+{{- range .Types}}
+export type {{.}} = {{$module}}.{{.}}
+{{- end}}
+{{- else}}
+{{- if .Values}}
+export { {{join .Values ", "}} } from "{{.SourceFile}}"
+{{- end}}
+{{- if .Types}}
+export type { {{join .Types ", "}} } from "{{.SourceFile}}"
+{{- end}}
+{{- end}}
+{{- end}}
+`
+
+const mockTmpl = `
+/*
+* This file is a generated Typescript file for GRPC Gateway, DO NOT MODIFY
+*/
+{{range .Dependencies}}{{if eq moduleSystem "commonjs"}}import {{.ModuleIdentifier}} = require("{{.SourceFile}}"){{else}}import * as {{.ModuleIdentifier}} from "{{.SourceFile}}"{{end}}
+{{end}}
+{{- range .Messages}}
+// make{{.Name}} returns {{.Name}} with every field set to a deterministic dummy value, nested messages are populated by their make functions.
+// the overrides take precedence over the dummy values
+export function make{{.Name}}(overrides?: Partial<{{.Type}}>): {{.Type}} {
+{{- if eq messageKind "class"}}
+  return Object.assign(new {{.Type}}(), {
+{{- range .Fields}}
+    {{.Name}}: {{.Value}},
+{{- end}}
+{{- if .Fields}}
+  {{end}}}, overrides)
+{{- else}}
+  const mock: {{.Type}} = {
+{{- range .Fields}}
+    {{.Name}}: {{.Value}},
+{{- end}}
+{{- if .Fields}}
+  {{end}}}
+  return {...mock, ...overrides}
+{{- end}}
+}
+{{end}}
+`
 
-	return strings.Join(fields, ", ")
+const reactQueryTmpl = `
+/*
+* This file is a generated Typescript file for GRPC Gateway, DO NOT MODIFY
+*/
+{{range .Dependencies}}{{if eq moduleSystem "commonjs"}}import {{.ModuleIdentifier}} = require("{{.SourceFile}}"){{else}}import * as {{.ModuleIdentifier}} from "{{.SourceFile}}"{{end}}
+{{end}}
+{{- range .Methods}}
+{{- if .IsQuery}}
+// {{untitle .Name}}QueryKey returns the query key of {{.Method}} for the request, e.g. to invalidate its queries
+export function {{untitle .Name}}QueryKey(req: {{.RequestType}}{{if .IsEmptyRequest}} = {}{{end}}) {
+  return ["{{.Service}}", "{{.Method}}", req] as const
+}
 
+// use{{.Name}}Query queries {{.Method}} of {{.Service}}, the signal of the query is forwarded to the request along with the request options
+export function use{{.Name}}Query(req: {{.RequestType}}{{if .IsEmptyRequest}} = {}{{end}}, initReq?: fm.InitReq, options?: Omit<ReactQuery.UseQueryOptions<{{.ResponseType}}>, "queryKey" | "queryFn">) {
+  return ReactQuery.useQuery({
+    ...options,
+    queryKey: {{untitle .Name}}QueryKey(req),
+    queryFn: ({signal}) => {{.Client}}.{{.Method}}(req, {...initReq, signal}),
+  })
+}
+{{else}}
+// use{{.Name}}Mutation sends {{.Method}} of {{.Service}} with the variables of the mutation as the request, along with the request options
+export function use{{.Name}}Mutation(initReq?: fm.InitReq, options?: Omit<ReactQuery.UseMutationOptions<{{.ResponseType}}, Error, {{if .IsEmptyRequest}}void{{else}}{{.RequestType}}{{end}}>, "mutationFn">) {
+  return ReactQuery.useMutation({
+    ...options,
+    mutationFn: ({{if not .IsEmptyRequest}}req: {{.RequestType}}{{end}}) => {{.Client}}.{{.Method}}({{if .IsEmptyRequest}}{}{{else}}req{{end}}, initReq),
+  })
 }
+{{end}}
+{{- end}}
+`
 
 // GetFetchModuleTemplate returns the go template for fetch module
 func GetFetchModuleTemplate() *template.Template {
@@ -403,6 +1697,16 @@ func GetFetchModuleTemplate() *template.Template {
 	return template.Must(t.Parse(fetchTmpl))
 }
 
+// untitle lowercases the first letter of the name, e.g. the enum helper functions are named after the enum in lowerCamelCase
+func untitle(name string) string {
+	if name == "" {
+		return name
+	}
+
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
 // include is the include template functions copied from
 // copied from: https://github.com/helm/helm/blob/8648ccf5d35d682dcd5f7a9c2082f0aaf071e817/pkg/engine/engine.go#L147-L154
 func include(t *template.Template) func(name string, data interface{}) (string, error) {
@@ -416,40 +1720,160 @@ func include(t *template.Template) func(name string, data interface{}) (string,
 }
 
 func tsType(r *registry.Registry, fieldType data.Type) string {
+	return renderTSType(r, fieldType, func(typeInfo *registry.TypeInformation, isExternal bool) string {
+		if !isExternal || r.IsBundled(typeInfo.File) {
+			return typeInfo.PackageIdentifier
+		}
+
+		return data.GetModuleName(typeInfo.Package, typeInfo.File) + "." + typeInfo.PackageIdentifier
+	})
+}
+
+// renderTSType renders the typescript type of the field, the enums and the messages are referenced by the name returned by typeRef
+func renderTSType(r *registry.Registry, fieldType data.Type, typeRef func(typeInfo *registry.TypeInformation, isExternal bool) string) string {
 	info := fieldType.GetType()
 	typeInfo, ok := r.Types[info.Type]
 	if ok && typeInfo.IsMapEntry {
-		keyType := tsType(r, typeInfo.KeyType)
-		valueType := tsType(r, typeInfo.ValueType)
+		keyType := renderTSType(r, typeInfo.KeyType, typeRef)
+		if isInt64Type(typeInfo.KeyType.Type) {
+			// keys of JSON objects are always strings, 64-bit integer keys can't be indexed by the int64 type.
+			// the Entries helper of the map field converts them
+			keyType = "string"
+		}
+		valueType := renderTSType(r, typeInfo.ValueType, typeRef)
+
+		readonly := ""
+		if r.Readonly {
+			readonly = "readonly "
+		}
 
-		return fmt.Sprintf("{[key: %s]: %s}", keyType, valueType)
+		if keyTypeInfo, ok := r.Types[typeInfo.KeyType.Type]; ok && keyTypeInfo.ProtoType == descriptorpb.FieldDescriptorProto_TYPE_ENUM {
+			// index signature can't be an enum or a union, a mapped type constrains the keys to the enum values instead.
+			// keys are optional as the map doesn't necessarily contain all of them
+			return fmt.Sprintf("{%s[key in %s]?: %s}", readonly, keyType, valueType)
+		}
+		return fmt.Sprintf("{%s[key: %s]: %s}", readonly, keyType, valueType)
+	}
+
+	if typedArray := typedArrayType(r, fieldType); typedArray != "" {
+		// typed arrays don't have readonly variants
+		return typedArray
 	}
 
 	typeStr := ""
 	if strings.Index(info.Type, ".") != 0 {
-		typeStr = mapScalaType(info.Type)
-	} else if !info.IsExternal {
-		typeStr = typeInfo.PackageIdentifier
+		typeStr = mapScalaType(r, info.Type)
 	} else {
-		typeStr = data.GetModuleName(typeInfo.Package, typeInfo.File) + "." + typeInfo.PackageIdentifier
+		typeStr = typeRef(typeInfo, info.IsExternal)
 	}
 
 	if info.IsRepeated {
+		if r.Readonly {
+			return "ReadonlyArray<" + typeStr + ">"
+		}
 		typeStr += "[]"
 	}
 	return typeStr
 }
 
-func mapScalaType(protoType string) string {
+// renderOptionalType renders the union typing the field as absent in the style of ts_optional_style, for proto3 optional fields
+// and singular message fields, which grpc-gateway omits when they are unset. the other fields are only optional properties.
+// message fields are left as they are in the undefined style, as optional properties are undefined already
+func renderOptionalType(r *registry.Registry) func(f *data.Field) string {
+	return func(f *data.Field) string {
+		isMap := r.Types[f.Type] != nil && r.Types[f.Type].IsMapEntry
+		isSingularMessage := f.IsMessage && !f.IsRepeated && !f.IsOneOfField && !isMap
+		switch {
+		case r.OptionalStyle == registry.OptionalStyleNull && (f.IsOptional || isSingularMessage):
+			return " | null"
+		case r.OptionalStyle == registry.OptionalStyleBoth && (f.IsOptional || isSingularMessage):
+			return " | null | undefined"
+		case f.IsOptional:
+			return " | undefined"
+		}
+
+		return ""
+	}
+}
+
+// requestType renders the type of the request of a method, google.protobuf.Empty is rendered as an empty object
+func requestType(r *registry.Registry) func(arg *data.MethodArgument) string {
+	return func(arg *data.MethodArgument) string {
+		if arg.IsEmpty {
+			return "{}"
+		}
+
+		return tsType(r, arg)
+	}
+}
+
+// requestParam renders the request parameter of a method, the request of google.protobuf.Empty can be left out
+func requestParam(r *registry.Registry) func(arg *data.MethodArgument) string {
+	return func(arg *data.MethodArgument) string {
+		if arg.IsEmpty {
+			return "req: {} = {}"
+		}
+
+		return "req: " + tsType(r, arg)
+	}
+}
+
+// responseType renders the type of the response of a method, google.protobuf.Empty is rendered as void
+func responseType(r *registry.Registry) func(arg *data.MethodArgument) string {
+	return func(arg *data.MethodArgument) string {
+		if arg.IsEmpty {
+			return "void"
+		}
+
+		return tsType(r, arg)
+	}
+}
+
+func mapScalaType(r *registry.Registry, protoType string) string {
 	switch protoType {
-	case "uint64", "sint64", "int64", "fixed64", "sfixed64", "string":
+	case "uint64", "sint64", "int64", "fixed64", "sfixed64":
+		return r.Int64Type
+	case "string", "duration":
 		return "string"
+	case "fieldmask":
+		if r.FieldMaskType == registry.FieldMaskTypeArray {
+			if r.Readonly {
+				return "ReadonlyArray<string>"
+			}
+			return "string[]"
+		}
+		return "string"
+	case "timestamp":
+		return r.TimestampType
+	case "struct":
+		if r.Readonly {
+			return "{readonly [key: string]: any}"
+		}
+		return "{[key: string]: any}"
+	case "value":
+		return "any"
+	case "listvalue":
+		if r.Readonly {
+			return "ReadonlyArray<any>"
+		}
+		return "any[]"
+	case "nullvalue":
+		return "null"
+	case "any":
+		// Any is encoded as the fields of the message next to the type URL of the message in "@type"
+		if r.Readonly {
+			return `{readonly "@type": string, readonly [key: string]: any}`
+		}
+		return `{"@type": string, [key: string]: any}`
 	case "float", "double", "int32", "sint32", "uint32", "fixed32", "sfixed32":
 		return "number"
 	case "bool":
 		return "boolean"
 	case "bytes":
-		return "Uint8Array"
+		if r.BytesType == registry.BytesTypeUint8Array {
+			return "Uint8Array"
+		}
+		return "string"
 	}
 
 	return ""
@@ -0,0 +1,46 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus" // nolint: depguard
+)
+
+// LoadTemplateDir parses the .tmpl files inside the directory into the template in alphabetical order, overriding the built-in templates.
+// {{define}} blocks override the named templates, e.g. "messages", and the content outside of them overrides the template of the whole file
+func LoadTemplateDir(t *template.Template, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return errors.Wrapf(err, "error looking up template directory %s", dir)
+	}
+
+	if !info.IsDir() {
+		return errors.Errorf("template directory %s is not a directory", dir)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return errors.Wrapf(err, "error listing templates in %s", dir)
+	}
+
+	if len(files) == 0 {
+		log.Warnf("no .tmpl files are found in template directory %s, using the built-in templates", dir)
+	}
+
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			return errors.Wrapf(err, "error reading template %s", f)
+		}
+
+		log.Debugf("overriding templates with %s", f)
+		if _, err := t.Parse(string(content)); err != nil {
+			return errors.Wrapf(err, "error parsing template %s", f)
+		}
+	}
+
+	return nil
+}
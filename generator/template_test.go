@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSDoc(t *testing.T) {
+	tests := []struct {
+		name     string
+		comment  string
+		indent   string
+		tags     []string
+		expected string
+	}{
+		{
+			name:     "empty comment",
+			comment:  "",
+			indent:   "",
+			expected: "",
+		},
+		{
+			name:     "single line with leading space from proto",
+			comment:  " the host name",
+			indent:   "  ",
+			expected: "  /**\n   * the host name\n   */\n",
+		},
+		{
+			name:     "multi line keeps relative indentation",
+			comment:  " first line\n   indented\n\n last line\n",
+			indent:   "",
+			expected: "/**\n * first line\n *   indented\n *\n * last line\n */\n",
+		},
+		{
+			name:     "tags are appended after the comment",
+			comment:  " the host name",
+			indent:   "",
+			tags:     []string{"", "@deprecated"},
+			expected: "/**\n * the host name\n *\n * @deprecated\n */\n",
+		},
+		{
+			name:     "tags without comment",
+			comment:  "",
+			indent:   "  ",
+			tags:     []string{"@deprecated"},
+			expected: "  /**\n   * @deprecated\n   */\n",
+		},
+		{
+			name:     "comment terminator is escaped",
+			comment:  " a */ b",
+			indent:   "",
+			expected: "/**\n * a *\\/ b\n */\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, jsdoc(tt.comment, tt.indent, tt.tags...))
+		})
+	}
+}
+
+func TestFormatCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		indent     string
+		quoteStyle string
+		expected   string
+	}{
+		{
+			name:       "defaults are left as they are",
+			code:       "if (a) {\n  return \"a\"\n}",
+			indent:     "  ",
+			quoteStyle: "double",
+			expected:   "if (a) {\n  return \"a\"\n}",
+		},
+		{
+			name:       "each level of indentation is replaced",
+			code:       "/**\n * doc\n */\nif (a) {\n  if (b) {\n    return\n  }\n}",
+			indent:     "\t",
+			quoteStyle: "double",
+			expected:   "/**\n * doc\n */\nif (a) {\n\tif (b) {\n\t\treturn\n\t}\n}",
+		},
+		{
+			name:       "strings are single quoted with the quotes escaped",
+			code:       `const a = "it's \"b\"" + 'c'`,
+			indent:     "  ",
+			quoteStyle: "single",
+			expected:   `const a = 'it\'s "b"' + 'c'`,
+		},
+		{
+			name:       "comments are left as they are",
+			code:       "// doesn't \"change\"\n/* \"a\" */ \"b\"",
+			indent:     "  ",
+			quoteStyle: "single",
+			expected:   "// doesn't \"change\"\n/* \"a\" */ 'b'",
+		},
+		{
+			name:       "only the expressions of template literals are re-quoted",
+			code:       "`\"a\" ${req[\"id\"]} ${{a: \"b\"}[\"a\"]} ` + \"c\"",
+			indent:     "  ",
+			quoteStyle: "single",
+			expected:   "`\"a\" ${req['id']} ${{a: 'b'}['a']} ` + 'c'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatCode(tt.code, tt.indent, tt.quoteStyle))
+		})
+	}
+}
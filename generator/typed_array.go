@@ -0,0 +1,24 @@
+package generator
+
+import (
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// typedArrayType returns the typed array the repeated field is rendered as with ts_typed_arrays, Float32Array for float and
+// Float64Array for double fields, and an empty string for the other fields
+func typedArrayType(r *registry.Registry, fieldType data.Type) string {
+	info := fieldType.GetType()
+	if !r.TypedArrays || !info.IsRepeated {
+		return ""
+	}
+
+	switch info.Type {
+	case "float":
+		return "Float32Array"
+	case "double":
+		return "Float64Array"
+	}
+
+	return ""
+}
@@ -0,0 +1,159 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/registry"
+)
+
+// fieldValidation is the field of a message with constraints, which the validator of the message reads into v before checking them
+type fieldValidation struct {
+	// Name is the rendered name of the field
+	Name string
+	// Checks are the constraints of the field
+	Checks []*validationCheck
+}
+
+// validationCheck is a constraint of a field checked by the validator of its message
+type validationCheck struct {
+	// Condition is the javascript expression which is true when the value v of the field violates the constraint
+	Condition string
+	// Message is the violation reported by the validator as a javascript string literal
+	Message string
+}
+
+// getFieldValidations returns the fields of the message with constraints, the validator of a message is only generated
+// when it has any
+func getFieldValidations(r *registry.Registry) func(m *data.Message) []*fieldValidation {
+	return func(m *data.Message) []*fieldValidation {
+		validations := make([]*fieldValidation, 0)
+		for _, f := range m.Fields {
+			if f.Rules == nil {
+				continue
+			}
+
+			name := renderFieldName(r, f.Name, f.JSONName)
+			checks := validationChecks(r, f, name)
+			if len(checks) > 0 {
+				validations = append(validations, &fieldValidation{Name: name, Checks: checks})
+			}
+		}
+
+		return validations
+	}
+}
+
+// validationChecks renders the checks of the constraints of the field. unset fields are checked as their zero values, which
+// is how grpc-gateway decodes them on the server
+func validationChecks(r *registry.Registry, f *data.Field, name string) []*validationCheck {
+	rules := f.Rules
+	checks := make([]*validationCheck, 0)
+	check := func(condition, format string, args ...interface{}) {
+		checks = append(checks, &validationCheck{
+			Condition: condition,
+			Message:   jsString(name + " " + fmt.Sprintf(format, args...)),
+		})
+	}
+
+	typeInfo, isMap := r.Types[f.Type]
+	isMap = isMap && typeInfo.IsMapEntry
+	scalarType := mapScalaType(r, f.Type)
+	switch {
+	case f.IsRepeated || isMap:
+		length := "(v ?? []).length"
+		if isMap {
+			length = "Object.keys(v ?? {}).length"
+		}
+		if rules.Required {
+			check(length+" === 0", "is required")
+		}
+		if rules.MinItems != nil {
+			check(fmt.Sprintf("%s < %d", length, *rules.MinItems), "must have at least %d items", *rules.MinItems)
+		}
+		if rules.MaxItems != nil {
+			check(fmt.Sprintf("%s > %d", length, *rules.MaxItems), "must have at most %d items", *rules.MaxItems)
+		}
+	case f.Type == "string":
+		if rules.Required {
+			check(`v === undefined || v === null || v === ""`, "is required")
+		}
+		// the lengths are counted in characters like protoc-gen-validate does, rather than in UTF-16 code units
+		length := `[...(v ?? "")].length`
+		if rules.Len != nil {
+			check(fmt.Sprintf("%s !== %d", length, *rules.Len), "must be %d characters", *rules.Len)
+		}
+		if rules.MinLen != nil {
+			check(fmt.Sprintf("%s < %d", length, *rules.MinLen), "must be at least %d characters", *rules.MinLen)
+		}
+		if rules.MaxLen != nil {
+			check(fmt.Sprintf("%s > %d", length, *rules.MaxLen), "must be at most %d characters", *rules.MaxLen)
+		}
+		if rules.Pattern != "" {
+			check(fmt.Sprintf(`!new RegExp(%s).test(v ?? "")`, jsString(rules.Pattern)), "must match the pattern %s", rules.Pattern)
+		}
+		if rules.Prefix != "" {
+			check(fmt.Sprintf(`!(v ?? "").startsWith(%s)`, jsString(rules.Prefix)), "must start with %s", rules.Prefix)
+		}
+		if rules.Suffix != "" {
+			check(fmt.Sprintf(`!(v ?? "").endsWith(%s)`, jsString(rules.Suffix)), "must end with %s", rules.Suffix)
+		}
+		if rules.Contains != "" {
+			check(fmt.Sprintf(`!(v ?? "").includes(%s)`, jsString(rules.Contains)), "must contain %s", rules.Contains)
+		}
+	case scalarType == "number" || isInt64Type(f.Type):
+		// 64-bit integers rendered as strings or bigints are compared as numbers
+		value := "Number(v ?? 0)"
+		if rules.Required {
+			check(value+" === 0", "is required")
+		}
+		if rules.Const != "" {
+			check(fmt.Sprintf("%s !== %s", value, rules.Const), "must be %s", rules.Const)
+		}
+		numericRangeChecks(rules, value, check)
+	default:
+		if rules.Required {
+			check("v === undefined || v === null", "is required")
+		}
+	}
+
+	return checks
+}
+
+// numericRangeChecks renders the checks of the bounds of a numeric field. a lower bound greater than the upper bound
+// excludes the range between them instead, e.g. gt: 10, lt: 0 only allows the values outside of [0, 10]
+func numericRangeChecks(rules *data.FieldRules, value string, check func(condition, format string, args ...interface{})) {
+	lower, lowerOperator, lowerMessage := rules.Gt, "<=", "greater than"
+	if rules.Gte != "" {
+		lower, lowerOperator, lowerMessage = rules.Gte, "<", "greater than or equal to"
+	}
+	upper, upperOperator, upperMessage := rules.Lt, ">=", "less than"
+	if rules.Lte != "" {
+		upper, upperOperator, upperMessage = rules.Lte, ">", "less than or equal to"
+	}
+
+	if lower != "" && upper != "" {
+		lowerValue, _ := strconv.ParseFloat(lower, 64)
+		upperValue, _ := strconv.ParseFloat(upper, 64)
+		if lowerValue > upperValue {
+			check(fmt.Sprintf("%s %s %s && %s %s %s", value, lowerOperator, lower, value, upperOperator, upper),
+				"must be %s %s or %s %s", lowerMessage, lower, upperMessage, upper)
+			return
+		}
+	}
+
+	if lower != "" {
+		check(fmt.Sprintf("%s %s %s", value, lowerOperator, lower), "must be %s %s", lowerMessage, lower)
+	}
+	if upper != "" {
+		check(fmt.Sprintf("%s %s %s", value, upperOperator, upper), "must be %s %s", upperMessage, upper)
+	}
+}
+
+// jsString renders the string as a javascript string literal, JSON strings are valid javascript strings
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	log "github.com/sirupsen/logrus" // nolint: depguard
+)
+
+// IsBundled returns whether the file will be rendered into the bundle file
+func (r *Registry) IsBundled(fileName string) bool {
+	return r.Bundle != "" && r.IsFileToGenerate(fileName)
+}
+
+// resolveBundleIdentifiers renames the enums, messages and services sharing the same name across packages
+// inside the bundle. All of them will be prefixed with their package name so that they are unique in the bundle
+func (r *Registry) resolveBundleIdentifiers(filesData map[string]*data.File) {
+	// group up fully qualified names by their identifiers
+	identifiers := make(map[string][]string)
+	for _, fileData := range filesData {
+		if !r.IsBundled(fileData.Name) {
+			continue
+		}
+
+		for _, e := range fileData.Enums {
+			identifiers[e.Name] = append(identifiers[e.Name], e.FQType)
+		}
+		for _, m := range fileData.Messages {
+			identifiers[m.Name] = append(identifiers[m.Name], m.FQType)
+		}
+		for _, s := range fileData.Services {
+			identifiers[s.Name] = append(identifiers[s.Name], s.FQType)
+		}
+	}
+
+	renamed := make(map[string]string)
+	for identifier, fqNames := range identifiers {
+		if len(fqNames) < 2 {
+			continue
+		}
+
+		for _, fqName := range fqNames {
+			typeInfo := r.Types[fqName]
+			prefix := data.GetPackagePrefix(typeInfo.Package)
+			if prefix == "" {
+				prefix = data.GetModuleName(typeInfo.Package, typeInfo.File)
+			}
+			log.Debugf("identifier %s of %s collides inside the bundle, renaming it to %s", identifier, fqName, prefix+identifier)
+			typeInfo.PackageIdentifier = prefix + identifier
+			renamed[fqName] = typeInfo.PackageIdentifier
+		}
+	}
+
+	for _, fileData := range filesData {
+		if !r.IsBundled(fileData.Name) {
+			continue
+		}
+
+		for _, e := range fileData.Enums {
+			if name, ok := renamed[e.FQType]; ok {
+				e.Name = name
+			}
+		}
+		for _, m := range fileData.Messages {
+			if name, ok := renamed[m.FQType]; ok {
+				m.Name = name
+			}
+		}
+		for _, s := range fileData.Services {
+			if name, ok := renamed[s.FQType]; ok {
+				s.Name = name
+			}
+		}
+	}
+}
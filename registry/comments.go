@@ -0,0 +1,88 @@
+package registry
+
+import (
+	"strconv"
+	"strings"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// field numbers inside descriptor protos, these make up the path of a location inside SourceCodeInfo
+// see https://github.com/protocolbuffers/protobuf/blob/master/src/google/protobuf/descriptor.proto
+const (
+	fileMessageTypePath   = 4
+	fileEnumTypePath      = 5
+	fileServicePath       = 6
+	fileExtensionPath     = 7
+	messageFieldPath      = 2
+	messageNestedTypePath = 3
+	messageEnumTypePath   = 4
+	messageExtensionPath  = 6
+	enumValuePath         = 2
+	serviceMethodPath     = 2
+)
+
+// sourceCodeInfo indexes the locations inside a file's SourceCodeInfo by their path
+type sourceCodeInfo map[string]*descriptorpb.SourceCodeInfo_Location
+
+func newSourceCodeInfo(f *descriptorpb.FileDescriptorProto) sourceCodeInfo {
+	info := make(sourceCodeInfo)
+	for _, l := range f.GetSourceCodeInfo().GetLocation() {
+		info[getSourceLocationKey(l.GetPath())] = l
+	}
+
+	return info
+}
+
+func getSourceLocationKey(path []int32) string {
+	parts := make([]string, 0, len(path))
+	for _, p := range path {
+		parts = append(parts, strconv.Itoa(int(p)))
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// appendPath returns a new path with elements appended, it never shares the underlying array with the parent path
+func appendPath(path []int32, elements ...int32) []int32 {
+	newPath := make([]int32, 0, len(path)+len(elements))
+	newPath = append(newPath, path...)
+	return append(newPath, elements...)
+}
+
+// getComments returns the leading and trailing comments of the entity located at the path inside the file.
+// the trailing comments will be appended after the leading comments separated by an empty line.
+// the content is kept as is in the proto and the reflowing is left to the rendering process
+func (r *Registry) getComments(fileName string, path []int32) string {
+	location, ok := r.sourceCodeInfo[fileName][getSourceLocationKey(path)]
+	if !ok {
+		return ""
+	}
+
+	comments := strings.TrimRight(location.GetLeadingComments(), "\n")
+	trailing := strings.TrimRight(location.GetTrailingComments(), "\n")
+	if strings.TrimSpace(trailing) != "" {
+		if strings.TrimSpace(comments) != "" {
+			comments += "\n\n"
+		}
+		comments += trailing
+	}
+
+	return comments
+}
+
+// getSourceLocation returns the proto file and the line the entity located at the path is declared at, e.g. foo.proto:42.
+// an empty string is returned when source locations are not emitted or the file comes without SourceCodeInfo
+func (r *Registry) getSourceLocation(fileName string, path []int32) string {
+	if !r.EmitSourceLocations {
+		return ""
+	}
+
+	location, ok := r.sourceCodeInfo[fileName][getSourceLocationKey(path)]
+	if !ok || len(location.GetSpan()) == 0 {
+		return ""
+	}
+
+	// lines of the span start from 0
+	return fileName + ":" + strconv.Itoa(int(location.GetSpan()[0])+1)
+}
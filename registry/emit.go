@@ -0,0 +1,34 @@
+package registry
+
+import (
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+	log "github.com/sirupsen/logrus" // nolint: depguard
+)
+
+// removeTypes leaves only the services in the files to generate when Emit is services.
+// the enums and the messages are generated by the types-only output, so the requests and the responses of the methods
+// are imported from it, including the ones of the file itself
+func (r *Registry) removeTypes(filesData map[string]*data.File) {
+	for _, fileData := range filesData {
+		if !r.IsFileToGenerate(fileData.Name) {
+			continue
+		}
+
+		log.Debugf("removing the types of %s, they are imported from %s", fileData.Name, r.TypesDir)
+		fileData.Enums = make([]*data.Enum, 0)
+		fileData.Messages = make([]*data.Message, 0)
+		fileData.ExternalDependingTypes = make([]string, 0)
+		for _, s := range fileData.Services {
+			for _, m := range s.Methods {
+				for _, arg := range []*data.MethodArgument{m.Input, m.Output} {
+					if arg.IsEmpty {
+						continue
+					}
+
+					arg.IsExternal = true
+					fileData.ExternalDependingTypes = append(fileData.ExternalDependingTypes, arg.Type)
+				}
+			}
+		}
+	}
+}
@@ -2,30 +2,45 @@ package registry
 
 import (
 	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pkg/errors"
 
 	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
 )
 
-func (r *Registry) analyseEnumType(fileData *data.File, packageName, fileName string, parents []string, enum *descriptorpb.EnumDescriptorProto) {
+func (r *Registry) analyseEnumType(fileData *data.File, packageName, fileName string, parents []string, path []int32, enum *descriptorpb.EnumDescriptorProto) error {
 	packageIdentifier := r.getNameOfPackageLevelIdentifier(parents, enum.GetName())
 	fqName := r.getFullQualifiedName(packageName, parents, enum.GetName())
 	protoType := descriptorpb.FieldDescriptorProto_TYPE_ENUM
-	r.Types[fqName] = &TypeInformation{
+	comment := r.getComments(fileName, path)
+	typeInfo := &TypeInformation{
 		FullyQualifiedName: fqName,
 		Package:            packageName,
 		File:               fileName,
 		PackageIdentifier:  packageIdentifier,
 		LocalIdentifier:    enum.GetName(),
 		ProtoType:          protoType,
+		Comment:            comment,
+	}
+	if err := r.registerType(typeInfo); err != nil {
+		return errors.WithStack(err)
 	}
 
 	enumData := data.NewEnum()
 	enumData.Name = packageIdentifier
+	enumData.FQType = fqName
+	enumData.Comment = comment
+	enumData.SourceLocation = r.getSourceLocation(fileName, path)
 
-	for _, e := range enum.GetValue() {
-		enumData.Values = append(enumData.Values, e.GetName())
+	for i, e := range enum.GetValue() {
+		typeInfo.EnumValues = append(typeInfo.EnumValues, e.GetName())
+		enumData.Values = append(enumData.Values, &data.EnumValue{
+			Name:    e.GetName(),
+			Number:  e.GetNumber(),
+			Comment: r.getComments(fileName, appendPath(path, enumValuePath, int32(i))),
+		})
 	}
 
 	fileData.Enums = append(fileData.Enums, enumData)
 
+	return nil
 }
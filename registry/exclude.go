@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// getExcludeInformation returns the glob patterns of ts_exclude, along with the regular expressions they are matched with
+func getExcludeInformation(paramsMap map[string]string) ([]string, []*regexp.Regexp, error) {
+	patterns := make([]string, 0)
+	regexps := make([]*regexp.Regexp, 0)
+	for _, pattern := range strings.Split(paramsMap[TSExclude], TSImportRootSeparator) {
+		if pattern == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(globToRegexp(pattern))
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid pattern %s in %s", pattern, TSExclude)
+		}
+
+		patterns = append(patterns, pattern)
+		regexps = append(regexps, re)
+	}
+
+	return patterns, regexps, nil
+}
+
+// globToRegexp converts the glob pattern into a regular expression matching the whole file name. * matches any characters
+// but /, ? matches a single character but /, and ** matches any characters across directories, e.g. third_party/**/*.proto
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+
+	return b.String()
+}
+
+// isExcluded returns whether the proto file matches any of the patterns of ts_exclude
+func (r *Registry) isExcluded(fileName string) bool {
+	for _, re := range r.excludeRegexps {
+		if re.MatchString(fileName) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"strings"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	log "github.com/sirupsen/logrus" // nolint: depguard
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+)
+
+// extension is an extension field declared in a file or nested inside a message,
+// it's rendered on the extended message after all files are analysed, as the extended message might be in any of them
+type extension struct {
+	fileName    string
+	packageName string
+	parents     []string
+	path        []int32
+	field       *descriptorpb.FieldDescriptorProto
+}
+
+// analyseExtensions adds the extension fields to the messages they extend.
+// the fields are keyed by their fully qualified names in brackets, which is how protojson encodes extensions
+func (r *Registry) analyseExtensions(filesData map[string]*data.File) {
+	for _, ext := range r.extensions {
+		name := strings.TrimPrefix(r.getFullQualifiedName(ext.packageName, ext.parents, ext.field.GetName()), ".")
+		extendee := ext.field.GetExtendee()
+		typeInfo, ok := r.Types[extendee]
+		if !ok {
+			log.Warnf("extension %s declared in %s is dropped, the extended message %s is not found", name, ext.fileName, extendee)
+			continue
+		}
+
+		if !r.IsFileToGenerate(typeInfo.File) {
+			log.Debugf("extension %s of %s is not rendered, %s is not generated", name, extendee, typeInfo.File)
+			continue
+		}
+
+		fileData := filesData[typeInfo.File]
+		msgData := fileData.FindMessage(extendee)
+		if msgData == nil {
+			log.Warnf("extension %s declared in %s is dropped, the extended message %s is not rendered in %s", name, ext.fileName, extendee, typeInfo.File)
+			continue
+		}
+
+		fieldData := r.analyseField(fileData, msgData, typeInfo.Package, ext.path, ext.field)
+		fieldData.Name = "[" + name + "]"
+		fieldData.JSONName = ""
+		fieldData.IsExtension = true
+		fieldData.Comment = r.getComments(ext.fileName, ext.path)
+		typeInfo.Fields = msgData.Fields
+		log.Debugf("extension %s declared in %s is added to %s", name, ext.fileName, extendee)
+	}
+}
@@ -1,7 +1,12 @@
 package registry
 
 import (
+	"strings"
+
 	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/iancoleman/strcase"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus" // nolint: depguard
 
 	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
 )
@@ -12,7 +17,11 @@ func (r *Registry) getFieldType(f *descriptorpb.FieldDescriptorProto) string {
 	if f.Type != nil {
 		switch *f.Type {
 		case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
-			typeName = f.GetTypeName()
+			if wktType, ok := r.getWellKnownType(f.GetTypeName()); ok {
+				typeName = wktType
+			} else {
+				typeName = f.GetTypeName()
+			}
 		case descriptorpb.FieldDescriptorProto_TYPE_STRING:
 			typeName = "string"
 		case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
@@ -49,21 +58,36 @@ func (r *Registry) getFieldType(f *descriptorpb.FieldDescriptorProto) string {
 	return typeName
 }
 
-func (r *Registry) analyseField(fileData *data.File, msgData *data.Message, packageName string, f *descriptorpb.FieldDescriptorProto) {
+func (r *Registry) analyseField(fileData *data.File, msgData *data.Message, packageName string, path []int32, f *descriptorpb.FieldDescriptorProto) *data.Field {
 	fqTypeName := r.getFieldType(f)
 
 	isExternal := r.isExternalDependenciesOutsidePackage(fqTypeName, packageName)
 
 	fieldData := &data.Field{
 		Name:         f.GetName(),
+		JSONName:     f.GetJsonName(),
 		Type:         fqTypeName,
 		IsExternal:   isExternal,
-		IsOneOfField: f.OneofIndex != nil,
+		IsOneOfField: f.OneofIndex != nil && !f.GetProto3Optional(),
+		IsOptional:   f.GetProto3Optional(),
+		IsRequired:   f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED,
+		IsDeprecated: f.GetOptions().GetDeprecated(),
+		IsMessage:    f.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
 		Message:      msgData,
+		Comment:      r.getComments(fileData.Name, path),
+	}
+
+	if r.EmitValidators {
+		rules, err := getFieldRules(f)
+		if err != nil {
+			log.Warnf("constraints of field %s of %s are ignored: %v", f.GetName(), msgData.FQType, err)
+		}
+		fieldData.Rules = rules
 	}
 
 	if f.Label != nil {
-		if f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+		// map fields are repeated map entries in the descriptor, they are rendered as maps rather than arrays
+		if f.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED && !r.isMapEntry(fqTypeName) {
 			fieldData.IsRepeated = true
 		}
 	}
@@ -91,4 +115,73 @@ func (r *Registry) analyseField(fileData *data.File, msgData *data.Message, pack
 	}
 
 	fileData.TrackPackageNonScalarType(fieldData)
+
+	return fieldData
+}
+
+// isMapEntry returns whether the type is the map entry type generated for a map field.
+// map entries are nested inside the message of the field, so they are always analysed before the field
+func (r *Registry) isMapEntry(fqTypeName string) bool {
+	typeInfo, ok := r.Types[fqTypeName]
+	return ok && typeInfo.IsMapEntry
+}
+
+// FieldName returns the name of the field in the case configured by ts_field_case.
+// for camel case, the json name defined in the descriptor takes precedence over the default lowerCamelCase conversion
+func (r *Registry) FieldName(name, jsonName string) string {
+	switch r.FieldCase {
+	case FieldCaseOriginal:
+		return name
+	case FieldCaseSnake:
+		return strcase.ToSnake(name)
+	}
+
+	if len(r.FieldAcronyms) > 0 && (jsonName == "" || jsonName == strcase.ToLowerCamel(name)) {
+		// custom json names are kept as they are, only the default conversion preserves the acronyms
+		return r.lowerCamelWithAcronyms(name)
+	}
+
+	if jsonName != "" {
+		return jsonName
+	}
+
+	return strcase.ToLowerCamel(name)
+}
+
+// lowerCamelWithAcronyms converts the snake_case name into lowerCamelCase with the words in FieldAcronyms upper cased,
+// e.g. userID for user_id. the first word stays lower case, e.g. idToken for id_token
+func (r *Registry) lowerCamelWithAcronyms(name string) string {
+	words := strings.Split(name, "_")
+	var b strings.Builder
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+
+		switch {
+		case b.Len() == 0:
+			b.WriteString(strcase.ToLowerCamel(w))
+		case r.FieldAcronyms[strings.ToLower(w)]:
+			b.WriteString(strings.ToUpper(w))
+		default:
+			b.WriteString(strcase.ToCamel(w))
+		}
+	}
+
+	return b.String()
+}
+
+// checkFieldNameCollisions returns an error when two fields of the message are rendered with the same property name,
+// e.g. foo_bar and fooBar in camel case, or fields with the same json_name, which would be duplicate keys in typescript
+func (r *Registry) checkFieldNameCollisions(fqName string, fields []*descriptorpb.FieldDescriptorProto) error {
+	renderedBy := make(map[string]string, len(fields))
+	for _, f := range fields {
+		name := r.FieldName(f.GetName(), f.GetJsonName())
+		if existing, ok := renderedBy[name]; ok {
+			return errors.Errorf("fields %s and %s of %s are both rendered as %s", existing, f.GetName(), fqName, name)
+		}
+		renderedBy[name] = f.GetName()
+	}
+
+	return nil
 }
@@ -1,6 +1,8 @@
 package registry
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"path/filepath"
 	"strings"
 
@@ -19,24 +21,71 @@ func (r *Registry) analyseFile(f *descriptorpb.FileDescriptorProto) (*data.File,
 	packageName := f.GetPackage()
 	parents := make([]string, 0)
 	fileData.Name = fileName
-	fileData.TSFileName = data.GetTSFileName(fileName)
+	r.filePackages[fileName] = packageName
+	fileData.TSFileName = r.getTSFileName(fileName)
+	if r.IsBundled(fileName) {
+		// imports of bundled files are resolved relatively to the bundle
+		fileData.TSFileName = r.Bundle
+	}
 	if proto.HasExtension(f.Options, options.E_TsPackage) {
 		r.TSPackages[fileData.TSFileName] = proto.GetExtension(f.Options, options.E_TsPackage).(string)
 	}
+	r.sourceCodeInfo[fileName] = newSourceCodeInfo(f)
+
+	if r.EmitSourceHash {
+		hash, err := getSourceHash(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error hashing %s", fileName)
+		}
+		fileData.SourceHash = hash
+	}
 
 	// analyse enums
-	for _, enum := range f.EnumType {
-		r.analyseEnumType(fileData, packageName, fileName, parents, enum)
+	for i, enum := range f.EnumType {
+		if err := r.analyseEnumType(fileData, packageName, fileName, parents, []int32{fileEnumTypePath, int32(i)}, enum); err != nil {
+			return nil, errors.Wrapf(err, "error analysing enum %s", enum.GetName())
+		}
 	}
 
 	// analyse messages, each message will go recursively
-	for _, message := range f.MessageType {
-		r.analyseMessage(fileData, packageName, fileName, parents, message)
+	for i, message := range f.MessageType {
+		if err := r.analyseMessage(fileData, packageName, fileName, parents, []int32{fileMessageTypePath, int32(i)}, message); err != nil {
+			return nil, errors.Wrapf(err, "error analysing message %s", message.GetName())
+		}
+	}
+
+	r.resolveNestedIdentifierCollisions(fileData, packageName)
+
+	// extensions declared in the file are rendered on the messages they extend
+	for i, ext := range f.Extension {
+		r.extensions = append(r.extensions, &extension{
+			fileName:    fileName,
+			packageName: packageName,
+			parents:     parents,
+			path:        []int32{fileExtensionPath, int32(i)},
+			field:       ext,
+		})
 	}
 
-	// analyse services
-	for _, service := range f.Service {
-		r.analyseService(fileData, packageName, fileName, service)
+	// analyse services, they are left out of the types-only output
+	for i, service := range f.Service {
+		if r.Emit == EmitTypes {
+			break
+		}
+		if r.EmitsDeclarations() && r.IsFileToGenerate(fileName) {
+			return nil, errors.Errorf("service %s can't be generated into declaration files with %s %s, only the types are generated with %s %s",
+				service.GetName(), TSFileExtension, r.TSFileExtension, TSEmit, EmitTypes)
+		}
+
+		serviceData := fileData
+		if r.EmitServiceFiles {
+			serviceData = newServiceFile(fileData, service.GetName())
+			fileData.ServiceFiles = append(fileData.ServiceFiles, serviceData)
+		}
+
+		if err := r.analyseService(serviceData, packageName, fileName, []int32{fileServicePath, int32(i)}, service); err != nil {
+			return nil, errors.Wrapf(err, "error analysing service %s", service.GetName())
+		}
 	}
 
 	// add fetch module after analysed all services in the file. will add dependencies if there is any
@@ -45,6 +94,13 @@ func (r *Registry) analyseFile(f *descriptorpb.FileDescriptorProto) (*data.File,
 		return nil, errors.Wrapf(err, "error adding fetch module for file %s", fileData.Name)
 	}
 
+	for _, serviceFile := range fileData.ServiceFiles {
+		if err := r.addFetchModuleDependencies(serviceFile); err != nil {
+			return nil, errors.Wrapf(err, "error adding fetch module for file %s", serviceFile.TSFileName)
+		}
+		analyseServiceFileTypeDependencies(serviceFile)
+	}
+
 	r.analyseFilePackageTypeDependencies(fileData)
 
 	return fileData, nil
@@ -56,7 +112,7 @@ func (r *Registry) addFetchModuleDependencies(fileData *data.File) error {
 		return nil
 	}
 
-	absDir, err := filepath.Abs(r.FetchModuleDirectory)
+	absDir, err := filepath.Abs(r.getOutputPath(r.FetchModuleDirectory))
 	if err != nil {
 		return errors.Wrapf(err, "error looking up absolute path for fetch module directory %s", r.FetchModuleDirectory)
 	}
@@ -69,9 +125,10 @@ func (r *Registry) addFetchModuleDependencies(fileData *data.File) error {
 		return errors.Wrapf(err, "error looking up root alias for fetch module directory %s", r.FetchModuleDirectory)
 	}
 
-	fileName := filepath.Join(r.FetchModuleDirectory, r.FetchModuleFilename)
+	fileName := r.getOutputPath(filepath.Join(r.FetchModuleDirectory, r.FetchModuleFilename))
 
-	sourceFile, err := r.getSourceFileForImport(fileData.TSFileName, fileName, foundAtRoot, alias)
+	basePath, _ := r.getImportPath(fileData.Name, fileData.TSFileName)
+	sourceFile, err := r.getSourceFileForImport(r.getOutputPath(basePath), fileName, foundAtRoot, alias)
 	if err != nil {
 		return errors.Wrapf(err, "error replacing source file with alias for %s", fileName)
 	}
@@ -108,3 +165,72 @@ func (r *Registry) analyseFilePackageTypeDependencies(fileData *data.File) {
 		}
 	}
 }
+
+// newServiceFile returns the file the service is generated into when each service is generated into its own file, next to
+// the generated file of the proto file
+func newServiceFile(fileData *data.File, serviceName string) *data.File {
+	serviceFile := data.NewFile()
+	serviceFile.Name = fileData.Name
+	serviceFile.TSFileName = data.GetServiceTSFileName(fileData.TSFileName, serviceName)
+	serviceFile.SourceHash = fileData.SourceHash
+	serviceFile.IsServiceFile = true
+	return serviceFile
+}
+
+// analyseServiceFileTypeDependencies makes the requests and the responses of the service external to the file the service
+// is generated into, including the ones of the proto file itself, which are imported from its generated file
+func analyseServiceFileTypeDependencies(serviceFile *data.File) {
+	for _, t := range serviceFile.PackageNonScalarType {
+		serviceFile.ExternalDependingTypes = append(serviceFile.ExternalDependingTypes, t.GetType().Type)
+		t.SetExternal(true)
+	}
+}
+
+// resolveNestedIdentifierCollisions renames the nested enums and messages whose concatenated package level identifiers
+// collide inside the file, e.g. A.BC and AB.C are both ABC. Each of them will be joined by an underscore instead,
+// which is A_BC and AB_C. Top level types always keep their names
+func (r *Registry) resolveNestedIdentifierCollisions(fileData *data.File, packageName string) {
+	identifiers := make(map[string]int)
+	for _, e := range fileData.Enums {
+		identifiers[e.Name]++
+	}
+	for _, m := range fileData.Messages {
+		identifiers[m.Name]++
+	}
+
+	rename := func(name, fqType string) string {
+		if identifiers[name] < 2 {
+			return name
+		}
+
+		parents := strings.Split(strings.TrimPrefix(fqType, r.getFullQualifiedName(packageName, nil, "")), ".")
+		if len(parents) < 2 {
+			return name
+		}
+
+		newName := strings.Join(parents, "_")
+		log.Debugf("identifier %s of %s collides with other types in %s, renaming it to %s", name, fqType, fileData.Name, newName)
+		r.Types[fqType].PackageIdentifier = newName
+		return newName
+	}
+
+	for _, e := range fileData.Enums {
+		e.Name = rename(e.Name, e.FQType)
+	}
+	for _, m := range fileData.Messages {
+		m.Name = rename(m.Name, m.FQType)
+	}
+}
+
+// getSourceHash returns the hex encoded sha256 hash of the descriptor of the proto file. protoc doesn't send the content of the
+// proto file, the descriptor is marshalled deterministically instead, which includes the comments and the source locations
+// along with the declarations, so the hash changes whenever anything in the proto file does
+func getSourceHash(f *descriptorpb.FileDescriptorProto) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(f)
+	if err != nil {
+		return "", errors.Wrap(err, "error marshalling file descriptor")
+	}
+
+	hash := sha256.Sum256(b)
+	return hex.EncodeToString(hash[:]), nil
+}
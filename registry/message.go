@@ -2,11 +2,12 @@ package registry
 
 import (
 	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pkg/errors"
 
 	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
 )
 
-func (r *Registry) analyseMessage(fileData *data.File, packageName, fileName string, parents []string, message *descriptorpb.DescriptorProto) {
+func (r *Registry) analyseMessage(fileData *data.File, packageName, fileName string, parents []string, path []int32, message *descriptorpb.DescriptorProto) error {
 	packageIdentifier := r.getNameOfPackageLevelIdentifier(parents, message.GetName())
 
 	fqName := r.getFullQualifiedName(packageName, parents, message.GetName()) // "." + packageName + "." + parentsPrefix + message.GetName()
@@ -19,10 +20,13 @@ func (r *Registry) analyseMessage(fileData *data.File, packageName, fileName str
 		PackageIdentifier:  packageIdentifier,
 		LocalIdentifier:    message.GetName(),
 		ProtoType:          protoType,
+		Comment:            r.getComments(fileName, path),
 	}
 
 	// register itself in the registry map
-	r.Types[fqName] = typeInfo
+	if err := r.registerType(typeInfo); err != nil {
+		return errors.WithStack(err)
+	}
 
 	if message.Options != nil {
 		if message.GetOptions().GetMapEntry() {
@@ -32,6 +36,9 @@ func (r *Registry) analyseMessage(fileData *data.File, packageName, fileName str
 			for _, f := range message.Field {
 				switch f.GetName() {
 				case "key":
+					if err := checkMapKeyType(fqName, f); err != nil {
+						return errors.WithStack(err)
+					}
 					typeInfo.KeyType = &data.MapEntryType{
 						Type:       r.getFieldType(f),
 						IsExternal: r.isExternalDependenciesOutsidePackage(f.GetTypeName(), packageName),
@@ -44,10 +51,13 @@ func (r *Registry) analyseMessage(fileData *data.File, packageName, fileName str
 				}
 
 			}
+			if typeInfo.KeyType == nil || typeInfo.ValueType == nil {
+				return errors.Errorf("map entry %s is missing its key or value field", fqName)
+			}
 			fileData.TrackPackageNonScalarType(typeInfo.KeyType)
 			fileData.TrackPackageNonScalarType(typeInfo.ValueType)
 			// no need to add a map type into
-			return
+			return nil
 
 		}
 	}
@@ -55,28 +65,88 @@ func (r *Registry) analyseMessage(fileData *data.File, packageName, fileName str
 	data := data.NewMessage()
 	data.Name = packageIdentifier
 	data.FQType = fqName
+	data.Comment = typeInfo.Comment
+	data.SourceLocation = r.getSourceLocation(fileName, path)
+	data.IsDeprecated = message.GetOptions().GetDeprecated()
 
 	newParents := append(parents, message.GetName())
 
 	// handle enums, by pulling the enums out to the top level
-	for _, enum := range message.EnumType {
-		r.analyseEnumType(fileData, packageName, fileName, newParents, enum)
+	for i, enum := range message.EnumType {
+		if err := r.analyseEnumType(fileData, packageName, fileName, newParents, appendPath(path, messageEnumTypePath, int32(i)), enum); err != nil {
+			return errors.WithStack(err)
+		}
 	}
 
 	// nested type also got pull out to the top level of the file
-	for _, msg := range message.NestedType {
-		r.analyseMessage(fileData, packageName, fileName, newParents, msg)
+	for i, msg := range message.NestedType {
+		if err := r.analyseMessage(fileData, packageName, fileName, newParents, appendPath(path, messageNestedTypePath, int32(i)), msg); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// proto3 optional fields are wrapped in synthetic one ofs, which are not real one of groups
+	syntheticOneOfs := make(map[int32]bool)
+	for _, f := range message.Field {
+		if f.GetProto3Optional() {
+			syntheticOneOfs[f.GetOneofIndex()] = true
+		}
 	}
 
 	// store a map of one of names
 	for idx, oneOf := range message.GetOneofDecl() {
+		if syntheticOneOfs[int32(idx)] {
+			continue
+		}
 		data.OneOfFieldsNames[int32(idx)] = oneOf.GetName()
 	}
 
-	// analyse fields in the messages
-	for _, f := range message.Field {
-		r.analyseField(fileData, data, packageName, f)
+	// analyse fields in the messages, reserved numbers and names are only declared in reserved_range and reserved_name
+	// of the descriptor, never as fields, so they are left out along with the rest of the declarations
+	if err := r.checkFieldNameCollisions(fqName, message.Field); err != nil {
+		return errors.WithStack(err)
+	}
+
+	typeInfo.FieldJSONNames = make(map[string]string)
+	for i, f := range message.Field {
+		r.analyseField(fileData, data, packageName, appendPath(path, messageFieldPath, int32(i)), f)
+		typeInfo.FieldJSONNames[f.GetName()] = f.GetJsonName()
+	}
+
+	typeInfo.Fields = data.Fields
+
+	// extensions declared inside the message are rendered on the messages they extend
+	for i, f := range message.Extension {
+		r.extensions = append(r.extensions, &extension{
+			fileName:    fileName,
+			packageName: packageName,
+			parents:     append([]string{}, newParents...),
+			path:        appendPath(path, messageExtensionPath, int32(i)),
+			field:       f,
+		})
 	}
 
 	fileData.Messages = append(fileData.Messages, data)
+
+	return nil
+}
+
+// checkMapKeyType makes sure the key of the map entry is one of the scalar types protobuf allows for keys, any integral
+// type, bool or string, along with enums which are rendered as mapped types. protoc rejects the other ones, so a different
+// key type comes from a malformed descriptor
+func checkMapKeyType(fqName string, key *descriptorpb.FieldDescriptorProto) error {
+	switch key.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+		descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		keyType := key.GetType().String()
+		if key.GetTypeName() != "" {
+			keyType += " " + key.GetTypeName()
+		}
+		return errors.Errorf("map entry %s has a key of type %s, map keys can only be integral types, bool, string or enums", fqName, keyType)
+	}
+
+	return nil
 }
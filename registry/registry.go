@@ -4,6 +4,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
@@ -18,14 +21,245 @@ const (
 	TSImportRootParamsKey = "ts_import_roots"
 	// TSImportRootAliasParamsKey contains the key for common_import_root_alias in parameters
 	TSImportRootAliasParamsKey = "ts_import_root_aliases"
-	// TSImportRootSeparator separates the ts import root inside ts_import_roots & ts_import_root_aliases
+	// TSImportRootAliasMapParamsKey contains the key for the root=alias pairs in parameters
+	TSImportRootAliasMapParamsKey = "ts_import_root_alias_map"
+	// TSImportRootSeparator separates the ts import root inside ts_import_roots, ts_import_root_aliases & ts_import_root_alias_map
 	TSImportRootSeparator = ";"
+	// TSImportRootAliasMapSeparator separates the root and the alias inside a pair of ts_import_root_alias_map
+	TSImportRootAliasMapSeparator = "="
+	// TSImportRootMarkerParamsKey contains the key for the file names marking the import root in parameters, separated by TSImportRootSeparator
+	TSImportRootMarkerParamsKey = "ts_import_root_marker"
+	// TSPackageMapParamsKey contains the key for the package=dir pairs in parameters, the pairs are separated by TSImportRootSeparator
+	TSPackageMapParamsKey = "ts_package_map"
+	// TSPackageMapSeparator separates the package and the directory inside a pair of ts_package_map
+	TSPackageMapSeparator = "="
+	// TSConfig is the parameter for the tsconfig.json whose path aliases are used for the imports instead of relative paths
+	TSConfig = "ts_tsconfig"
+	// TSExclude is the parameter for the glob patterns of the proto files to leave out of the generation, separated by TSImportRootSeparator
+	TSExclude = "ts_exclude"
+	// TSPackageName is the parameter for the name of the package the generated files are published in, whose subpaths are imported
+	// instead of relative paths
+	TSPackageName = "ts_package_name"
+	// FileMappingPrefix prefixes the parameters mapping a proto file to the module its generated file is imported from,
+	// like the M options of protoc-gen-go, e.g. Mfoo/bar.proto=@company/bar
+	FileMappingPrefix = "M"
+	// TSPackageDirs is the parameter to place the generated files into nested directories following the dotted proto package names
+	TSPackageDirs = "ts_package_dirs"
 	// FetchModuleDirectory is the parameter for directory where fetch module will live
 	FetchModuleDirectory = "fetch_module_directory"
 	// FetchModuleFileName is the file name for the individual fetch module
 	FetchModuleFileName = "fetch_module_filename"
 	// UseProtoNames will make the generator to generate field name the same as defined in the proto
 	UseProtoNames = "use_proto_names"
+	// TSInt64Type is the parameter for the typescript type 64-bit integer fields will be rendered as
+	TSInt64Type = "ts_int64_type"
+	// TSEnumStyle is the parameter for how enums will be rendered
+	TSEnumStyle = "ts_enum_style"
+	// TSEnumUnknown is the parameter for how the enum helpers handle values unknown to the client
+	TSEnumUnknown = "ts_enum_unknown"
+	// TSFileExtension is the parameter for the extension of the generated files
+	TSFileExtension = "ts_file_extension"
+	// TSBundle is the parameter for the file name all files to generate will be bundled into
+	TSBundle = "ts_bundle"
+	// TSWellKnownTypeMapping is the parameter to enable rendering well-known types as their JSON representation
+	TSWellKnownTypeMapping = "ts_wkt_mapping"
+	// TSTimestampType is the parameter for the typescript type google.protobuf.Timestamp will be rendered as
+	TSTimestampType = "ts_timestamp_type"
+	// TSFieldMaskType is the parameter for the typescript type google.protobuf.FieldMask will be rendered as
+	TSFieldMaskType = "ts_field_mask_type"
+	// TSTypedArrays is the parameter to render repeated float and double fields as typed arrays
+	TSTypedArrays = "ts_typed_arrays"
+	// TSIndent is the parameter for the indentation of the generated code, either tab or a number of spaces
+	TSIndent = "ts_indent"
+	// TSQuoteStyle is the parameter for the quotes of the strings in the generated code
+	TSQuoteStyle = "ts_quote_style"
+	// TSTarget is the parameter for the version of the typescript compiler the generated code is compiled with
+	TSTarget = "ts_target"
+	// TSOptionalStyle is the parameter for how unset message fields and proto3 optional fields are typed
+	TSOptionalStyle = "ts_optional_style"
+	// TSFieldCase is the parameter for the case of the rendered field names
+	TSFieldCase = "ts_field_case"
+	// TSFieldAcronyms is the parameter for the words upper cased in the camelCase field names, separated by TSImportRootSeparator
+	TSFieldAcronyms = "ts_field_acronyms"
+	// TSDryRun is the parameter to write a JSON summary of the generation instead of the generated files
+	TSDryRun = "ts_dry_run"
+	// TSVerbose is the parameter to write a summary of the generation to stderr once it's finished
+	TSVerbose = "ts_verbose"
+	// TSTemplateDir is the parameter for the directory of the templates overriding the built-in ones
+	TSTemplateDir = "ts_template_dir"
+	// TSSkipEmpty is the parameter to skip the files without any enums, messages or services
+	TSSkipEmpty = "ts_skip_empty"
+	// TSModuleSystem is the parameter for the module system of the import and export statements in the generated files
+	TSModuleSystem = "ts_module_system"
+	// TSMessageKind is the parameter for the kind of declarations messages will be rendered as
+	TSMessageKind = "ts_message_kind"
+	// TSClientStyle is the parameter for the kind of clients generated for the services
+	TSClientStyle = "ts_client_style"
+	// TSMethodNames is the parameter for what the methods of the clients are named after, the rpc names or the operation ids
+	TSMethodNames = "ts_method_names"
+	// TSEmit is the parameter for what is generated out of the files, the types, the services or both of them
+	TSEmit = "ts_emit"
+	// TSTypesDir is the parameter for the directory of the types-only output the services import the types from
+	TSTypesDir = "ts_types_dir"
+	// TSBytesType is the parameter for the typescript type bytes fields will be rendered as
+	TSBytesType = "ts_bytes_type"
+	// TSEnumNamespaces is the parameter to generate namespaces re-exporting the enums nested in messages following the nesting in the proto file
+	TSEnumNamespaces = "ts_enum_namespaces"
+	// TSEmitGuards is the parameter to generate type guards for messages
+	TSEmitGuards = "ts_emit_guards"
+	// TSEmitEquals is the parameter to generate a function comparing two messages for each message
+	TSEmitEquals = "ts_emit_equals"
+	// TSEmitValidators is the parameter to generate a function checking the protoc-gen-validate or buf.validate constraints of the messages
+	TSEmitValidators = "ts_emit_validators"
+	// TSEmitFactories is the parameter to generate a factory returning the default value for each message
+	TSEmitFactories = "ts_emit_factories"
+	// TSWSBidi is the parameter to generate clients sending bidirectional streaming calls over WebSockets
+	TSWSBidi = "ts_ws_bidi"
+	// TSEmitSourceLocations is the parameter to generate a comment with the proto file and the line above each enum, message and service
+	TSEmitSourceLocations = "ts_emit_source_locations"
+	// TSEmitSourceHash is the parameter to generate a header with the hash of the proto file at the top of each generated file
+	TSEmitSourceHash = "ts_emit_source_hash"
+	// TSEmitMocks is the parameter to generate builders of messages populated with dummy values into a separate file for tests
+	TSEmitMocks = "ts_emit_mocks"
+	// TSEmitReactQuery is the parameter to generate React Query hooks for the methods of the services into a separate file
+	TSEmitReactQuery = "ts_emit_react_query"
+	// TSEmitServiceFiles is the parameter to generate each service into its own file importing the types of its proto file
+	TSEmitServiceFiles = "ts_emit_service_files"
+	// TSAPIObject is the parameter to generate a single object holding the clients of all the services of a file
+	TSAPIObject = "ts_api_object"
+	// TSReadonly is the parameter to render the properties of messages as readonly
+	TSReadonly = "ts_readonly"
+	// OutputDir is the parameter for the directory protoc writes the generated files into
+	OutputDir = "output_dir"
+	// TSEmitBarrels is the parameter to generate an index file re-exporting the generated files in each directory
+	TSEmitBarrels = "ts_emit_barrels"
+	// TSEmitRollupDTS is the parameter to generate a declaration file aggregating the types of all the generated files
+	TSEmitRollupDTS = "ts_emit_rollup_dts"
+	// Strict is the parameter to turn ambiguities and unresolved dependencies found during the generation into errors
+	Strict = "strict"
+)
+
+const (
+	// Int64TypeString renders 64-bit integers as string, which is how they are encoded in JSON
+	Int64TypeString = "string"
+	// Int64TypeNumber renders 64-bit integers as number, precision will be lost for values beyond 2^53
+	Int64TypeNumber = "number"
+	// Int64TypeBigInt renders 64-bit integers as native bigint
+	Int64TypeBigInt = "bigint"
+)
+
+// DeclarationFileExtension is the extension of TypeScript declaration files, which can't contain implementations
+const DeclarationFileExtension = ".d.ts"
+
+const (
+	// EnumStyleEnum renders enums as typescript enums with string values
+	EnumStyleEnum = "enum"
+	// EnumStyleStringUnion renders enums as union of string literal types
+	EnumStyleStringUnion = "string_union"
+	// EnumStyleConstEnum renders enums as typescript const enums with string values
+	EnumStyleConstEnum = "const_enum"
+)
+
+const (
+	// EnumUnknownFallback converts unknown values into the default value of the enum
+	EnumUnknownFallback = "fallback"
+	// EnumUnknownPreserve keeps unknown values as the names or the numbers they are received as
+	EnumUnknownPreserve = "preserve"
+	// EnumUnknownThrow throws an error for unknown values
+	EnumUnknownThrow = "throw"
+)
+
+const (
+	// TimestampTypeString renders timestamps as RFC 3339 strings, which is how they are encoded in JSON
+	TimestampTypeString = "string"
+	// TimestampTypeDate renders timestamps as Date
+	TimestampTypeDate = "Date"
+)
+
+const (
+	// FieldMaskTypeString renders field masks as the comma-joined camelCase paths, which is how they are encoded in JSON
+	FieldMaskTypeString = "string"
+	// FieldMaskTypeArray renders field masks as arrays of the paths, converted from and into the JSON string by the message classes
+	FieldMaskTypeArray = "array"
+)
+
+const (
+	// IndentTab indents the generated code with tabs
+	IndentTab = "tab"
+	// DefaultIndent is the indentation the templates are written with
+	DefaultIndent = "  "
+	// DefaultTarget is the typescript version the generated code is compatible with when ts_target isn't set
+	DefaultTarget = "4.0"
+)
+
+const (
+	// QuoteStyleDouble quotes the strings with double quotes, which is how the templates are written
+	QuoteStyleDouble = "double"
+	// QuoteStyleSingle quotes the strings with single quotes
+	QuoteStyleSingle = "single"
+)
+
+const (
+	// OptionalStyleUndefined types unset fields as undefined, which is how they are absent in the JSON of grpc-gateway
+	OptionalStyleUndefined = "undefined"
+	// OptionalStyleNull types unset fields as null
+	OptionalStyleNull = "null"
+	// OptionalStyleBoth types unset fields as either null or undefined
+	OptionalStyleBoth = "both"
+)
+
+const (
+	// FieldCaseCamel renders field names as their json_name or in lowerCamelCase, which is how grpc-gateway encodes them by default
+	FieldCaseCamel = "camel"
+	// FieldCaseSnake renders field names in snake_case
+	FieldCaseSnake = "snake"
+	// FieldCaseOriginal renders field names the same as defined in the proto
+	FieldCaseOriginal = "original"
+)
+
+const (
+	// ModuleSystemESM renders ES module import and export statements
+	ModuleSystemESM = "esm"
+	// ModuleSystemCommonJS renders the typescript flavour of CommonJS require, which keeps the imported types
+	ModuleSystemCommonJS = "commonjs"
+)
+
+const (
+	// MessageKindInterface renders messages as object types, which the JSON payload can be used as directly
+	MessageKindInterface = "interface"
+	// MessageKindClass renders messages as classes converting themselves from and into JSON with fromJSON and toJSON
+	MessageKindClass = "class"
+)
+
+const (
+	// ClientStyleFetch renders the services as classes sending the requests with fetch
+	ClientStyleFetch = "fetch"
+	// ClientStyleAngular renders the services as Angular injectable classes sending the requests with HttpClient and returning Observables
+	ClientStyleAngular = "angular"
+)
+
+const (
+	// MethodNamesRPC names the methods of the clients after the rpcs
+	MethodNamesRPC = "rpc"
+	// MethodNamesOperationID names the methods of the clients after the operation_id of their openapiv2_operation option, or the
+	// selector of their google.api.http option, falling back to the rpcs
+	MethodNamesOperationID = "operation_id"
+)
+
+const (
+	// EmitAll generates both the types and the services
+	EmitAll = "all"
+	// EmitTypes generates the enums and the messages without the services
+	EmitTypes = "types"
+	// EmitServices generates the services, which import the types from the types-only output in TypesDir
+	EmitServices = "services"
+)
+
+const (
+	// BytesTypeString renders bytes as base64 encoded strings, which is how they are encoded in JSON
+	BytesTypeString = "string"
+	// BytesTypeUint8Array renders bytes as Uint8Array, the clients convert them from and into base64 encoded strings
+	BytesTypeUint8Array = "uint8array"
 )
 
 // Registry analyse generation request, spits out the data the the rendering process
@@ -37,7 +271,15 @@ type Registry struct {
 	// FilesToGenerate contains a list of actual file to generate, different from all the files from the request, some of which are import files
 	FilesToGenerate map[string]bool
 
-	// TSImportRoots represents the ts import root for the generator to figure out required import path, will default to cwd
+	// Exclude are the glob patterns of the proto files which aren't generated even when they are in the files to generate of the request,
+	// their types are still analysed so that the other files can refer to them
+	Exclude []string
+
+	// excludeRegexps are the regular expressions the proto files are matched with for Exclude
+	excludeRegexps []*regexp.Regexp
+
+	// TSImportRoots represents the ts import root for the generator to figure out required import path, will default to cwd, or the
+	// directory under cwd the files to generate are found in when protoc is run from a parent directory of the include path
 	TSImportRoots []string
 
 	// TSImportRootAliases if not empty will substitutes the common import root when writing the import into the js file
@@ -55,8 +297,204 @@ type Registry struct {
 	// UseProtoNames will cause the generator to generate field name the same as defined in the proto
 	UseProtoNames bool
 
+	// FieldCase is the case of the rendered field names, it defaults to original when UseProtoNames is set
+	FieldCase string
+
+	// FieldAcronyms are the lower case words upper cased in the camelCase field names, e.g. id renders user_id as userID
+	FieldAcronyms map[string]bool
+
+	// Strict turns ambiguities and unresolved dependencies found during the generation into errors
+	Strict bool
+
+	// OutputDir is the directory protoc writes the generated files into, the generated file names are relative to it.
+	// relative import paths are computed from the generated files inside it, it defaults to the current directory
+	OutputDir string
+
+	// EnumNamespaces will generate a namespace for each message with nested enums, so that the enums are reachable by their nesting, e.g. Outer.Status
+	EnumNamespaces bool
+
+	// EmitGuards will generate a type guard function for each message
+	EmitGuards bool
+
+	// EmitEquals will generate a function comparing two messages field by field for each message
+	EmitEquals bool
+
+	// EmitValidators will generate a function checking the protoc-gen-validate or buf.validate constraints of the fields
+	// for each message with constraints
+	EmitValidators bool
+
+	// EmitFactories will generate a factory function returning the default value for each message
+	EmitFactories bool
+
+	// WSBidi will generate clients for bidirectional streaming methods, which send and receive the messages over a WebSocket
+	// bridged to the method, e.g. by grpc-websocket-proxy. client streaming methods are skipped otherwise
+	WSBidi bool
+
+	// EmitSourceLocations will generate a comment with the proto file and the line each enum, message and service is declared at
+	// above them, which are read from the SourceCodeInfo of the files
+	EmitSourceLocations bool
+
+	// EmitSourceHash will generate a header with the sha256 hash of the descriptor of the proto file at the top of the files generated
+	// out of it, so that generated files which are stale relative to the proto file can be detected
+	EmitSourceHash bool
+
+	// EmitMocks will generate a make function for each message returning it with every field set to a dummy value,
+	// into a .mock file next to each generated file so that they are left out of production bundles
+	EmitMocks bool
+
+	// EmitReactQuery will generate a query hook for each GET method and a mutation hook for each other unary method of the services,
+	// into a .query file next to each generated file so that only the React apps depend on React Query
+	EmitReactQuery bool
+
+	// EmitServiceFiles will generate each service into a .client file next to the generated file of its proto file, which imports
+	// the requests and the responses from it, so that the apps only bundle the services they use
+	EmitServiceFiles bool
+
+	// APIObject will generate an api object holding a client of each service of the file keyed by the service name,
+	// along with a createApi function creating the clients with a shared default InitReq
+	APIObject bool
+
+	// Readonly will render the properties of messages as readonly, with readonly arrays and maps
+	Readonly bool
+
+	// EmitBarrels will generate an index file re-exporting the generated files in each directory
+	EmitBarrels bool
+
+	// EmitRollupDTS will generate a declaration file declaring the enums and the messages of all the generated files,
+	// with a namespace for each proto package
+	EmitRollupDTS bool
+
+	// DryRun will write a JSON summary of the generated files, their types and resolved imports instead of the generated files
+	DryRun bool
+
+	// Verbose will write a summary line counting the generated files, the registered types and the resolved imports to stderr
+	Verbose bool
+
+	// Stats counts how the imports of the files to generate have been resolved by the last Analyse
+	Stats ResolutionStats
+
+	// TemplateDir is the directory of the templates overriding the built-in ones, the built-in templates are used when it's empty
+	TemplateDir string
+
+	// SkipEmpty will skip generating the files without any enums, messages or services
+	SkipEmpty bool
+
 	// TSPackages stores the package name keyed by the TS file name
 	TSPackages map[string]string
+
+	// PackageMap stores the directory the generated files of a proto package are considered to live in keyed by the proto package,
+	// it overrides the directory of the proto file when computing imports
+	PackageMap map[string]string
+
+	// TSConfig is the tsconfig.json whose paths of the compilerOptions are used for the imports matching them instead of relative paths
+	TSConfig string
+
+	// tsconfigPaths are the path aliases read from TSConfig
+	tsconfigPaths []tsconfigPath
+
+	// TSPackageName is the name of the package the generated files are published in, e.g. @company/protos. the generated files
+	// are imported as the subpaths of the package following their paths inside the output directory or their import root
+	TSPackageName string
+
+	// FileMappings stores the module specifier the generated file of a proto file is imported from keyed by the proto file name,
+	// it's set by the M parameters and takes precedence over every other way of resolving imports
+	FileMappings map[string]string
+
+	// PackageDirs will place the generated files into nested directories following the dotted proto package names,
+	// e.g. the generated file of a/b/c.proto with package x.y lives in x/y/c.pb.ts. files without a package are not moved
+	PackageDirs bool
+
+	// Int64Type is the typescript type for 64-bit integer fields, one of string, number or bigint
+	Int64Type string
+
+	// TSFileExtension is the extension of the generated files, it follows the .pb in the file name
+	TSFileExtension string
+
+	// Bundle is the name of the file all the files to generate will be rendered into, bundling is disabled when it's empty
+	Bundle string
+
+	// EnumStyle is how enums will be rendered, one of enum, string_union or const_enum
+	EnumStyle string
+
+	// EnumUnknown is how the enum helpers handle values unknown to the client, e.g. added by a newer server,
+	// one of fallback, preserve or throw
+	EnumUnknown string
+
+	// WellKnownTypeMapping will cause the generator to render well-known types as their JSON representation
+	WellKnownTypeMapping bool
+
+	// TimestampType is the typescript type for google.protobuf.Timestamp when well-known type mapping is enabled
+	TimestampType string
+
+	// FieldMaskType is the typescript type for google.protobuf.FieldMask when well-known type mapping is enabled, one of string or array
+	FieldMaskType string
+
+	// TypedArrays will render repeated float and double fields as Float32Array and Float64Array, which are converted from and into
+	// the JSON arrays sent by grpc-gateway by the message classes
+	TypedArrays bool
+
+	// Indent is the indentation of a single level of the generated code, e.g. two spaces or a tab
+	Indent string
+
+	// QuoteStyle is the quotes of the strings in the generated code, one of double or single
+	QuoteStyle string
+
+	// Target is the version of the typescript compiler the generated code is compiled with, which gates the newer syntax
+	Target string
+
+	// targetVersion is the parsed version of Target
+	targetVersion targetVersion
+
+	// OptionalStyle is how unset message fields and proto3 optional fields are typed, one of undefined, null or both
+	OptionalStyle string
+
+	// BytesType is the typescript type bytes fields will be rendered as
+	BytesType string
+
+	// ModuleSystem is the module system of the import and export statements in the generated files, one of esm or commonjs
+	ModuleSystem string
+
+	// ClientStyle is the kind of clients generated for the services, one of fetch or angular
+	ClientStyle string
+
+	// MethodNames is what the methods of the clients are named after, one of rpc or operation_id
+	MethodNames string
+
+	// MessageKind is the kind of declarations messages will be rendered as, one of interface or class
+	MessageKind string
+
+	// Emit is what is generated out of the files, one of all, types or services
+	Emit string
+
+	// TypesDir is the directory the types-only output is generated into, the services import the types of the files to generate from it
+	// when Emit is services
+	TypesDir string
+
+	// sourceCodeInfo stores the source code locations of each file keyed by the proto file name
+	sourceCodeInfo map[string]sourceCodeInfo
+
+	// filePackages stores the proto package of each file keyed by the proto file name
+	filePackages map[string]string
+
+	// extensions are the extension fields declared in the analysed files
+	extensions []*extension
+
+	// importRootIndex stores the import root each proto file has been found at keyed by the proto file name,
+	// so that the import roots are only looked up once per file rather than once per depending file
+	importRootIndex map[string]importRoot
+
+	// deriveImportRoot is whether the import root has been defaulted to cwd, in which case it's derived from the files
+	// to generate when they are analysed
+	deriveImportRoot bool
+}
+
+// ResolutionStats counts how the imports of the files to generate have been resolved
+type ResolutionStats struct {
+	// Dependencies is the number of imports of the files to generate
+	Dependencies int
+	// ImportRootLookups is the number of proto files looked up in the import roots on the file system, which is the slow path
+	// taken by the files which are neither mapped by the M file mappings nor placed by ts_package_map
+	ImportRootLookups int
 }
 
 // NewRegistry initialise the registry and return the instance
@@ -75,6 +513,161 @@ func NewRegistry(paramsMap map[string]string) (*Registry, error) {
 	log.Debugf("found fetch module directory %s", fetchModuleDirectory)
 	log.Debugf("found fetch module name %s", fetchModuleFilename)
 
+	packageMap, err := getPackageMapInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting package map information")
+	}
+	log.Debugf("found package map %v", packageMap)
+
+	tsconfigPaths := make([]tsconfigPath, 0)
+	if paramsMap[TSConfig] != "" {
+		tsconfigPaths, err = loadTSConfigPaths(paramsMap[TSConfig])
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting tsconfig paths information")
+		}
+	}
+	log.Debugf("found tsconfig paths %v", tsconfigPaths)
+
+	fileMappings, err := getFileMappingsInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting file mappings information")
+	}
+	log.Debugf("found file mappings %v", fileMappings)
+
+	int64Type, err := getInt64Information(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting int64 type information")
+	}
+	log.Debugf("found int64 type %s", int64Type)
+
+	enumStyle, err := getEnumStyleInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting enum style information")
+	}
+	log.Debugf("found enum style %s", enumStyle)
+
+	enumUnknown, err := getEnumUnknownInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting enum unknown information")
+	}
+	log.Debugf("found enum unknown %s", enumUnknown)
+
+	timestampType, err := getTimestampTypeInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting timestamp type information")
+	}
+	log.Debugf("found timestamp type %s", timestampType)
+
+	indent, err := getIndentInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting indent information")
+	}
+	log.Debugf("found indent %q", indent)
+
+	quoteStyle, err := getQuoteStyleInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting quote style information")
+	}
+	log.Debugf("found quote style %s", quoteStyle)
+
+	target, targetVersion, err := getTargetInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting target information")
+	}
+	log.Debugf("found target %s", target)
+
+	optionalStyle, err := getOptionalStyleInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting optional style information")
+	}
+	log.Debugf("found optional style %s", optionalStyle)
+
+	bytesType, err := getBytesTypeInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting bytes type information")
+	}
+	log.Debugf("found bytes type %s", bytesType)
+
+	moduleSystem, err := getModuleSystemInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting module system information")
+	}
+	log.Debugf("found module system %s", moduleSystem)
+
+	clientStyle, err := getClientStyleInformation(paramsMap, moduleSystem)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting client style information")
+	}
+	log.Debugf("found client style %s", clientStyle)
+
+	if paramsMap[TSEmitReactQuery] == "true" && clientStyle != ClientStyleFetch {
+		return nil, errors.Errorf("%s is only supported with %s %s", TSEmitReactQuery, TSClientStyle, ClientStyleFetch)
+	}
+
+	if paramsMap[TSAPIObject] == "true" && clientStyle != ClientStyleFetch {
+		return nil, errors.Errorf("%s is only supported with %s %s", TSAPIObject, TSClientStyle, ClientStyleFetch)
+	}
+
+	methodNames, err := getMethodNamesInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting method names information")
+	}
+	log.Debugf("found method names %s", methodNames)
+
+	messageKind, err := getMessageKindInformation(paramsMap, bytesType)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting message kind information")
+	}
+	log.Debugf("found message kind %s", messageKind)
+
+	fieldMaskType, err := getFieldMaskTypeInformation(paramsMap, messageKind)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting field mask type information")
+	}
+	log.Debugf("found field mask type %s", fieldMaskType)
+
+	// plain objects are sent and received as they are, so only the message classes convert 64-bit integers from and into
+	// the strings of JSON
+	if int64Type != Int64TypeString && messageKind != MessageKindClass {
+		return nil, errors.Errorf("%s %s is only supported with %s %s", TSInt64Type, int64Type, TSMessageKind, MessageKindClass)
+	}
+
+	if paramsMap[TSTypedArrays] == "true" && messageKind != MessageKindClass {
+		return nil, errors.Errorf("%s is only supported with %s %s", TSTypedArrays, TSMessageKind, MessageKindClass)
+	}
+
+	emit, typesDir, err := getEmitInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting emit information")
+	}
+	log.Debugf("found emit %s with types directory %s", emit, typesDir)
+
+	if paramsMap[TSEmitServiceFiles] == "true" {
+		if emit != EmitAll {
+			return nil, errors.Errorf("%s is only supported with %s %s", TSEmitServiceFiles, TSEmit, EmitAll)
+		}
+		for _, param := range []string{TSBundle, TSAPIObject} {
+			if paramsMap[param] != "" && paramsMap[param] != "false" {
+				return nil, errors.Errorf("%s is not supported with %s", TSEmitServiceFiles, param)
+			}
+		}
+	}
+
+	tsFileExtension := getTSFileExtension(paramsMap)
+	log.Debugf("found ts file extension %s", tsFileExtension)
+
+	// declaration files only declare the types, the options generating implementations can't be rendered into them
+	if strings.HasSuffix(tsFileExtension, DeclarationFileExtension) {
+		if messageKind == MessageKindClass {
+			return nil, errors.Errorf("%s %s is not supported with %s %s", TSMessageKind, messageKind, TSFileExtension, tsFileExtension)
+		}
+		for _, param := range []string{TSEmitFactories, TSEmitGuards, TSEmitEquals, TSEmitValidators, TSEmitMocks, TSEmitReactQuery, TSEmitServiceFiles, TSAPIObject} {
+			if paramsMap[param] == "true" {
+				return nil, errors.Errorf("%s is not supported with %s %s", param, TSFileExtension, tsFileExtension)
+			}
+		}
+	}
+
 	useProtoNames := false
 
 	useProtoNamesVal, ok := paramsMap[UseProtoNames]
@@ -83,14 +676,96 @@ func NewRegistry(paramsMap map[string]string) (*Registry, error) {
 		useProtoNames = useProtoNamesVal == "true"
 	}
 
+	fieldCase, err := getFieldCaseInformation(paramsMap, useProtoNames)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting field case information")
+	}
+	log.Debugf("found field case %s", fieldCase)
+
+	if fieldCase == FieldCaseSnake {
+		log.Warnf("%s is set to %s, grpc-gateway serializes field names in camelCase unless it's configured to use proto names, the generated types may not match the payload at runtime", TSFieldCase, FieldCaseSnake)
+	}
+
+	fieldAcronyms, err := getFieldAcronymsInformation(paramsMap, fieldCase, messageKind)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting field acronyms information")
+	}
+	log.Debugf("found field acronyms %v", fieldAcronyms)
+
+	exclude, excludeRegexps, err := getExcludeInformation(paramsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting exclude information")
+	}
+	log.Debugf("found exclude patterns %v", exclude)
+
+	wellKnownTypeMapping := paramsMap[TSWellKnownTypeMapping] == "true"
+
+	_, hasImportRoots := paramsMap[TSImportRootParamsKey]
+	deriveImportRoot := !hasImportRoots && paramsMap[TSImportRootMarkerParamsKey] == "" && tsImportRootAliases[0] == ""
+
 	r := &Registry{
 		Types:                make(map[string]*TypeInformation),
 		TSImportRoots:        tsImportRoots,
 		TSImportRootAliases:  tsImportRootAliases,
+		deriveImportRoot:     deriveImportRoot,
 		FetchModuleDirectory: fetchModuleDirectory,
 		FetchModuleFilename:  fetchModuleFilename,
 		UseProtoNames:        useProtoNames,
+		FieldCase:            fieldCase,
+		FieldAcronyms:        fieldAcronyms,
+		Exclude:              exclude,
+		excludeRegexps:       excludeRegexps,
+		Strict:               paramsMap[Strict] == "true",
+		EmitBarrels:          paramsMap[TSEmitBarrels] == "true",
+		EmitRollupDTS:        paramsMap[TSEmitRollupDTS] == "true",
+		SkipEmpty:            paramsMap[TSSkipEmpty] == "true",
+		TemplateDir:          paramsMap[TSTemplateDir],
+		DryRun:               paramsMap[TSDryRun] == "true",
+		Verbose:              paramsMap[TSVerbose] == "true",
+		OutputDir:            paramsMap[OutputDir],
+		Readonly:             paramsMap[TSReadonly] == "true",
+		EmitGuards:           paramsMap[TSEmitGuards] == "true",
+		EmitEquals:           paramsMap[TSEmitEquals] == "true",
+		EmitValidators:       paramsMap[TSEmitValidators] == "true",
+		EnumNamespaces:       paramsMap[TSEnumNamespaces] == "true",
+		EmitFactories:        paramsMap[TSEmitFactories] == "true",
+		EmitMocks:            paramsMap[TSEmitMocks] == "true",
+		EmitReactQuery:       paramsMap[TSEmitReactQuery] == "true",
+		EmitServiceFiles:     paramsMap[TSEmitServiceFiles] == "true",
+		APIObject:            paramsMap[TSAPIObject] == "true",
+		EmitSourceLocations:  paramsMap[TSEmitSourceLocations] == "true",
+		EmitSourceHash:       paramsMap[TSEmitSourceHash] == "true",
+		WSBidi:               paramsMap[TSWSBidi] == "true",
 		TSPackages:           make(map[string]string),
+		PackageMap:           packageMap,
+		FileMappings:         fileMappings,
+		TSConfig:             paramsMap[TSConfig],
+		tsconfigPaths:        tsconfigPaths,
+		TSPackageName:        paramsMap[TSPackageName],
+		PackageDirs:          paramsMap[TSPackageDirs] == "true",
+		Int64Type:            int64Type,
+		TSFileExtension:      tsFileExtension,
+		Bundle:               paramsMap[TSBundle],
+		EnumStyle:            enumStyle,
+		WellKnownTypeMapping: wellKnownTypeMapping,
+		TimestampType:        timestampType,
+		FieldMaskType:        fieldMaskType,
+		TypedArrays:          paramsMap[TSTypedArrays] == "true",
+		Indent:               indent,
+		QuoteStyle:           quoteStyle,
+		Target:               target,
+		targetVersion:        targetVersion,
+		OptionalStyle:        optionalStyle,
+		EnumUnknown:          enumUnknown,
+		BytesType:            bytesType,
+		ModuleSystem:         moduleSystem,
+		ClientStyle:          clientStyle,
+		MethodNames:          methodNames,
+		Emit:                 emit,
+		TypesDir:             typesDir,
+		MessageKind:          messageKind,
+		sourceCodeInfo:       make(map[string]sourceCodeInfo),
+		filePackages:         make(map[string]string),
 	}
 
 	return r, nil
@@ -111,9 +786,354 @@ func getFetchModuleDirectory(paramsMap map[string]string) (fetchModuleDirectory
 	return fetchModuleDirectory, fetchModuleFile, nil
 }
 
+func getPackageMapInformation(paramsMap map[string]string) (map[string]string, error) {
+	packageMap := make(map[string]string)
+	packageMapValue := paramsMap[TSPackageMapParamsKey]
+	if packageMapValue == "" {
+		return packageMap, nil
+	}
+
+	for _, pair := range strings.Split(packageMapValue, TSImportRootSeparator) {
+		parts := strings.SplitN(pair, TSPackageMapSeparator, 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Errorf("invalid pair %s in %s, expected package=dir", pair, TSPackageMapParamsKey)
+		}
+
+		packageMap[parts[0]] = filepath.Clean(parts[1])
+	}
+
+	return packageMap, nil
+}
+
+func getFileMappingsInformation(paramsMap map[string]string) (map[string]string, error) {
+	// the keys are sorted so the same parameter is reported when more than one is invalid
+	keys := make([]string, 0)
+	for key := range paramsMap {
+		if strings.HasPrefix(key, FileMappingPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	fileMappings := make(map[string]string)
+	for _, key := range keys {
+		module := paramsMap[key]
+		protoFile := strings.TrimPrefix(key, FileMappingPrefix)
+		if !strings.HasSuffix(protoFile, ".proto") {
+			return nil, errors.Errorf("unknown parameter %s, expected %sfile.proto=module", key, FileMappingPrefix)
+		}
+		if module == "" {
+			return nil, errors.Errorf("invalid mapping %s=%s, expected %sfile.proto=module", key, module, FileMappingPrefix)
+		}
+
+		fileMappings[protoFile] = module
+	}
+
+	return fileMappings, nil
+}
+
+func getInt64Information(paramsMap map[string]string) (string, error) {
+	int64Type, ok := paramsMap[TSInt64Type]
+	if !ok || int64Type == "" {
+		return Int64TypeString, nil
+	}
+
+	switch int64Type {
+	case Int64TypeString, Int64TypeNumber, Int64TypeBigInt:
+		return int64Type, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are string, number and bigint", int64Type, TSInt64Type)
+	}
+}
+
+func getTSFileExtension(paramsMap map[string]string) string {
+	extension, ok := paramsMap[TSFileExtension]
+	if !ok || extension == "" {
+		return ".ts"
+	}
+
+	if !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+
+	return extension
+}
+
+func getEnumStyleInformation(paramsMap map[string]string) (string, error) {
+	enumStyle, ok := paramsMap[TSEnumStyle]
+	if !ok || enumStyle == "" {
+		return EnumStyleEnum, nil
+	}
+
+	switch enumStyle {
+	case EnumStyleEnum, EnumStyleStringUnion, EnumStyleConstEnum:
+		return enumStyle, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are enum, string_union and const_enum", enumStyle, TSEnumStyle)
+	}
+}
+
+func getEnumUnknownInformation(paramsMap map[string]string) (string, error) {
+	enumUnknown, ok := paramsMap[TSEnumUnknown]
+	if !ok || enumUnknown == "" {
+		return EnumUnknownFallback, nil
+	}
+
+	switch enumUnknown {
+	case EnumUnknownFallback, EnumUnknownThrow:
+		return enumUnknown, nil
+	case EnumUnknownPreserve:
+		// the properties of the message classes are typed with the enums, which can't hold the preserved values
+		if paramsMap[TSMessageKind] == MessageKindClass {
+			return "", errors.Errorf("%s %s is not supported with %s %s", TSEnumUnknown, enumUnknown, TSMessageKind, MessageKindClass)
+		}
+		return enumUnknown, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are fallback, preserve and throw", enumUnknown, TSEnumUnknown)
+	}
+}
+
+func getIndentInformation(paramsMap map[string]string) (string, error) {
+	indent, ok := paramsMap[TSIndent]
+	if !ok || indent == "" {
+		return DefaultIndent, nil
+	}
+
+	if indent == IndentTab {
+		return "\t", nil
+	}
+
+	spaces, err := strconv.Atoi(indent)
+	if err != nil || spaces < 1 || spaces > 8 {
+		return "", errors.Errorf("unsupported value %s for %s, valid values are tab and a number of spaces from 1 to 8", indent, TSIndent)
+	}
+
+	return strings.Repeat(" ", spaces), nil
+}
+
+func getQuoteStyleInformation(paramsMap map[string]string) (string, error) {
+	quoteStyle, ok := paramsMap[TSQuoteStyle]
+	if !ok || quoteStyle == "" {
+		return QuoteStyleDouble, nil
+	}
+
+	switch quoteStyle {
+	case QuoteStyleDouble, QuoteStyleSingle:
+		return quoteStyle, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are double and single", quoteStyle, TSQuoteStyle)
+	}
+}
+
+func getOptionalStyleInformation(paramsMap map[string]string) (string, error) {
+	optionalStyle, ok := paramsMap[TSOptionalStyle]
+	if !ok || optionalStyle == "" {
+		return OptionalStyleUndefined, nil
+	}
+
+	switch optionalStyle {
+	case OptionalStyleUndefined, OptionalStyleNull, OptionalStyleBoth:
+		return optionalStyle, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are undefined, null and both", optionalStyle, TSOptionalStyle)
+	}
+}
+
+func getTimestampTypeInformation(paramsMap map[string]string) (string, error) {
+	timestampType, ok := paramsMap[TSTimestampType]
+	if !ok || timestampType == "" {
+		return TimestampTypeString, nil
+	}
+
+	switch timestampType {
+	case TimestampTypeString, TimestampTypeDate:
+		return timestampType, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are string and Date", timestampType, TSTimestampType)
+	}
+}
+
+func getBytesTypeInformation(paramsMap map[string]string) (string, error) {
+	bytesType, ok := paramsMap[TSBytesType]
+	if !ok || bytesType == "" {
+		return BytesTypeString, nil
+	}
+
+	switch bytesType {
+	case BytesTypeString, BytesTypeUint8Array:
+		return bytesType, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are string and uint8array", bytesType, TSBytesType)
+	}
+}
+
+func getModuleSystemInformation(paramsMap map[string]string) (string, error) {
+	moduleSystem, ok := paramsMap[TSModuleSystem]
+	if !ok || moduleSystem == "" {
+		return ModuleSystemESM, nil
+	}
+
+	switch moduleSystem {
+	case ModuleSystemESM, ModuleSystemCommonJS:
+		return moduleSystem, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are esm and commonjs", moduleSystem, TSModuleSystem)
+	}
+}
+
+func getFieldMaskTypeInformation(paramsMap map[string]string, messageKind string) (string, error) {
+	fieldMaskType, ok := paramsMap[TSFieldMaskType]
+	if !ok || fieldMaskType == "" {
+		return FieldMaskTypeString, nil
+	}
+
+	switch fieldMaskType {
+	case FieldMaskTypeString:
+		return fieldMaskType, nil
+	case FieldMaskTypeArray:
+		// plain objects are sent as they are, only the message classes convert the paths from and into the JSON string
+		if messageKind != MessageKindClass {
+			return "", errors.Errorf("%s %s is only supported with %s %s", TSFieldMaskType, fieldMaskType, TSMessageKind, MessageKindClass)
+		}
+		return fieldMaskType, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are string and array", fieldMaskType, TSFieldMaskType)
+	}
+}
+
+func getMessageKindInformation(paramsMap map[string]string, bytesType string) (string, error) {
+	messageKind, ok := paramsMap[TSMessageKind]
+	if !ok || messageKind == "" {
+		return MessageKindInterface, nil
+	}
+
+	switch messageKind {
+	case MessageKindInterface:
+		return messageKind, nil
+	case MessageKindClass:
+		if bytesType == BytesTypeUint8Array {
+			return "", errors.Errorf("%s %s is not supported with %s %s", TSMessageKind, messageKind, TSBytesType, bytesType)
+		}
+		return messageKind, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are interface and class", messageKind, TSMessageKind)
+	}
+}
+
+func getClientStyleInformation(paramsMap map[string]string, moduleSystem string) (string, error) {
+	clientStyle, ok := paramsMap[TSClientStyle]
+	if !ok || clientStyle == "" {
+		return ClientStyleFetch, nil
+	}
+
+	switch clientStyle {
+	case ClientStyleFetch:
+		return clientStyle, nil
+	case ClientStyleAngular:
+		if moduleSystem != ModuleSystemESM {
+			return "", errors.Errorf("%s %s is only supported with %s %s", TSClientStyle, clientStyle, TSModuleSystem, ModuleSystemESM)
+		}
+		return clientStyle, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are fetch and angular", clientStyle, TSClientStyle)
+	}
+}
+
+func getMethodNamesInformation(paramsMap map[string]string) (string, error) {
+	methodNames, ok := paramsMap[TSMethodNames]
+	if !ok || methodNames == "" {
+		return MethodNamesRPC, nil
+	}
+
+	switch methodNames {
+	case MethodNamesRPC, MethodNamesOperationID:
+		return methodNames, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are rpc and operation_id", methodNames, TSMethodNames)
+	}
+}
+
+func getFieldAcronymsInformation(paramsMap map[string]string, fieldCase, messageKind string) (map[string]bool, error) {
+	fieldAcronyms := make(map[string]bool)
+	fieldAcronymsValue := paramsMap[TSFieldAcronyms]
+	if fieldAcronymsValue == "" {
+		return fieldAcronyms, nil
+	}
+
+	if fieldCase != FieldCaseCamel {
+		return nil, errors.Errorf("%s is only supported with %s %s", TSFieldAcronyms, TSFieldCase, FieldCaseCamel)
+	}
+	if messageKind != MessageKindClass {
+		// the properties of interfaces are the keys of the JSON payload, renaming them would break the serialization
+		return nil, errors.Errorf("%s is only supported with %s %s, which serializes the fields by their json names", TSFieldAcronyms, TSMessageKind, MessageKindClass)
+	}
+
+	for _, acronym := range strings.Split(fieldAcronymsValue, TSImportRootSeparator) {
+		if acronym == "" {
+			continue
+		}
+		fieldAcronyms[strings.ToLower(acronym)] = true
+	}
+
+	return fieldAcronyms, nil
+}
+
+func getEmitInformation(paramsMap map[string]string) (string, string, error) {
+	emit, ok := paramsMap[TSEmit]
+	if !ok || emit == "" {
+		return EmitAll, "", nil
+	}
+
+	switch emit {
+	case EmitAll, EmitTypes:
+		return emit, "", nil
+	case EmitServices:
+		typesDir := paramsMap[TSTypesDir]
+		if typesDir == "" {
+			return "", "", errors.Errorf("%s %s requires %s to import the types from", TSEmit, emit, TSTypesDir)
+		}
+		if paramsMap[TSBundle] != "" {
+			return "", "", errors.Errorf("%s %s is not supported with %s", TSEmit, emit, TSBundle)
+		}
+		return emit, filepath.Clean(typesDir), nil
+	default:
+		return "", "", errors.Errorf("unsupported value %s for %s, valid values are all, types and services", emit, TSEmit)
+	}
+}
+
+func getFieldCaseInformation(paramsMap map[string]string, useProtoNames bool) (string, error) {
+	fieldCase, ok := paramsMap[TSFieldCase]
+	if !ok || fieldCase == "" {
+		if useProtoNames {
+			return FieldCaseOriginal, nil
+		}
+
+		return FieldCaseCamel, nil
+	}
+
+	switch fieldCase {
+	case FieldCaseCamel, FieldCaseSnake, FieldCaseOriginal:
+		return fieldCase, nil
+	default:
+		return "", errors.Errorf("unsupported value %s for %s, valid values are camel, snake and original", fieldCase, TSFieldCase)
+	}
+}
+
 func getTSImportRootInformation(paramsMap map[string]string) ([]string, []string, error) {
 	tsImportRootsValue, ok := paramsMap[TSImportRootParamsKey]
 
+	if markers := paramsMap[TSImportRootMarkerParamsKey]; markers != "" {
+		if ok {
+			return nil, nil, errors.Errorf("%s and %s cannot be used together", TSImportRootParamsKey, TSImportRootMarkerParamsKey)
+		}
+
+		markedRoot, err := findMarkedImportRoot(paramsMap[OutputDir], strings.Split(markers, TSImportRootSeparator))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error looking up the import root marker")
+		}
+		tsImportRootsValue, ok = markedRoot, true
+	}
+
 	if !ok {
 		tsImportRootsValue = "."
 	}
@@ -156,6 +1176,37 @@ func getTSImportRootInformation(paramsMap map[string]string) ([]string, []string
 
 	}
 
+	tsImportRootAliasMapValue := paramsMap[TSImportRootAliasMapParamsKey]
+	if tsImportRootAliasMapValue == "" {
+		return tsImportRoots, tsImportRootAliases, nil
+	}
+
+	// root=alias pairs either assign the alias to an existing root or add the root to the import roots
+	for _, pair := range strings.Split(tsImportRootAliasMapValue, TSImportRootSeparator) {
+		parts := strings.SplitN(pair, TSImportRootAliasMapSeparator, 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, nil, errors.Errorf("invalid pair %s in %s, expected root=alias", pair, TSImportRootAliasMapParamsKey)
+		}
+
+		absRoot, err := filepath.Abs(parts[0])
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error turning path %s into absolute path", parts[0])
+		}
+
+		found := false
+		for i, root := range tsImportRoots {
+			if root == absRoot {
+				tsImportRootAliases[i] = parts[1]
+				found = true
+			}
+		}
+
+		if !found {
+			tsImportRoots = append(tsImportRoots, absRoot)
+			tsImportRootAliases = append(tsImportRootAliases, parts[1])
+		}
+	}
+
 	return tsImportRoots, tsImportRootAliases, nil
 }
 
@@ -179,6 +1230,25 @@ type TypeInformation struct {
 	KeyType *data.MapEntryType
 	// Value type is the type information for the map value
 	ValueType *data.MapEntryType
+	// Comment is the comment attached to the type in the proto file
+	Comment string
+	// EnumValues is the names of the values in declaration order when the type is an enum
+	EnumValues []string
+	// FieldJSONNames is the json name of the fields keyed by the field name when the type is a message
+	FieldJSONNames map[string]string
+	// Fields are the fields of the message when the type is a message
+	Fields []*data.Field
+}
+
+// registerType stores the type information keyed by its fully qualified name.
+// the same name defined in different files is an error, the type registered first would be overwritten silently otherwise
+func (r *Registry) registerType(typeInfo *TypeInformation) error {
+	if existing, ok := r.Types[typeInfo.FullyQualifiedName]; ok && existing.File != typeInfo.File {
+		return errors.Errorf("%s is defined in both %s and %s", typeInfo.FullyQualifiedName, existing.File, typeInfo.File)
+	}
+
+	r.Types[typeInfo.FullyQualifiedName] = typeInfo
+	return nil
 }
 
 // IsFileToGenerate contains the file to be generated in the request
@@ -187,12 +1257,32 @@ func (r *Registry) IsFileToGenerate(name string) bool {
 	return ok && result
 }
 
+// EmitsDeclarations returns whether the generated files are declaration files, into which only the types are rendered
+func (r *Registry) EmitsDeclarations() bool {
+	return strings.HasSuffix(r.TSFileExtension, DeclarationFileExtension)
+}
+
 // Analyse analyses the the file inputs, stores types information and spits out the rendering data
 func (r *Registry) Analyse(req *plugin.CodeGeneratorRequest) (map[string]*data.File, error) {
 	r.FilesToGenerate = make(map[string]bool)
 	for _, f := range req.GetFileToGenerate() {
+		if r.isExcluded(f) {
+			log.Debugf("file %s is excluded by %s, it's only analysed for its types", f, TSExclude)
+			continue
+		}
 		r.FilesToGenerate[f] = true
 	}
+	if r.deriveImportRoot {
+		root, err := findDefaultImportRoot(".", req.GetFileToGenerate())
+		if err != nil {
+			return nil, errors.Wrap(err, "error deriving the import root from the files to generate")
+		}
+		log.Debugf("derived import root %s from the files to generate", root)
+		r.TSImportRoots[0] = root
+	}
+	r.importRootIndex = make(map[string]importRoot)
+	r.extensions = nil
+	r.Stats = ResolutionStats{}
 
 	files := req.GetProtoFile()
 	log.Debugf("about to start anaylyse files, %d in total", len(files))
@@ -206,6 +1296,13 @@ func (r *Registry) Analyse(req *plugin.CodeGeneratorRequest) (map[string]*data.F
 		data[f.GetName()] = fileData
 	}
 
+	// extended messages might be in any of the files, so extensions are only added once all of them are analysed
+	r.analyseExtensions(data)
+
+	if r.Emit == EmitServices {
+		r.removeTypes(data)
+	}
+
 	// when finishes we have a full map of types and where they are located
 	// collect all the external dependencies and back fill it to the file data.
 	err := r.collectExternalDependenciesFromData(data)
@@ -213,6 +1310,10 @@ func (r *Registry) Analyse(req *plugin.CodeGeneratorRequest) (map[string]*data.F
 		return nil, errors.Wrap(err, "error collecting external dependency information after analysis finished")
 	}
 
+	if r.Bundle != "" {
+		r.resolveBundleIdentifiers(data)
+	}
+
 	return data, nil
 }
 
@@ -239,7 +1340,19 @@ func (r *Registry) getFullQualifiedName(packageName string, parents []string, na
 }
 
 func (r *Registry) isExternalDependenciesOutsidePackage(fqTypeName, packageName string) bool {
-	return strings.Index(fqTypeName, "."+packageName) != 0 && strings.Index(fqTypeName, ".") == 0
+	if strings.Index(fqTypeName, ".") != 0 {
+		// scalar types
+		return false
+	}
+
+	if packageName == "" {
+		// types of packageless files can't be told apart from the types of other packages by their names,
+		// e.g. .foo.Bar might be Bar nested in the packageless Foo. they are resolved by their files
+		// in analyseFilePackageTypeDependencies like types of the same package in different files
+		return false
+	}
+
+	return !strings.HasPrefix(fqTypeName, "."+packageName+".")
 }
 
 // findRootAliasForPath iterate through all ts_import_roots and try to find an alias with the first matching the ts_import_root
@@ -272,6 +1385,254 @@ func (r *Registry) findRootAliasForPath(predicate func(root string) (bool, error
 	return foundAtRoot, alias, nil
 }
 
+// importRoot is the import root a proto file has been found at, along with the alias of the root
+type importRoot struct {
+	root  string
+	alias string
+}
+
+// findMarkedImportRoot walks up from the output directory to the nearest directory containing any of the marker files, e.g. package.json.
+// it falls back to the current directory with a warning when none of the parent directories contains a marker
+func findMarkedImportRoot(outputDir string, markers []string) (string, error) {
+	dir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "error looking up absolute path for output directory %s", outputDir)
+	}
+
+	for {
+		for _, marker := range markers {
+			_, err := os.Stat(filepath.Join(dir, marker))
+			if err == nil {
+				log.Debugf("found import root marker %s in %s", marker, dir)
+				return dir, nil
+			}
+
+			if !os.IsNotExist(err) {
+				return "", errors.Wrapf(err, "error looking up %s in %s", marker, dir)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	log.Warnf("none of %v is found in the parent directories of the output directory, using the current directory as the import root", markers)
+	return ".", nil
+}
+
+// defaultImportRootMaxDepth is how many levels below the current directory are looked into for the default import root,
+// so that protoc run from a large tree, e.g. the home directory, doesn't walk all of it
+const defaultImportRootMaxDepth = 3
+
+// findDefaultImportRoot returns the directory the files to generate are relative to when no import root is passed.
+// protoc is often run from a parent directory of its include path, e.g. `protoc -I api/protos` from the root of the
+// repository, so when the files share a common directory, dir and its descendants up to defaultImportRootMaxDepth levels
+// below it are visited in lexical order and the first one containing all of the files is the import root. hidden
+// directories and node_modules are not visited.
+// it falls back to dir when the files don't share a common directory or none of the directories contains them
+func findDefaultImportRoot(dir string, files []string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrapf(err, "error looking up absolute path for %s", dir)
+	}
+	if len(files) == 0 {
+		return absDir, nil
+	}
+
+	common := path.Dir(files[0])
+	for _, f := range files[1:] {
+		for common != "." && !strings.HasPrefix(f, common+"/") {
+			common = path.Dir(common)
+		}
+	}
+	if common == "." {
+		// a bare file name could be found in any unrelated directory, so only dir is considered
+		return absDir, nil
+	}
+
+	// the common directory is looked up first, so only the candidates containing it are checked for all of the files
+	containsFiles := func(root string) bool {
+		if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(common))); err != nil || !info.IsDir() {
+			return false
+		}
+		for _, f := range files {
+			if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(f))); err != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	found := ""
+	err = filepath.WalkDir(absDir, func(current string, entry os.DirEntry, err error) error {
+		// the directories which can't be read are skipped, and so is the rest of the tree once the root is found
+		if err != nil || !entry.IsDir() {
+			return nil
+		}
+		if found != "" || (current != absDir && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == "node_modules")) {
+			return filepath.SkipDir
+		}
+		if containsFiles(current) {
+			found = current
+			return filepath.SkipDir
+		}
+		// the descendants of the deepest directories are not listed
+		if rel, err := filepath.Rel(absDir, current); err == nil && rel != "." &&
+			strings.Count(rel, string(filepath.Separator)) >= defaultImportRootMaxDepth-1 {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "error looking up the files to generate from %s", absDir)
+	}
+	if found != "" {
+		return found, nil
+	}
+
+	log.Debugf("the files to generate are not found from %s, using it as the import root", absDir)
+	return absDir, nil
+}
+
+// findImportRootForFile returns the first import root containing the proto file and its alias.
+// import roots are visited in the order of ts_import_roots, so the result is the same regardless of the platform.
+// if the file is present in more than one root, a warning will be logged, or an error returned in strict mode.
+// the result is cached in the import root index so the file system is only visited once for each file
+func (r *Registry) findImportRootForFile(fileName string) (foundAtRoot, alias string, err error) {
+	if found, ok := r.importRootIndex[fileName]; ok {
+		return found.root, found.alias, nil
+	}
+
+	r.Stats.ImportRootLookups++
+	matches := make([]int, 0, 1)
+	for i, root := range r.TSImportRoots {
+		absRoot, err := filepath.Abs(root)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "error looking up absolute path for %s", root)
+		}
+
+		_, err = os.Stat(filepath.Join(absRoot, fileName))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return "", "", errors.Wrapf(err, "error looking up %s in import root %s", fileName, absRoot)
+		}
+
+		matches = append(matches, i)
+	}
+
+	if len(matches) > 1 {
+		roots := make([]string, 0, len(matches))
+		for _, i := range matches {
+			roots = append(roots, r.TSImportRoots[i])
+		}
+
+		if r.Strict {
+			return "", "", errors.Errorf("%s is found in multiple import roots %v", fileName, roots)
+		}
+		log.Warnf("%s is found in multiple import roots %v, using the first one %s", fileName, roots, roots[0])
+	}
+
+	if len(matches) > 0 {
+		foundAtRoot = r.TSImportRoots[matches[0]]
+		if matches[0] < len(r.TSImportRootAliases) {
+			alias = r.TSImportRootAliases[matches[0]]
+		}
+	}
+
+	r.importRootIndex[fileName] = importRoot{root: foundAtRoot, alias: alias}
+	return foundAtRoot, alias, nil
+}
+
+// getTSFileName returns the name of the generated file for the proto file, inside the package directories when PackageDirs is set
+func (r *Registry) getTSFileName(fileName string) string {
+	packageName := r.filePackages[fileName]
+	if r.PackageDirs && packageName != "" {
+		return data.GetPackageTSFileName(packageName, fileName, r.TSFileExtension)
+	}
+
+	return data.GetTSFileName(fileName, r.TSFileExtension)
+}
+
+// IsImportedFromOutput returns whether the generated file of the proto file is imported from the files generated by this run,
+// rather than a module of the M file mappings or the ts_package file option
+func (r *Registry) IsImportedFromOutput(fileName string) bool {
+	if _, ok := r.FileMappings[fileName]; ok {
+		return false
+	}
+
+	if _, ok := r.TSPackages[r.getTSFileName(fileName)]; ok {
+		return false
+	}
+
+	return r.IsFileToGenerate(fileName)
+}
+
+// getImportPath returns the path of the generated file considered when computing imports, and whether it doesn't follow the directory of the proto file.
+// the generated files of the packages in ts_package_map live in the mapped directory rather than the directory of the proto file,
+// the same goes for the package directories when PackageDirs is set
+func (r *Registry) getImportPath(fileName, tsFileName string) (string, bool) {
+	if r.IsBundled(fileName) {
+		return tsFileName, false
+	}
+
+	packageName := r.filePackages[fileName]
+	if dir, ok := r.PackageMap[packageName]; ok {
+		return filepath.Join(dir, filepath.Base(tsFileName)), true
+	}
+
+	if r.PackageDirs && packageName != "" {
+		return tsFileName, true
+	}
+
+	return tsFileName, false
+}
+
+// getOutputPath returns the path of the generated file inside the output directory
+func (r *Registry) getOutputPath(fileName string) string {
+	if r.OutputDir == "" || filepath.IsAbs(fileName) {
+		return fileName
+	}
+
+	return filepath.Join(r.OutputDir, fileName)
+}
+
+// findLongestAliasedRoot returns the longest import root with an alias containing the target
+func (r *Registry) findLongestAliasedRoot(target string) (foundAtRoot, alias string, found bool) {
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", "", false
+	}
+
+	for i, root := range r.TSImportRoots {
+		if i >= len(r.TSImportRootAliases) || r.TSImportRootAliases[i] == "" {
+			continue
+		}
+
+		absRoot, err := filepath.Abs(root)
+		if err != nil || !isPathInside(absTarget, absRoot) {
+			continue
+		}
+
+		if !found || len(absRoot) > len(foundAtRoot) {
+			foundAtRoot, alias, found = absRoot, r.TSImportRootAliases[i], true
+		}
+	}
+
+	return foundAtRoot, alias, found
+}
+
+// isPathInside returns whether the path is inside the directory, both of them need to be absolute
+func isPathInside(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // getSourceFileForImport will return source file for import use.
 // if alias is provided it will try to replace the absolute root with target's absolute path with alias
 // if no alias then it will try to return a relative path to the source file
@@ -288,8 +1649,21 @@ func (r *Registry) getSourceFileForImport(source, target, root, alias string) (s
 			return "", errors.Wrapf(err, "error looking up absolute path for root %s", root)
 		}
 
-		ret = strings.ReplaceAll(absTarget, absRoot, alias)
+		// only the root at the beginning of the path is replaced, the same directory name might appear later in the path
+		rel, err := filepath.Rel(absRoot, absTarget)
+		if err != nil || !isPathInside(absTarget, absRoot) {
+			return "", errors.Errorf("target %s is not inside the root %s aliased as %s", target, root, alias)
+		}
+
+		ret = strings.TrimSuffix(alias, "/") + "/" + filepath.ToSlash(rel)
 		log.Debugf("replacing root alias %s for %s, result: %s", alias, target, ret)
+	} else if subpath, ok := r.getPackageSubpath(absTarget, root); ok {
+		// the files published in the package are imported by the subpaths the package exports
+		ret = subpath
+		log.Debugf("replacing the relative path for %s with the package subpath %s", target, ret)
+	} else if tsconfigAlias, ok := r.getTSConfigAlias(absTarget); ok {
+		// the path aliases of tsconfig.json are preferred over relative paths
+		ret = tsconfigAlias
 	} else { // return relative path here
 		log.Debugf("no root alias found, trying to get the relative path for %s", target)
 		absSource, err := filepath.Abs(source)
@@ -302,89 +1676,182 @@ func (r *Registry) getSourceFileForImport(source, target, root, alias string) (s
 			return "", errors.Wrapf(err, "error looking up relative path for source target %s", target)
 		}
 
-		slashPath := filepath.ToSlash(ret)
-		log.Debugf("got relative path %s for %s", target, slashPath)
+		// import paths always use forward slashes regardless of the platform
+		ret = filepath.ToSlash(ret)
+		log.Debugf("got relative path %s for %s", target, ret)
 
-		if !strings.HasPrefix(slashPath, "../") { // sub directory will not have relative path ./, if this happens, prepend one
-			ret = filepath.FromSlash("./" + slashPath)
+		// the same and sub directories, including files in the current directory without any separators, will not have relative path ./,
+		// which typescript would resolve as a package rather than a file. if this happens, prepend one
+		if !strings.HasPrefix(ret, "../") {
+			ret = "./" + ret
 		}
 
 		log.Debugf("no root alias found, trying to get the relative path for %s, result: %s", target, ret)
 	}
 
-	// remove .ts suffix if there's any
-	suffixIndex := strings.LastIndex(ret, ".ts")
-	if suffixIndex != -1 {
-		ret = ret[0:suffixIndex]
-	}
+	ret = data.TrimTSExtension(ret)
 
 	return ret, nil
 
 }
 
+// getPackageSubpath returns the subpath of ts_package_name the target is imported from, which is the path of the target inside the root,
+// or inside the output directory when the target isn't in any import roots. the targets outside of them aren't published in the package
+func (r *Registry) getPackageSubpath(absTarget, root string) (string, bool) {
+	if r.TSPackageName == "" {
+		return "", false
+	}
+
+	if root == "" {
+		root = r.getOutputPath(".")
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil || !isPathInside(absTarget, absRoot) {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(absRoot, absTarget)
+	if err != nil {
+		return "", false
+	}
+
+	return strings.TrimSuffix(r.TSPackageName, "/") + "/" + filepath.ToSlash(rel), true
+}
+
 func (r *Registry) collectExternalDependenciesFromData(filesData map[string]*data.File) error {
 	for _, fileData := range filesData {
-		log.Debugf("collecting dependencies information for %s", fileData.TSFileName)
-		// dependency group up the dependency by package+file
-		dependencies := make(map[string]*data.Dependency)
-		for _, typeName := range fileData.ExternalDependingTypes {
-			typeInfo, ok := r.Types[typeName]
-			if !ok {
-				return errors.Errorf("cannot find type info for %s, $v", typeName)
+		if !r.IsFileToGenerate(fileData.Name) {
+			// dependencies are only rendered for the files to generate, no need to look them up for the others
+			continue
+		}
+
+		if err := r.collectExternalDependencies(fileData); err != nil {
+			return err
+		}
+
+		// the services generated into their own files import the types of the file itself as well
+		for _, serviceFile := range fileData.ServiceFiles {
+			if err := r.collectExternalDependencies(serviceFile); err != nil {
+				return err
 			}
-			identifier := typeInfo.Package + "|" + typeInfo.File
-
-			if _, ok := dependencies[identifier]; !ok {
-				// only fill in if this file has not been mentioned before.
-				// the way import in the genrated file works is like
-				// import * as [ModuleIdentifier] from '[Source File]'
-				// so there only needs to be added once.
-				// Referencing types will be [ModuleIdentifier].[PackageIdentifier]
-				base := fileData.TSFileName
-				target := data.GetTSFileName(typeInfo.File)
-				sourceFile := ""
-				if pkg, ok := r.TSPackages[target]; ok {
-					log.Debugf("package import override %s has been found for file %s", pkg, target)
-					sourceFile = pkg
-				} else {
-					foundAtRoot, alias, err := r.findRootAliasForPath(func(absRoot string) (bool, error) {
-						completePath := filepath.Join(absRoot, typeInfo.File)
-						_, err := os.Stat(completePath)
-						if err != nil {
-							if os.IsNotExist(err) {
-								return false, nil
-							}
-
-							return false, err
-
-						} else {
-							return true, nil
-						}
-
-					})
-					if err != nil {
-						return errors.WithStack(err)
-					}
+		}
+	}
 
-					if foundAtRoot != "" {
-						target = filepath.Join(foundAtRoot, target)
-					}
+	return nil
+}
+
+// collectExternalDependencies resolves the imports of the external types the file depends on and adds them to its dependencies
+func (r *Registry) collectExternalDependencies(fileData *data.File) error {
+	log.Debugf("collecting dependencies information for %s", fileData.TSFileName)
+	// dependency group up the dependency by package+file
+	dependencies := make(map[string]*data.Dependency)
+	// the same type is tracked for every field referring to it, e.g. recursive messages, only visit it once
+	visited := make(map[string]bool)
+	for _, typeName := range fileData.ExternalDependingTypes {
+		if visited[typeName] {
+			continue
+		}
+		visited[typeName] = true
+
+		typeInfo, ok := r.Types[typeName]
+		if !ok {
+			return errors.Errorf("cannot find type info for %s depended on by %s", typeName, fileData.Name)
+		}
 
-					sourceFile, err = r.getSourceFileForImport(base, target, foundAtRoot, alias)
-					if err != nil {
-						return errors.Wrap(err, "error getting source file for import")
+		if typeInfo.File == fileData.Name && r.Emit != EmitServices && !fileData.IsServiceFile {
+			// types of the file itself are referenced locally, a file never imports itself
+			continue
+		}
+
+		if r.IsBundled(fileData.Name) && r.IsBundled(typeInfo.File) {
+			// types inside the bundle are referenced locally
+			continue
+		}
+		identifier := typeInfo.Package + "|" + typeInfo.File
+
+		if _, ok := dependencies[identifier]; !ok {
+			// only fill in if this file has not been mentioned before.
+			// the way import in the genrated file works is like
+			// import * as [ModuleIdentifier] from '[Source File]'
+			// so there only needs to be added once.
+			// Referencing types will be [ModuleIdentifier].[PackageIdentifier]
+			basePath, _ := r.getImportPath(fileData.Name, fileData.TSFileName)
+			base := r.getOutputPath(basePath)
+			target := r.getTSFileName(typeInfo.File)
+			sourceFile := ""
+			if module, ok := r.FileMappings[typeInfo.File]; ok {
+				log.Debugf("file mapping %s has been found for file %s", module, typeInfo.File)
+				sourceFile = module
+			} else if pkg, ok := r.TSPackages[target]; ok {
+				log.Debugf("package import override %s has been found for file %s", pkg, target)
+				sourceFile = pkg
+			} else if r.Emit == EmitServices && r.IsFileToGenerate(typeInfo.File) {
+				// the types of the files to generate are generated into the types-only output
+				target = filepath.Join(r.TypesDir, target)
+				log.Debugf("types of file %s are imported from %s", typeInfo.File, target)
+				foundAtRoot, alias, _ := r.findLongestAliasedRoot(target)
+
+				var err error
+				sourceFile, err = r.getSourceFileForImport(base, target, foundAtRoot, alias)
+				if err != nil {
+					return errors.Wrap(err, "error getting source file for import")
+				}
+			} else if mappedTarget, ok := r.getImportPath(typeInfo.File, target); ok {
+				log.Debugf("package %s of file %s is placed at %s", typeInfo.Package, typeInfo.File, mappedTarget)
+				target = r.getOutputPath(mappedTarget)
+				foundAtRoot, alias, _ := r.findLongestAliasedRoot(target)
+
+				var err error
+				sourceFile, err = r.getSourceFileForImport(base, target, foundAtRoot, alias)
+				if err != nil {
+					return errors.Wrap(err, "error getting source file for import")
+				}
+			} else {
+				foundAtRoot, alias, err := r.findImportRootForFile(typeInfo.File)
+				if err != nil {
+					return errors.Wrapf(err, "error resolving %s of type %s depended on by %s", typeInfo.File, typeName, fileData.Name)
+				}
+
+				if foundAtRoot != "" {
+					target = filepath.Join(foundAtRoot, target)
+				} else {
+					if r.Strict {
+						return errors.Errorf("cannot resolve %s of type %s depended on by %s, the file is not found in any import roots %v", typeInfo.File, typeName, fileData.Name, r.TSImportRoots)
 					}
+					// files not found in any import roots are generated into the output directory
+					log.Debugf("%s is not found in any import roots %v, assuming it is generated into the output directory", typeInfo.File, r.TSImportRoots)
+					target = r.getOutputPath(target)
+				}
+
+				// nested roots might have their own aliases, the most specific one wins
+				if aliasedRoot, rootAlias, ok := r.findLongestAliasedRoot(target); ok {
+					foundAtRoot, alias = aliasedRoot, rootAlias
 				}
-				dependencies[identifier] = &data.Dependency{
-					ModuleIdentifier: data.GetModuleName(typeInfo.Package, typeInfo.File),
-					SourceFile:       sourceFile,
+
+				sourceFile, err = r.getSourceFileForImport(base, target, foundAtRoot, alias)
+				if err != nil {
+					return errors.Wrap(err, "error getting source file for import")
 				}
 			}
+			dependencies[identifier] = &data.Dependency{
+				ModuleIdentifier: data.GetModuleName(typeInfo.Package, typeInfo.File),
+				SourceFile:       sourceFile,
+			}
 		}
+	}
 
-		for _, dependency := range dependencies {
-			fileData.Dependencies = append(fileData.Dependencies, dependency)
+	// collecting the dependencies again must not import the same file twice, only the new imports are added
+	imported := make(map[data.Dependency]bool)
+	for _, dependency := range fileData.Dependencies {
+		imported[*dependency] = true
+	}
+	for _, dependency := range dependencies {
+		if imported[*dependency] {
+			continue
 		}
+		fileData.Dependencies = append(fileData.Dependencies, dependency)
+		r.Stats.Dependencies++
 	}
 
 	return nil
@@ -0,0 +1,721 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugin "github.com/golang/protobuf/protoc-gen-go/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGetSourceFileForImport(t *testing.T) {
+	r, err := NewRegistry(map[string]string{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		source   string
+		target   string
+		root     string
+		alias    string
+		expected string
+	}{
+		{
+			name:     "same directory",
+			source:   "a/b/foo.pb.ts",
+			target:   "a/b/bar.pb.ts",
+			expected: "./bar.pb",
+		},
+		{
+			name:     "current directory without separators",
+			source:   "foo.pb.ts",
+			target:   "bar.pb.ts",
+			expected: "./bar.pb",
+		},
+		{
+			name:     "sibling directories",
+			source:   "a/b/c/foo.pb.ts",
+			target:   "a/b/d/bar.pb.ts",
+			expected: "../d/bar.pb",
+		},
+		{
+			name:     "sub directory",
+			source:   "a/foo.pb.ts",
+			target:   "a/b/c/bar.pb.ts",
+			expected: "./b/c/bar.pb",
+		},
+		{
+			name:     "aliased root",
+			source:   "/root/a/foo.pb.ts",
+			target:   "/root/b/bar.pb.ts",
+			root:     "/root",
+			alias:    "@protos",
+			expected: "@protos/b/bar.pb",
+		},
+		{
+			name:     "aliased root repeated inside the path",
+			source:   "/root/a/foo.pb.ts",
+			target:   "/root/b/root/bar.pb.ts",
+			root:     "/root",
+			alias:    "@protos",
+			expected: "@protos/b/root/bar.pb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := r.getSourceFileForImport(tt.source, tt.target, tt.root, tt.alias)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestFindImportRootForFileIsIndexed(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	for _, root := range []string{first, second} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "a"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "a", "foo.proto"), nil, 0644))
+	}
+
+	r, err := NewRegistry(map[string]string{
+		TSImportRootParamsKey:      first + ";" + second,
+		TSImportRootAliasParamsKey: "@first;@second",
+	})
+	require.NoError(t, err)
+	r.importRootIndex = make(map[string]importRoot)
+
+	// the first root wins when the file is present in more than one root
+	root, alias, err := r.findImportRootForFile("a/foo.proto")
+	require.NoError(t, err)
+	assert.Equal(t, first, root)
+	assert.Equal(t, "@first", alias)
+
+	// the file system is not visited again once the file is indexed
+	require.NoError(t, os.RemoveAll(first))
+	root, alias, err = r.findImportRootForFile("a/foo.proto")
+	require.NoError(t, err)
+	assert.Equal(t, first, root)
+	assert.Equal(t, "@first", alias)
+}
+
+func TestFindDefaultImportRootUnderCommonSubdir(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"protos/a/foo.proto", "protos/b/bar.proto"}
+	for _, f := range append(files, "build/.keep") {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, "api", f)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "api", f), nil, 0644))
+	}
+	// a directory visited first containing only some of the files is not the import root
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "alpha", "protos", "a"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "alpha", "protos", "a", "foo.proto"), nil, 0644))
+
+	// protoc run from a parent directory of the include path
+	root, err := findDefaultImportRoot(dir, files)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "api"), root)
+
+	// the ancestors of the directory are not visited
+	root, err = findDefaultImportRoot(filepath.Join(dir, "api", "build"), files)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "api", "build"), root)
+
+	// the directory is the import root when the files don't share a common directory
+	root, err = findDefaultImportRoot(dir, []string{"foo.proto", "protos/a/foo.proto"})
+	require.NoError(t, err)
+	assert.Equal(t, dir, root)
+
+	// the directory is the import root when the files are not found
+	root, err = findDefaultImportRoot(filepath.Join(dir, "alpha"), []string{"protos/c/baz.proto"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "alpha"), root)
+}
+
+func TestFindDefaultImportRootDepth(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"protos/a/foo.proto"}
+	for _, root := range []string{"a/b/c", "d/e/f/g"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, root, "protos", "a"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, root, files[0]), nil, 0644))
+	}
+
+	// the deepest directory looked into is the import root
+	root, err := findDefaultImportRoot(dir, files)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "a", "b", "c"), root)
+
+	// the deeper trees are not walked, so the files are not found in them
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "a")))
+	root, err = findDefaultImportRoot(dir, files)
+	require.NoError(t, err)
+	assert.Equal(t, dir, root)
+
+	// the depth is relative to the directory
+	root, err = findDefaultImportRoot(filepath.Join(dir, "d"), files)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "d", "e", "f", "g"), root)
+}
+
+func TestImportRootIsDerivedOnlyWhenDefaulted(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "api", "a"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api", "a", "foo.proto"), nil, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), nil, 0644))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() { require.NoError(t, os.Chdir(wd)) }()
+
+	req := &plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"a/foo.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("a/foo.proto"),
+			Package: proto.String("a"),
+		}},
+	}
+
+	r, err := NewRegistry(map[string]string{})
+	require.NoError(t, err)
+	_, err = r.Analyse(req)
+	require.NoError(t, err)
+	// the temporary directory may be a symlink, so the derived root is compared with the files it contains
+	assert.FileExists(t, filepath.Join(r.TSImportRoots[0], "a", "foo.proto"))
+	assert.Equal(t, "api", filepath.Base(r.TSImportRoots[0]))
+
+	for name, params := range map[string]map[string]string{
+		"import roots":       {TSImportRootParamsKey: dir},
+		"import root marker": {TSImportRootMarkerParamsKey: "package.json", OutputDir: dir},
+		"import root alias":  {TSImportRootAliasParamsKey: "@api"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewRegistry(params)
+			require.NoError(t, err)
+			roots := append([]string(nil), r.TSImportRoots...)
+
+			_, err = r.Analyse(req)
+			require.NoError(t, err)
+			assert.Equal(t, roots, r.TSImportRoots)
+		})
+	}
+}
+
+func TestFindImportRootForFileInMultipleRootsIsAnErrorInStrictMode(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	for _, root := range []string{first, second} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "foo.proto"), nil, 0644))
+	}
+
+	r, err := NewRegistry(map[string]string{
+		TSImportRootParamsKey: first + ";" + second,
+		Strict:                "true",
+	})
+	require.NoError(t, err)
+	r.importRootIndex = make(map[string]importRoot)
+
+	_, _, err = r.findImportRootForFile("foo.proto")
+	assert.EqualError(t, err, "foo.proto is found in multiple import roots ["+first+" "+second+"]")
+}
+
+func TestImportRootAliasMapPicksLongestRoot(t *testing.T) {
+	r, err := NewRegistry(map[string]string{
+		TSImportRootParamsKey:         "/repo",
+		TSImportRootAliasMapParamsKey: "/repo=@repo;/repo/protos/company=@company/protos",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/repo", "/repo/protos/company"}, r.TSImportRoots)
+	assert.Equal(t, []string{"@repo", "@company/protos"}, r.TSImportRootAliases)
+
+	root, alias, found := r.findLongestAliasedRoot("/repo/protos/company/a/foo.pb.ts")
+	require.True(t, found)
+	assert.Equal(t, "/repo/protos/company", root)
+	assert.Equal(t, "@company/protos", alias)
+
+	root, alias, found = r.findLongestAliasedRoot("/repo/protos/companyx/foo.pb.ts")
+	require.True(t, found)
+	assert.Equal(t, "/repo", root)
+	assert.Equal(t, "@repo", alias)
+
+	_, err = NewRegistry(map[string]string{TSImportRootAliasMapParamsKey: "/repo"})
+	assert.EqualError(t, err, "error getting common import root information: invalid pair /repo in ts_import_root_alias_map, expected root=alias")
+}
+
+func TestOutputDirIsTheBaseOfRelativeImports(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "dep.proto"), nil, 0644))
+
+	r, err := NewRegistry(map[string]string{
+		TSImportRootParamsKey: filepath.Join(dir, "src"),
+		OutputDir:             filepath.Join(dir, "gen", "ts"),
+	})
+	require.NoError(t, err)
+
+	filesData, err := r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"a/b.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("dep.proto"),
+				Package:     proto.String("dep"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Dep")}},
+			},
+			{
+				Name:       proto.String("a/b.proto"),
+				Package:    proto.String("a"),
+				Dependency: []string{"dep.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("B"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:     proto.String("dep"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".dep.Dep"),
+					}},
+				}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	dependencies := filesData["a/b.proto"].Dependencies
+	require.Len(t, dependencies, 1)
+	assert.Equal(t, "../../../src/dep.pb", dependencies[0].SourceFile)
+}
+
+func TestUnresolvedDependencyIsAnErrorInStrictMode(t *testing.T) {
+	root := t.TempDir()
+	r, err := NewRegistry(map[string]string{
+		TSImportRootParamsKey: root,
+		Strict:                "true",
+	})
+	require.NoError(t, err)
+
+	_, err = r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("dep.proto"),
+				Package:     proto.String("dep"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Dep")}},
+			},
+			{
+				Name:       proto.String("a.proto"),
+				Package:    proto.String("a"),
+				Dependency: []string{"dep.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("A"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:     proto.String("dep"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".dep.Dep"),
+					}},
+				}},
+			},
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot resolve dep.proto of type .dep.Dep depended on by a.proto, the file is not found in any import roots ["+root+"]")
+}
+
+func TestPackageNameSubpathsReplaceRelativeImports(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRegistry(map[string]string{TSPackageName: "@company/protos", OutputDir: filepath.Join(dir, "gen")})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		target   string
+		root     string
+		expected string
+	}{
+		{name: "path inside the output directory", target: "gen/foo/v1/foo.pb.ts", expected: "@company/protos/foo/v1/foo.pb"},
+		{name: "path inside the import root", target: "protos/bar/bar.pb.ts", root: "protos", expected: "@company/protos/bar/bar.pb"},
+		{name: "relative path outside of the package", target: "other/baz.pb.ts", expected: "../../other/baz.pb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := ""
+			if tt.root != "" {
+				root = filepath.Join(dir, tt.root)
+			}
+
+			result, err := r.getSourceFileForImport(filepath.Join(dir, "gen", "app", "source.pb.ts"), filepath.Join(dir, tt.target), root, "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestPackageMapOverridesTheDirectoryOfImports(t *testing.T) {
+	r, err := NewRegistry(map[string]string{
+		TSPackageMapParamsKey: "company.dep=libs/dep;company.app=apps/app",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"company.dep": "libs/dep", "company.app": "apps/app"}, r.PackageMap)
+
+	filesData, err := r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"protos/app/v1/app.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("protos/dep/v1/dep.proto"),
+				Package:     proto.String("company.dep"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Dep")}},
+			},
+			{
+				Name:       proto.String("protos/app/v1/app.proto"),
+				Package:    proto.String("company.app"),
+				Dependency: []string{"protos/dep/v1/dep.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("App"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:     proto.String("dep"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".company.dep.Dep"),
+					}},
+				}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	dependencies := filesData["protos/app/v1/app.proto"].Dependencies
+	require.Len(t, dependencies, 1)
+	assert.Equal(t, "../../libs/dep/dep.pb", dependencies[0].SourceFile)
+
+	_, err = NewRegistry(map[string]string{TSPackageMapParamsKey: "company.dep"})
+	assert.EqualError(t, err, "error getting package map information: invalid pair company.dep in ts_package_map, expected package=dir")
+}
+
+func TestFileMappingsOverrideTheImportOfFiles(t *testing.T) {
+	r, err := NewRegistry(map[string]string{
+		"Mprotos/dep/v1/dep.proto": "@company/dep",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"protos/dep/v1/dep.proto": "@company/dep"}, r.FileMappings)
+
+	filesData, err := r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"protos/app/v1/app.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("protos/dep/v1/dep.proto"),
+				Package:     proto.String("company.dep"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Dep")}},
+			},
+			{
+				Name:       proto.String("protos/app/v1/app.proto"),
+				Package:    proto.String("company.app"),
+				Dependency: []string{"protos/dep/v1/dep.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("App"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:     proto.String("dep"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".company.dep.Dep"),
+					}},
+				}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	dependencies := filesData["protos/app/v1/app.proto"].Dependencies
+	require.Len(t, dependencies, 1)
+	assert.Equal(t, "@company/dep", dependencies[0].SourceFile)
+
+	_, err = NewRegistry(map[string]string{"Mprotos/dep/v1/dep.proto": ""})
+	assert.EqualError(t, err, "error getting file mappings information: invalid mapping Mprotos/dep/v1/dep.proto=, expected Mfile.proto=module")
+
+	_, err = NewRegistry(map[string]string{"Mprotos/dep/v1/dep.proto": "@company/dep", "Mode": "strict", "Mtypo": "@company/typo"})
+	assert.EqualError(t, err, "error getting file mappings information: unknown parameter Mode, expected Mfile.proto=module")
+}
+
+func TestTSConfigPathAliasesReplaceRelativeImports(t *testing.T) {
+	dir := t.TempDir()
+	tsconfig := `{
+  // comments and trailing commas are allowed in tsconfig.json
+  "compilerOptions": {
+    "baseUrl": "./src",
+    "paths": {
+      "@app/*": ["app/*"],
+      "@deep/*": ["app/deep/*"], /* the most specific alias wins */
+      "@config": ["config/index.ts"],
+      "@settings": ["config/index.ts"],
+      "@shared/*": ["lib/*"],
+      "@lib/*": ["lib/*"],
+    },
+  },
+}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tsconfig.json"), []byte(tsconfig), 0644))
+
+	r, err := NewRegistry(map[string]string{TSConfig: filepath.Join(dir, "tsconfig.json")})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{name: "wildcard alias", target: "src/app/foo.pb.ts", expected: "@app/foo.pb"},
+		{name: "most specific alias", target: "src/app/deep/bar.pb.ts", expected: "@deep/bar.pb"},
+		{name: "exact alias", target: "src/config/index.ts", expected: "@config"},
+		{name: "first alias of the same target", target: "src/lib/qux.pb.ts", expected: "@lib/qux.pb"},
+		{name: "relative path without any alias", target: "src/other/baz.pb.ts", expected: "../other/baz.pb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := r.getSourceFileForImport(filepath.Join(dir, "src", "gen", "source.pb.ts"), filepath.Join(dir, tt.target), "", "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+
+	_, err = NewRegistry(map[string]string{TSConfig: filepath.Join(dir, "missing.json")})
+	assert.Error(t, err)
+}
+
+func TestImportRootMarker(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "web", "src", "gen"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "web", "package.json"), nil, 0644))
+
+	r, err := NewRegistry(map[string]string{
+		TSImportRootMarkerParamsKey: "tsconfig.json;package.json",
+		OutputDir:                   filepath.Join(dir, "web", "src", "gen"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "web")}, r.TSImportRoots)
+
+	_, err = NewRegistry(map[string]string{
+		TSImportRootParamsKey:       dir,
+		TSImportRootMarkerParamsKey: "package.json",
+	})
+	assert.EqualError(t, err, "error getting common import root information: ts_import_roots and ts_import_root_marker cannot be used together")
+}
+
+func TestDuplicateFullyQualifiedNamesAreAnError(t *testing.T) {
+	r, err := NewRegistry(map[string]string{})
+	require.NoError(t, err)
+
+	_, err = r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"b.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("a.proto"),
+				Package:     proto.String("dup"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Message")}},
+			},
+			{
+				Name:        proto.String("b.proto"),
+				Package:     proto.String("dup"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Message")}},
+			},
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ".dup.Message is defined in both a.proto and b.proto")
+}
+
+func TestFieldNameCollisionsAreAnError(t *testing.T) {
+	analyse := func(params map[string]string, fields ...*descriptorpb.FieldDescriptorProto) error {
+		r, err := NewRegistry(params)
+		require.NoError(t, err)
+
+		_, err = r.Analyse(&plugin.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{{
+				Name:        proto.String("a.proto"),
+				Package:     proto.String("collision"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Message"), Field: fields}},
+			}},
+		})
+		return err
+	}
+	field := func(name, jsonName string, number int32) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(jsonName),
+			Number:   proto.Int32(number),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+		}
+	}
+
+	err := analyse(map[string]string{}, field("foo_bar", "fooBar", 1), field("fooBar", "fooBar", 2))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fields foo_bar and fooBar of .collision.Message are both rendered as fooBar")
+
+	err = analyse(map[string]string{}, field("first", "name", 1), field("second", "name", 2))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fields first and second of .collision.Message are both rendered as name")
+
+	// the original names are unique within a message
+	assert.NoError(t, analyse(map[string]string{TSFieldCase: FieldCaseOriginal}, field("foo_bar", "fooBar", 1), field("fooBar", "fooBar", 2)))
+}
+
+func TestPackageDirsPlaceGeneratedFilesByPackage(t *testing.T) {
+	r, err := NewRegistry(map[string]string{TSPackageDirs: "true"})
+	require.NoError(t, err)
+
+	filesData, err := r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"protos/app.proto", "protos/dep.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("protos/dep.proto"),
+				Package:     proto.String("company.dep.v1"),
+				MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Dep")}},
+			},
+			{
+				Name:       proto.String("protos/app.proto"),
+				Package:    proto.String("company.app.v1"),
+				Dependency: []string{"protos/dep.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("App"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:     proto.String("dep"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".company.dep.v1.Dep"),
+					}},
+				}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "company/dep/v1/dep.pb.ts", filesData["protos/dep.proto"].TSFileName)
+	assert.Equal(t, "company/app/v1/app.pb.ts", filesData["protos/app.proto"].TSFileName)
+
+	dependencies := filesData["protos/app.proto"].Dependencies
+	require.Len(t, dependencies, 1)
+	assert.Equal(t, "../../dep/v1/dep.pb", dependencies[0].SourceFile)
+}
+
+func TestTypesOfTheSameFileAreNotImported(t *testing.T) {
+	r, err := NewRegistry(map[string]string{})
+	require.NoError(t, err)
+
+	filesData, err := r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"protos/a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("protos/a.proto"),
+			Package: proto.String("a"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("A"),
+					Field: []*descriptorpb.FieldDescriptorProto{{
+						Name:     proto.String("b"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".a.B"),
+					}},
+				},
+				{Name: proto.String("B")},
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	fileData := filesData["protos/a.proto"]
+	assert.Empty(t, fileData.Dependencies)
+
+	// even when a type of the file itself ends up among the external ones, it doesn't import itself
+	fileData.ExternalDependingTypes = append(fileData.ExternalDependingTypes, ".a.B")
+	require.NoError(t, r.collectExternalDependenciesFromData(filesData))
+	assert.Empty(t, fileData.Dependencies)
+}
+
+func TestMutualImports(t *testing.T) {
+	r, err := NewRegistry(map[string]string{})
+	require.NoError(t, err)
+
+	message := func(name, typeName string) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("peer"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(typeName)},
+				{Name: proto.String("peers"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(typeName)},
+			},
+		}
+	}
+
+	// a.proto and b.proto import each other, which the generated files do as well
+	filesData, err := r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"protos/a/a.proto", "protos/b/b.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:        proto.String("protos/a/a.proto"),
+				Package:     proto.String("a"),
+				Dependency:  []string{"protos/b/b.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{message("A", ".b.B")},
+			},
+			{
+				Name:        proto.String("protos/b/b.proto"),
+				Package:     proto.String("b"),
+				Dependency:  []string{"protos/a/a.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{message("B", ".a.A")},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// each file imports the other one once, however many fields refer to its types
+	dependencies := filesData["protos/a/a.proto"].Dependencies
+	require.Len(t, dependencies, 1)
+	assert.Equal(t, "BB", dependencies[0].ModuleIdentifier)
+	assert.Equal(t, "../b/b.pb", dependencies[0].SourceFile)
+
+	dependencies = filesData["protos/b/b.proto"].Dependencies
+	require.Len(t, dependencies, 1)
+	assert.Equal(t, "AA", dependencies[0].ModuleIdentifier)
+	assert.Equal(t, "../a/a.pb", dependencies[0].SourceFile)
+
+	// collecting the dependencies again doesn't add them twice
+	require.NoError(t, r.collectExternalDependenciesFromData(filesData))
+	assert.Len(t, filesData["protos/a/a.proto"].Dependencies, 1)
+	assert.Len(t, filesData["protos/b/b.proto"].Dependencies, 1)
+}
+
+func TestMapWithMessageKey(t *testing.T) {
+	r, err := NewRegistry(map[string]string{})
+	require.NoError(t, err)
+
+	// protoc rejects message keys, a descriptor with one is malformed
+	_, err = r.Analyse(&plugin.CodeGeneratorRequest{
+		FileToGenerate: []string{"protos/a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("protos/a.proto"),
+				Package: proto.String("a"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{Name: proto.String("Key")},
+					{
+						Name: proto.String("Index"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: proto.String("entries"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".a.Index.EntriesEntry")},
+						},
+						NestedType: []*descriptorpb.DescriptorProto{
+							{
+								Name:    proto.String("EntriesEntry"),
+								Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+								Field: []*descriptorpb.FieldDescriptorProto{
+									{Name: proto.String("key"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".a.Key")},
+									{Name: proto.String("value"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "map entry .a.Index.EntriesEntry has a key of type TYPE_MESSAGE .a.Key")
+}
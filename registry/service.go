@@ -2,14 +2,22 @@ package registry
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
 	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	swagger "github.com/grpc-ecosystem/grpc-gateway/protoc-gen-swagger/options"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus" // nolint: depguard
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
 )
 
+// identifierRegexp matches the names of the client methods which are valid identifiers
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
 func getHTTPAnnotation(m *descriptorpb.MethodDescriptorProto) *annotations.HttpRule {
 	option := proto.GetExtension(m.GetOptions(), annotations.E_Http)
 	return option.(*annotations.HttpRule)
@@ -19,12 +27,7 @@ func hasHTTPAnnotation(m *descriptorpb.MethodDescriptorProto) bool {
 	return getHTTPAnnotation(m) != nil
 }
 
-func getHTTPMethodPath(m *descriptorpb.MethodDescriptorProto) (method, path string) {
-	if !hasHTTPAnnotation(m) {
-		return "", ""
-	}
-
-	rule := getHTTPAnnotation(m)
+func getHTTPMethodPath(rule *annotations.HttpRule) (method, path string) {
 	pattern := rule.Pattern
 	switch pattern.(type) {
 	case *annotations.HttpRule_Get:
@@ -42,12 +45,8 @@ func getHTTPMethodPath(m *descriptorpb.MethodDescriptorProto) (method, path stri
 	}
 }
 
-func getHTTPBody(m *descriptorpb.MethodDescriptorProto) *string {
-	if !hasHTTPAnnotation(m) {
-		return nil
-	}
+func getHTTPBody(rule *annotations.HttpRule) *string {
 	empty := ""
-	rule := getHTTPAnnotation(m)
 	pattern := rule.Pattern
 	switch pattern.(type) {
 	case *annotations.HttpRule_Get:
@@ -58,76 +57,166 @@ func getHTTPBody(m *descriptorpb.MethodDescriptorProto) *string {
 	}
 }
 
-func (r *Registry) analyseService(fileData *data.File, packageName string, fileName string, service *descriptorpb.ServiceDescriptorProto) {
+// getOperationID returns the operation_id of the openapiv2_operation option of the method. protoc-gen-openapiv2 numbers its
+// option the same as protoc-gen-swagger, so the option of either of them is read
+func getOperationID(m *descriptorpb.MethodDescriptorProto) string {
+	option := proto.GetExtension(m.GetOptions(), swagger.E_Openapiv2Operation)
+	return option.(*swagger.Operation).GetOperationId()
+}
+
+// getMethodName returns the name of the client method of the rpc. with MethodNamesOperationID it's the operation_id of the
+// openapiv2_operation option, then the last part of the selector of the google.api.http option, e.g. GetBook for
+// library.LibraryService.GetBook, and the name of the rpc when the method has neither of them
+func (r *Registry) getMethodName(m *descriptorpb.MethodDescriptorProto) string {
+	if r.MethodNames != MethodNamesOperationID {
+		return m.GetName()
+	}
+
+	if operationID := getOperationID(m); operationID != "" {
+		return operationID
+	}
+
+	if selector := getHTTPAnnotation(m).GetSelector(); selector != "" {
+		return selector[strings.LastIndex(selector, ".")+1:]
+	}
+
+	return m.GetName()
+}
+
+// checkMethodNames makes sure the names of the client methods of the service are identifiers, and that no two methods share
+// a name, which the operation ids or the selectors could otherwise lead to
+func checkMethodNames(fqName string, methods []*data.Method) error {
+	names := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		if !identifierRegexp.MatchString(m.Name) {
+			return errors.Errorf("method %s of %s is not a valid identifier", m.Name, fqName)
+		}
+		if names[m.Name] {
+			return errors.Errorf("more than one method of %s is named %s", fqName, m.Name)
+		}
+		names[m.Name] = true
+	}
+
+	return nil
+}
+
+// getAdditionalBindingName returns the name of the method for the nth additional binding, counting from 1
+func getAdditionalBindingName(methodName string, n int) string {
+	return fmt.Sprintf("%sBinding%d", methodName, n)
+}
+
+func (r *Registry) analyseService(fileData *data.File, packageName string, fileName string, path []int32, service *descriptorpb.ServiceDescriptorProto) error {
 	packageIdentifier := service.GetName()
 	fqName := "." + packageName + "." + packageIdentifier
 
 	// register itself in the registry map
-	r.Types[fqName] = &TypeInformation{
+	err := r.registerType(&TypeInformation{
 		FullyQualifiedName: fqName,
 		Package:            packageName,
 		File:               fileName,
 		PackageIdentifier:  packageIdentifier,
 		LocalIdentifier:    service.GetName(),
+		Comment:            r.getComments(fileName, path),
+	})
+	if err != nil {
+		return errors.WithStack(err)
 	}
 
 	serviceData := data.NewService()
 	serviceData.Name = service.GetName()
+	serviceData.FQType = fqName
+	serviceData.Comment = r.Types[fqName].Comment
+	serviceData.SourceLocation = r.getSourceLocation(fileName, path)
 	serviceURLPart := packageName + "." + serviceData.Name
 
-	for _, method := range service.Method {
-		// don't support client streaming, will ignore the client streaming method
-		if method.GetClientStreaming() {
+	for i, method := range service.Method {
+		// client streaming is not supported by grpc-gateway, bidirectional streaming methods are only kept with WSBidi,
+		// which sends them over a WebSocket
+		if method.GetClientStreaming() && !(r.WSBidi && method.GetServerStreaming()) {
+			continue
+		}
+
+		httpMethod := "POST"
+		url := "/" + serviceURLPart + "/" + method.GetName()
+		var body *string
+		if hasHTTPAnnotation(method) {
+			rule := getHTTPAnnotation(method)
+			hm, u := getHTTPMethodPath(rule)
+			if hm != "" && u != "" {
+				httpMethod = hm
+				url = u
+			}
+			body = getHTTPBody(rule)
+		}
+
+		if method.GetClientStreaming() && strings.Contains(url, "{") {
+			// the messages are sent over the WebSocket once it's open, there's no request to fill in the path parameters with
+			log.Warnf("bidirectional streaming method %s of %s is skipped, path parameters are not supported over WebSockets", method.GetName(), fqName)
 			continue
 		}
 
+		// google.protobuf.Empty is not referred to by the clients, so it doesn't need to be imported
 		inputTypeFQName := *method.InputType
 		isInputTypeExternal := r.isExternalDependenciesOutsidePackage(inputTypeFQName, packageName)
 
-		if isInputTypeExternal {
+		if isInputTypeExternal && inputTypeFQName != emptyType {
 			fileData.ExternalDependingTypes = append(fileData.ExternalDependingTypes, inputTypeFQName)
 		}
 
 		outputTypeFQName := *method.OutputType
 		isOutputTypeExternal := r.isExternalDependenciesOutsidePackage(outputTypeFQName, packageName)
 
-		if isOutputTypeExternal {
+		if isOutputTypeExternal && outputTypeFQName != emptyType {
 			fileData.ExternalDependingTypes = append(fileData.ExternalDependingTypes, outputTypeFQName)
 		}
 
-		httpMethod := "POST"
-		url := "/" + serviceURLPart + "/" + method.GetName()
-		if hasHTTPAnnotation(method) {
-			hm, u := getHTTPMethodPath(method)
-			if hm != "" && u != "" {
-				httpMethod = hm
-				url = u
-			}
-		}
-		body := getHTTPBody(method)
-
 		methodData := &data.Method{
-			Name: method.GetName(),
+			Name: r.getMethodName(method),
 			URL:  url,
 			Input: &data.MethodArgument{
 				Type:       inputTypeFQName,
 				IsExternal: isInputTypeExternal,
+				IsEmpty:    inputTypeFQName == emptyType,
 			},
 			Output: &data.MethodArgument{
 				Type:       outputTypeFQName,
 				IsExternal: isOutputTypeExternal,
+				IsEmpty:    outputTypeFQName == emptyType,
 			},
 			ServerStreaming: method.GetServerStreaming(),
 			ClientStreaming: method.GetClientStreaming(),
 			HTTPMethod:      httpMethod,
 			HTTPRequestBody: body,
+			Idempotent:      method.GetOptions().GetIdempotencyLevel() != descriptorpb.MethodOptions_IDEMPOTENCY_UNKNOWN,
+			Comment:         r.getComments(fileName, appendPath(path, serviceMethodPath, int32(i))),
 		}
 
-		fileData.TrackPackageNonScalarType(methodData.Input)
-		fileData.TrackPackageNonScalarType(methodData.Output)
+		if !methodData.Input.IsEmpty {
+			fileData.TrackPackageNonScalarType(methodData.Input)
+		}
+		if !methodData.Output.IsEmpty {
+			fileData.TrackPackageNonScalarType(methodData.Output)
+		}
 
 		serviceData.Methods = append(serviceData.Methods, methodData)
+
+		// each additional binding gets its own method, which shares everything with the primary binding except the route
+		if hasHTTPAnnotation(method) {
+			for j, binding := range getHTTPAnnotation(method).GetAdditionalBindings() {
+				bindingMethod := *methodData
+				bindingMethod.Name = getAdditionalBindingName(methodData.Name, j+1)
+				bindingMethod.HTTPMethod, bindingMethod.URL = getHTTPMethodPath(binding)
+				bindingMethod.HTTPRequestBody = getHTTPBody(binding)
+				serviceData.Methods = append(serviceData.Methods, &bindingMethod)
+			}
+		}
+	}
+
+	if err := checkMethodNames(fqName, serviceData.Methods); err != nil {
+		return errors.WithStack(err)
 	}
 
 	fileData.Services = append(fileData.Services, serviceData)
+
+	return nil
 }
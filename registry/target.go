@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// targetVersion is a version of the typescript compiler, compared by its major and minor versions
+type targetVersion struct {
+	major int
+	minor int
+}
+
+// SatisfiesTarget is the first typescript version supporting the satisfies operator
+const SatisfiesTarget = "4.9"
+
+// getTargetInformation returns the typescript version of ts_target, e.g. 4.9 or 5, along with its parsed version
+func getTargetInformation(paramsMap map[string]string) (string, targetVersion, error) {
+	target, ok := paramsMap[TSTarget]
+	if !ok || target == "" {
+		target = DefaultTarget
+	}
+
+	version, err := parseTargetVersion(target)
+	if err != nil {
+		return "", targetVersion{}, errors.Errorf("unsupported value %s for %s, valid values are typescript versions such as 4.9 or 5", target, TSTarget)
+	}
+
+	return target, version, nil
+}
+
+// parseTargetVersion parses a typescript version made of a major version and an optional minor version
+func parseTargetVersion(target string) (targetVersion, error) {
+	parts := strings.Split(target, ".")
+	if len(parts) > 2 {
+		return targetVersion{}, errors.Errorf("invalid typescript version %s", target)
+	}
+
+	version := targetVersion{}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return targetVersion{}, errors.Errorf("invalid typescript version %s", target)
+		}
+		if i == 0 {
+			version.major = n
+		} else {
+			version.minor = n
+		}
+	}
+
+	return version, nil
+}
+
+// SupportsTarget returns whether the syntax introduced in the typescript version, e.g. SatisfiesTarget, can be generated
+// for the compiler of ts_target
+func (r *Registry) SupportsTarget(target string) bool {
+	version, err := parseTargetVersion(target)
+	if err != nil {
+		return false
+	}
+
+	if r.targetVersion.major != version.major {
+		return r.targetVersion.major > version.major
+	}
+
+	return r.targetVersion.minor >= version.minor
+}
@@ -0,0 +1,148 @@
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus" // nolint: depguard
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+)
+
+// tsconfigPath is a path alias out of the paths of the compilerOptions in tsconfig.json
+type tsconfigPath struct {
+	// alias is the pattern imported by the code, e.g. @app/*
+	alias string
+	// target is the absolute path the alias is resolved to, e.g. /repo/src/app/*
+	target string
+}
+
+// tsconfig is the part of tsconfig.json the path aliases are read from
+type tsconfig struct {
+	CompilerOptions struct {
+		BaseURL string              `json:"baseUrl"`
+		Paths   map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+// loadTSConfigPaths reads the path aliases out of the tsconfig.json. the targets are resolved against the baseUrl,
+// or the directory of the tsconfig.json without it
+func loadTSConfigPaths(fileName string) ([]tsconfigPath, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", fileName)
+	}
+
+	config := &tsconfig{}
+	if err := json.Unmarshal(stripJSONComments(content), config); err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", fileName)
+	}
+
+	absDir, err := filepath.Abs(filepath.Dir(fileName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error looking up absolute path for %s", fileName)
+	}
+	baseURL := filepath.Join(absDir, config.CompilerOptions.BaseURL)
+
+	// the aliases are sorted, so that the first one in lexical order wins among aliases of the same target
+	aliases := make([]string, 0, len(config.CompilerOptions.Paths))
+	for alias := range config.CompilerOptions.Paths {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	paths := make([]tsconfigPath, 0, len(config.CompilerOptions.Paths))
+	for _, alias := range aliases {
+		for _, target := range config.CompilerOptions.Paths[alias] {
+			paths = append(paths, tsconfigPath{alias: alias, target: filepath.Join(baseURL, target)})
+		}
+	}
+
+	return paths, nil
+}
+
+// stripJSONComments removes the comments and the trailing commas tsconfig.json allows on top of JSON
+func stripJSONComments(content []byte) []byte {
+	stripped := make([]byte, 0, len(content))
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '"':
+			// strings are copied as they are, including escaped quotes
+			start := i
+			for i++; i < len(content) && content[i] != '"'; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(content) {
+				i = len(content) - 1
+			}
+			stripped = append(stripped, content[start:i+1]...)
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			i--
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(string(content[i+2:]), "*/")
+			if end < 0 {
+				i = len(content)
+				break
+			}
+			i += end + 3
+		case c == ']' || c == '}':
+			// a trailing comma is only followed by white spaces before the closing bracket
+			trimmed := strings.TrimRight(string(stripped), " \t\r\n")
+			if strings.HasSuffix(trimmed, ",") {
+				stripped = append([]byte(strings.TrimSuffix(trimmed, ",")), stripped[len(trimmed):]...)
+			}
+			stripped = append(stripped, c)
+		default:
+			stripped = append(stripped, c)
+		}
+	}
+
+	return stripped
+}
+
+// getTSConfigAlias returns the import of the target through the most specific path alias of tsconfig.json matching it,
+// which is the one with the longest target. exact targets win over the ones with a wildcard, and the first alias in
+// lexical order wins among the ones of the same target
+func (r *Registry) getTSConfigAlias(absTarget string) (string, bool) {
+	target := data.TrimTSExtension(absTarget)
+	result, matchedLength, matchedExact := "", -1, false
+	for _, p := range r.tsconfigPaths {
+		wildcard := strings.Index(p.target, "*")
+		if wildcard < 0 {
+			if target == data.TrimTSExtension(p.target) && !matchedExact {
+				result, matchedExact = p.alias, true
+			}
+			continue
+		}
+
+		prefix, suffix := p.target[:wildcard], p.target[wildcard+1:]
+		if matchedExact || !strings.HasPrefix(target, prefix) || len(prefix) <= matchedLength {
+			continue
+		}
+
+		rest := strings.TrimPrefix(target, prefix)
+		if suffix = data.TrimTSExtension(suffix); !strings.HasSuffix(rest, suffix) {
+			continue
+		}
+		rest = strings.TrimSuffix(rest, suffix)
+
+		result, matchedLength = strings.Replace(p.alias, "*", filepath.ToSlash(rest), 1), len(prefix)
+	}
+
+	if result == "" {
+		return "", false
+	}
+
+	log.Debugf("tsconfig path alias %s has been found for %s", result, absTarget)
+	return result, true
+}
@@ -0,0 +1,282 @@
+package registry
+
+import (
+	"math"
+	"strconv"
+
+	descriptorpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/grpc-ecosystem/protoc-gen-grpc-gateway-ts/data"
+)
+
+const (
+	// validateRulesExtension is the field number of the validate.rules field option of protoc-gen-validate
+	validateRulesExtension = 1071
+	// bufValidateFieldExtension is the field number of the buf.validate.field field option of protovalidate
+	bufValidateFieldExtension = 1159
+)
+
+// the field numbers of the rules shared by protoc-gen-validate and protovalidate, whose messages are numbered the same
+const (
+	rulesString   = 14
+	rulesMessage  = 17
+	rulesRepeated = 18
+	rulesMap      = 19
+	// rulesRequired is the required rule of protovalidate, protoc-gen-validate only has it for messages
+	rulesRequired = 25
+
+	messageRulesRequired = 2
+
+	numericRulesConst = 1
+	numericRulesLt    = 2
+	numericRulesLte   = 3
+	numericRulesGt    = 4
+	numericRulesGte   = 5
+
+	stringRulesMinLen   = 2
+	stringRulesMaxLen   = 3
+	stringRulesPattern  = 6
+	stringRulesPrefix   = 7
+	stringRulesSuffix   = 8
+	stringRulesContains = 9
+	stringRulesLen      = 19
+
+	itemsRulesMin = 1
+	itemsRulesMax = 2
+)
+
+// wireField is the last value of a field of an encoded message, the varint, fixed32 and fixed64 values are in value and
+// the length-delimited ones in bytes
+type wireField struct {
+	value uint64
+	bytes []byte
+}
+
+// parseWireFields decodes the fields of the encoded message keyed by their numbers, the last value of each field wins
+func parseWireFields(b []byte) (map[protowire.Number]wireField, error) {
+	fields := make(map[protowire.Number]wireField)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		field := wireField{}
+		switch typ {
+		case protowire.VarintType:
+			field.value, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			field.value = uint64(v)
+		case protowire.Fixed64Type:
+			field.value, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			field.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		fields[num] = field
+	}
+
+	return fields, nil
+}
+
+// getFieldRules reads the protoc-gen-validate or protovalidate constraints of the field. protoc sends the options of
+// the plugins it doesn't know as unknown fields of the field options, so they are decoded from the wire format
+func getFieldRules(f *descriptorpb.FieldDescriptorProto) (*data.FieldRules, error) {
+	if f.GetOptions() == nil {
+		return nil, nil
+	}
+
+	options, err := parseWireFields(f.GetOptions().ProtoReflect().GetUnknown())
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding the field options")
+	}
+
+	var rules *data.FieldRules
+	for _, extension := range []protowire.Number{validateRulesExtension, bufValidateFieldExtension} {
+		option, ok := options[extension]
+		if !ok {
+			continue
+		}
+
+		fields, err := parseWireFields(option.bytes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error decoding the constraints of extension %d", extension)
+		}
+
+		if rules == nil {
+			rules = &data.FieldRules{}
+		}
+		if err := readFieldRules(f, fields, rules); err != nil {
+			return nil, errors.Wrapf(err, "error decoding the constraints of extension %d", extension)
+		}
+	}
+
+	return rules, nil
+}
+
+// readFieldRules reads the rules of the type of the field out of the decoded constraints
+func readFieldRules(f *descriptorpb.FieldDescriptorProto, fields map[protowire.Number]wireField, rules *data.FieldRules) error {
+	rules.Required = rules.Required || fields[rulesRequired].value != 0
+	if message, ok := fields[rulesMessage]; ok {
+		messageRules, err := parseWireFields(message.bytes)
+		if err != nil {
+			return err
+		}
+		rules.Required = rules.Required || messageRules[messageRulesRequired].value != 0
+	}
+
+	if items, ok := fields[rulesRepeated]; ok {
+		return readItemsRules(items.bytes, rules)
+	}
+	if items, ok := fields[rulesMap]; ok {
+		return readItemsRules(items.bytes, rules)
+	}
+
+	if stringRules, ok := fields[rulesString]; ok {
+		return readStringRules(stringRules.bytes, rules)
+	}
+
+	if number := numericRulesNumber(f.GetType()); number != 0 {
+		if numericRules, ok := fields[protowire.Number(number)]; ok {
+			return readNumericRules(f.GetType(), numericRules.bytes, rules)
+		}
+	}
+
+	return nil
+}
+
+// numericRulesNumber returns the field number of the rules of the numeric type, from float = 1 to sfixed64 = 12, 0 for the
+// other types
+func numericRulesNumber(t descriptorpb.FieldDescriptorProto_Type) int {
+	switch t {
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT:
+		return 1
+	case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
+		return 2
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32:
+		return 3
+	case descriptorpb.FieldDescriptorProto_TYPE_INT64:
+		return 4
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT32:
+		return 5
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT64:
+		return 6
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT32:
+		return 7
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT64:
+		return 8
+	case descriptorpb.FieldDescriptorProto_TYPE_FIXED32:
+		return 9
+	case descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
+		return 10
+	case descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+		return 11
+	case descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return 12
+	}
+
+	return 0
+}
+
+// readNumericRules reads the bounds of a numeric field, which are encoded as values of the type of the field
+func readNumericRules(t descriptorpb.FieldDescriptorProto_Type, b []byte, rules *data.FieldRules) error {
+	fields, err := parseWireFields(b)
+	if err != nil {
+		return err
+	}
+
+	for number, bound := range map[protowire.Number]*string{
+		numericRulesConst: &rules.Const,
+		numericRulesLt:    &rules.Lt,
+		numericRulesLte:   &rules.Lte,
+		numericRulesGt:    &rules.Gt,
+		numericRulesGte:   &rules.Gte,
+	} {
+		if field, ok := fields[number]; ok {
+			*bound = formatNumericValue(t, field.value)
+		}
+	}
+
+	return nil
+}
+
+// formatNumericValue renders the wire value of the numeric type as a number literal
+func formatNumericValue(t descriptorpb.FieldDescriptorProto_Type, value uint64) string {
+	switch t {
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT:
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(value))), 'g', -1, 32)
+	case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
+		return strconv.FormatFloat(math.Float64frombits(value), 'g', -1, 64)
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32, descriptorpb.FieldDescriptorProto_TYPE_INT64:
+		return strconv.FormatInt(int64(value), 10)
+	case descriptorpb.FieldDescriptorProto_TYPE_SINT32, descriptorpb.FieldDescriptorProto_TYPE_SINT64:
+		return strconv.FormatInt(protowire.DecodeZigZag(value), 10)
+	case descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+		return strconv.FormatInt(int64(int32(value)), 10)
+	case descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return strconv.FormatInt(int64(value), 10)
+	}
+
+	return strconv.FormatUint(value, 10)
+}
+
+// readStringRules reads the length, the pattern and the substrings of a string field
+func readStringRules(b []byte, rules *data.FieldRules) error {
+	fields, err := parseWireFields(b)
+	if err != nil {
+		return err
+	}
+
+	for number, length := range map[protowire.Number]**uint64{
+		stringRulesLen:    &rules.Len,
+		stringRulesMinLen: &rules.MinLen,
+		stringRulesMaxLen: &rules.MaxLen,
+	} {
+		if field, ok := fields[number]; ok {
+			value := field.value
+			*length = &value
+		}
+	}
+
+	for number, substring := range map[protowire.Number]*string{
+		stringRulesPattern:  &rules.Pattern,
+		stringRulesPrefix:   &rules.Prefix,
+		stringRulesSuffix:   &rules.Suffix,
+		stringRulesContains: &rules.Contains,
+	} {
+		if field, ok := fields[number]; ok {
+			*substring = string(field.bytes)
+		}
+	}
+
+	return nil
+}
+
+// readItemsRules reads the number of elements of a repeated field or entries of a map field
+func readItemsRules(b []byte, rules *data.FieldRules) error {
+	fields, err := parseWireFields(b)
+	if err != nil {
+		return err
+	}
+
+	if field, ok := fields[itemsRulesMin]; ok {
+		value := field.value
+		rules.MinItems = &value
+	}
+	if field, ok := fields[itemsRulesMax]; ok {
+		value := field.value
+		rules.MaxItems = &value
+	}
+
+	return nil
+}
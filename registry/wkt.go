@@ -0,0 +1,37 @@
+package registry
+
+// wellKnownTypes maps the fully qualified names of well-known types to the intermediate types they are rendered as.
+// grpc-gateway encodes these types as JSON primitives instead of objects, so that they don't need to be imported
+// the mapping only takes effect when ts_wkt_mapping is enabled
+var wellKnownTypes = map[string]string{
+	".google.protobuf.Timestamp":   "timestamp",
+	".google.protobuf.Duration":    "duration",
+	".google.protobuf.FieldMask":   "fieldmask",
+	".google.protobuf.DoubleValue": "double",
+	".google.protobuf.FloatValue":  "float",
+	".google.protobuf.Int64Value":  "int64",
+	".google.protobuf.UInt64Value": "uint64",
+	".google.protobuf.Int32Value":  "int32",
+	".google.protobuf.UInt32Value": "uint32",
+	".google.protobuf.BoolValue":   "bool",
+	".google.protobuf.StringValue": "string",
+	".google.protobuf.BytesValue":  "bytes",
+	".google.protobuf.Struct":      "struct",
+	".google.protobuf.Value":       "value",
+	".google.protobuf.ListValue":   "listvalue",
+	".google.protobuf.NullValue":   "nullvalue",
+	".google.protobuf.Any":         "any",
+}
+
+// emptyType is the fully qualified name of google.protobuf.Empty, which is rendered as no request or a void response for methods
+const emptyType = ".google.protobuf.Empty"
+
+// getWellKnownType returns the intermediate type of the well-known type if the mapping is enabled
+func (r *Registry) getWellKnownType(fqTypeName string) (string, bool) {
+	if !r.WellKnownTypeMapping {
+		return "", false
+	}
+
+	t, ok := wellKnownTypes[fqTypeName]
+	return t, ok
+}